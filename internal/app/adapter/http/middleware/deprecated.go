@@ -0,0 +1,13 @@
+package middleware
+
+import "github.com/gofiber/fiber/v2"
+
+// Deprecated middleware that mark the response of a legacy route using the
+// Deprecation header, so the clients know to move on. The route keep working
+// as it is.
+func Deprecated() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		return c.Next()
+	}
+}
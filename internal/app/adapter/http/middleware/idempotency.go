@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime/multipart"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
+	"github.com/mdanialr/sns_backend/internal/responses"
+	cons "github.com/mdanialr/sns_backend/pkg/constant"
+	resp "github.com/mdanialr/sns_backend/pkg/response"
+)
+
+const (
+	// HeaderIdempotencyKey the header that has the key chosen by the client
+	// to make a create request safe to retry.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed the header that mark a response as the replay
+	// of the one that was given to the first request.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// Idempotency middleware that make a create request safe to retry using the
+// Idempotency-Key header, which should be put after the authentication. The
+// successful response of the first request is kept and replayed to its
+// retries that have the same key and payload, while reusing the key for a
+// different payload is rejected. The key of a failed request is released, so
+// it may be retried as usual.
+func Idempotency(svc idempotency_service.IService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > 255 {
+			return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrDetail(HeaderIdempotencyKey+" header should be at most 255 characters"))
+		}
+		fp, err := fingerprint(c)
+		if err != nil {
+			return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrDetail(err.Error()))
+		}
+
+		kept, err := svc.Begin(c.Context(), key, fp)
+		if err != nil {
+			switch {
+			case errors.Is(err, idempotency_service.ErrInProgress):
+				return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+			case errors.Is(err, idempotency_service.ErrKeyReused):
+				return resp.ErrorCode(c, fiber.StatusUnprocessableEntity, resp.WithErr(err))
+			}
+			return resp.Error(c, resp.WithErr(err))
+		}
+		if kept != nil {
+			c.Set(HeaderIdempotentReplayed, "true")
+			if kept.ETag != "" {
+				c.Set(fiber.HeaderETag, kept.ETag)
+			}
+			if kept.Location != "" {
+				c.Location(kept.Location)
+			}
+			c.Set(fiber.HeaderContentType, kept.ContentType)
+			return c.Status(kept.StatusCode).Send(kept.Body)
+		}
+
+		if err = c.Next(); err != nil {
+			svc.Release(c.Context(), key)
+			return err
+		}
+		// only the successful response is kept, so a request that failed
+		// because of a passing problem may be retried
+		code := c.Response().StatusCode()
+		if code < fiber.StatusOK || code >= fiber.StatusMultipleChoices {
+			svc.Release(c.Context(), key)
+			return nil
+		}
+		svc.Finish(c.Context(), key, &responses.IdempotentResponse{
+			StatusCode:  code,
+			ContentType: string(c.Response().Header.ContentType()),
+			ETag:        string(c.Response().Header.Peek(fiber.HeaderETag)),
+			Location:    string(c.Response().Header.Peek(fiber.HeaderLocation)),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		})
+		return nil
+	}
+}
+
+// payloadHeaders the request headers that carry the payload rather than the
+// body, such as the ones of a tus upload creation.
+var payloadHeaders = []string{"Upload-Length", "Upload-Metadata"}
+
+// fingerprint return the hash of the method, path and payload of given
+// request, including the payloadHeaders that are set. Multipart form is hashed
+// using its fields and files rather than the raw body, since the boundary is
+// random on each retry.
+func fingerprint(c *fiber.Ctx) (string, error) {
+	hs := sha256.New()
+	fmt.Fprintf(hs, "%s %s\n", c.Method(), c.Path())
+	for _, h := range payloadHeaders {
+		if v := c.Get(h); v != "" {
+			fmt.Fprintf(hs, "%s: %s\n", h, v)
+		}
+	}
+	if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		hs.Write(c.Body())
+		return hex.EncodeToString(hs.Sum(nil)), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", err
+	}
+	for _, k := range sortedKeys(form.Value) {
+		for _, v := range form.Value[k] {
+			fmt.Fprintf(hs, "%q=%q\n", k, v)
+		}
+	}
+	for _, k := range sortedKeys(form.File) {
+		for _, fh := range form.File[k] {
+			fmt.Fprintf(hs, "%q=%q:", k, fh.Filename)
+			if err = hashFile(hs, fh); err != nil {
+				return "", err
+			}
+			hs.Write([]byte("\n"))
+		}
+	}
+
+	return hex.EncodeToString(hs.Sum(nil)), nil
+}
+
+// hashFile write the content of given file into given hash.
+func hashFile(hs hash.Hash, fh *multipart.FileHeader) error {
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(hs, f)
+	return err
+}
+
+// sortedKeys return the keys of given map in order, so the map is hashed the
+// same way every time.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mdanialr/sns_backend/pkg/etag"
+	resp "github.com/mdanialr/sns_backend/pkg/response"
+	"github.com/spf13/viper"
+)
+
+// IfMatch middleware that check the If-Match header of a request that change
+// a link. The header must have a version if `server.strict_if_match` is true,
+// so neither a missing header nor a wildcard is accepted, while a value that
+// can never match any version is rejected right away. Comparing it with the
+// current version is left to the handler.
+func IfMatch(v *viper.Viper) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ver, ok := etag.Parse(c.Get(fiber.HeaderIfMatch))
+		if !ok {
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErrMsg("If-Match header does not match any version"))
+		}
+		if ver == 0 && v.GetBool("server.strict_if_match") {
+			return resp.ErrorCode(c, fiber.StatusPreconditionRequired, resp.WithErrMsg("If-Match header is required"))
+		}
+		return c.Next()
+	}
+}
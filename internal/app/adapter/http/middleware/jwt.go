@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	jwtMiddleware "github.com/gofiber/jwt/v3"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/mdanialr/sns_backend/pkg/actor"
 	cons "github.com/mdanialr/sns_backend/pkg/constant"
 	resp "github.com/mdanialr/sns_backend/pkg/response"
 	"github.com/spf13/viper"
@@ -23,7 +24,8 @@ func JWT(v *viper.Viper) fiber.Handler {
 }
 
 // validateUser make sure user in JWT claim same as in when generating them
-// which is using the JWT secret as the user.
+// which is using the JWT secret as the user. The subject of the JWT is kept as
+// the actor of the request.
 func validateUser(v *viper.Viper) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		jw := c.Locals("jwt")
@@ -35,6 +37,13 @@ func validateUser(v *viper.Viper) fiber.Handler {
 		if cl["user"] != v.GetString("jwt.secret") {
 			return resp.Error(c, resp.WithErrMsg("unexpected user was found in jwt token"))
 		}
+		// tokens issued before the subject was added are still valid
+		who := "jwt"
+		if sub, _ := cl["sub"].(string); sub != "" {
+			who += ":" + sub
+		}
+		c.Locals(actor.Key, who)
+
 		return c.Next()
 	}
 }
@@ -0,0 +1,103 @@
+package paste_handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	md "github.com/mdanialr/sns_backend/internal/app/adapter/http/middleware"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/paste_service"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	cons "github.com/mdanialr/sns_backend/pkg/constant"
+	resp "github.com/mdanialr/sns_backend/pkg/response"
+	"github.com/spf13/viper"
+)
+
+type pasteHandler struct {
+	v     *viper.Viper
+	route fiber.Router
+	svc   paste_service.IService
+	idem  idempotency_service.IService
+}
+
+// New init all endpoints within `/paste`. Given idempotency service make
+// the create requests safe to retry.
+func New(r fiber.Router, v *viper.Viper, svc paste_service.IService, idem idempotency_service.IService) {
+	pt := &pasteHandler{v, r, svc, idem}
+
+	api := pt.route.Group("/paste", md.JWT(pt.v))
+	api.Get("/", pt.Index)
+	api.Post("/create", md.Idempotency(pt.idem), pt.Create)
+	api.Post("/update", pt.Update)
+	api.Post("/delete", pt.Delete)
+}
+
+// Index retrieve all data in paste category.
+func (p *pasteHandler) Index(c *fiber.Ctx) error {
+	req := new(requests.Paste)
+	c.QueryParser(req)
+	// set up the query order and sort
+	req.SetQuery()
+	// validate the request
+	if err := req.ValidateQuery(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := p.svc.Index(c.Context(), req)
+	if err != nil {
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res.Data), resp.WithMeta(res.Pagination))
+}
+
+// Create save new Paste instance to DB.
+func (p *pasteHandler) Create(c *fiber.Ctx) error {
+	req := new(requests.Paste)
+	c.BodyParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := p.svc.Create(c.Context(), req)
+	if err != nil {
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Update do update an existing Paste instance in DB.
+func (p *pasteHandler) Update(c *fiber.Ctx) error {
+	req := new(requests.PasteUpdate)
+	c.BodyParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := p.svc.Update(c.Context(), req)
+	if err != nil {
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Delete remove a Paste instance from DB.
+func (p *pasteHandler) Delete(c *fiber.Ctx) error {
+	req := new(requests.PasteDelete)
+	c.BodyParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	if err := p.svc.Delete(c.Context(), req); err != nil {
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c)
+}
@@ -0,0 +1,110 @@
+package paste_handler_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/paste_handler"
+	"github.com/mdanialr/sns_backend/internal/core/service/paste_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	"github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	jwtSecret = "secret"
+	jwtDur    = "1m" // 1 minute is enough for every test run
+)
+
+func TestPasteHandler_Create(t *testing.T) {
+	testCases := []struct {
+		name           string
+		jwtToken       string
+		payload        io.Reader
+		setup          func(*mocks.Mockpaste_serviceIService)
+		expectCode     int
+		expectResponse string
+	}{
+		{
+			name:       "Given request without token should return status code Unauthorized",
+			payload:    strings.NewReader(`{}`),
+			setup:      func(*mocks.Mockpaste_serviceIService) {},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "Given request without paste should return status code Bad Request",
+			jwtToken:   createJWT(jwtDur, jwtSecret),
+			payload:    strings.NewReader(`{"url":"note","description":"a note","permanent":"true"}`),
+			setup:      func(*mocks.Mockpaste_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Given service that return error should return the error message and status code Bad Request",
+			jwtToken: createJWT(jwtDur, jwtSecret),
+			payload:  strings.NewReader(`{"url":"note","description":"a note","paste":"hello","permanent":"true"}`),
+			setup: func(svc *mocks.Mockpaste_serviceIService) {
+				svc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(nil, errors.New("url already been taken")).
+					Once()
+			},
+			expectCode:     http.StatusBadRequest,
+			expectResponse: `{"status":"FAILED","message":"url already been taken"}`,
+		},
+		{
+			name:     "Given valid request should return the newly created paste and status code OK",
+			jwtToken: createJWT(jwtDur, jwtSecret),
+			payload:  strings.NewReader(`{"url":"note","description":"a note","paste":"package main","language":"go","permanent":"true"}`),
+			setup: func(svc *mocks.Mockpaste_serviceIService) {
+				svc.EXPECT().
+					Create(mock.Anything, mock.MatchedBy(func(r *requests.Paste) bool {
+						return r.Paste == "package main" && r.Language == "go"
+					})).
+					Return(&responses.PasteResponse{
+						ID:          1,
+						Url:         "note",
+						Description: "a note",
+						Paste:       helper.Ptr("package main"),
+						Language:    helper.Ptr("go"),
+						RawUrl:      "/note/raw",
+						IsPermanent: helper.Ptr(true),
+					}, nil).
+					Once()
+			},
+			expectCode:     http.StatusOK,
+			expectResponse: `{"status":"SUCCESS","data":{"id":1,"url":"note","description":"a note","paste":"package main","language":"go","raw_url":"/note/raw","permanent":true}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			paste_handler.New(h.App, h.V, h.Dep.pasteSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.pasteSvc)
+
+			// setup request payload
+			req := h.setupJSONReq(http.MethodPost, h.R.Create, tc.payload)
+			if tc.jwtToken != "" {
+				req.Header.Add("Authorization", "Bearer "+tc.jwtToken)
+			}
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectResponse != "" {
+				assert.Equal(t, tc.expectResponse, resp.String())
+			}
+		})
+	}
+}
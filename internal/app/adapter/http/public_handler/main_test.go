@@ -0,0 +1,64 @@
+package public_handler_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gofiber/fiber/v2"
+	pasteMocks "github.com/mdanialr/sns_backend/internal/core/service/paste_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/send_service/mocks"
+	shMocks "github.com/mdanialr/sns_backend/internal/core/service/shorten_service/mocks"
+	"github.com/mdanialr/sns_backend/pkg/domains"
+)
+
+type (
+	publicDeps struct {
+		shSvc    *shMocks.Mockshorten_serviceIService
+		sendSvc  *mocks.Mocksend_serviceIService
+		pasteSvc *pasteMocks.Mockpaste_serviceIService
+	}
+	helperSetup struct {
+		App *fiber.App
+		Dom *domains.Domains
+		Dep publicDeps
+	}
+)
+
+// setupReq set up request instance with given additional request header.
+func (h *helperSetup) setupReq(method, route string, header map[string]string) *http.Request {
+	req := httptest.NewRequest(method, route, nil)
+	for k, v := range header {
+		req.Header.Add(k, v)
+	}
+
+	return req
+}
+
+func setupHelperTest() *helperSetup {
+	d := publicDeps{
+		shSvc:    new(shMocks.Mockshorten_serviceIService),
+		sendSvc:  new(mocks.Mocksend_serviceIService),
+		pasteSvc: new(pasteMocks.Mockpaste_serviceIService),
+	}
+
+	// the host of test requests is example.com by default
+	dom, _ := domains.New("https://example.com", "go.example.com")
+
+	return &helperSetup{
+		App: fiber.New(),
+		Dom: dom,
+		Dep: d,
+	}
+}
+
+// memFile in memory file that implement io.ReadSeekCloser.
+type memFile struct {
+	*bytes.Reader
+}
+
+func (m *memFile) Close() error { return nil }
+
+func newMemFile(s string) *memFile {
+	return &memFile{bytes.NewReader([]byte(s))}
+}
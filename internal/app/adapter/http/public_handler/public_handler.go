@@ -0,0 +1,311 @@
+package public_handler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mdanialr/sns_backend/internal/core/service/paste_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/send_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/shorten_service"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	resp "github.com/mdanialr/sns_backend/pkg/response"
+	"github.com/mdanialr/sns_backend/pkg/targeting"
+)
+
+// errUnknownHost the request is sent to a host that is not one of the public
+// domains.
+var errUnknownHost = errors.New("host is not one of the public domains")
+
+// inlineTypes the media types of a file that are safe to be shown by the
+// browser from the public domains. Any other file, such as HTML or SVG that
+// may run a script, is served as an attachment.
+var inlineTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/avif":      true,
+	"image/bmp":       true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// visitorCookie the cookie that keep the visitor key, so a visitor keep
+// getting the same variant of a Shorten even if the ip is changed.
+const visitorCookie = "sns_visitor"
+
+type publicHandler struct {
+	route    fiber.Router
+	dom      *domains.Domains
+	shSvc    shorten_service.IService
+	sendSvc  send_service.IService
+	pasteSvc paste_service.IService
+}
+
+// New init all endpoints that can be accessed by anyone without any
+// authentication. Each link is looked up within the public domain that match
+// the Host header of the request.
+func New(r fiber.Router, d *domains.Domains, shSvc shorten_service.IService, sendSvc send_service.IService, pasteSvc paste_service.IService) {
+	pb := &publicHandler{r, d, shSvc, sendSvc, pasteSvc}
+
+	pb.route.Get("/:url", pb.Lookup)
+	pb.route.Get("/:url/thumbnail", pb.Thumbnail)
+	pb.route.Get("/:url/files", pb.Archive)
+	pb.route.Get("/:url/files/:name", pb.File)
+	pb.route.Get("/:url/raw", pb.Raw)
+}
+
+// domain return the public domain that match the host of the request.
+func (p *publicHandler) domain(c *fiber.Ctx) (string, error) {
+	dom, ok := p.dom.Lookup(c.Hostname())
+	if !ok {
+		return "", errUnknownHost
+	}
+	return dom, nil
+}
+
+// Lookup redirect to the destination of a Shorten, otherwise serve the file
+// of a Send that has the url. The destination of a Shorten depends on the
+// platform, language and query parameters of the client. A link that is not
+// yet active is not found, while a link that is already ended is gone.
+func (p *publicHandler) Lookup(c *fiber.Ctx) error {
+	dom, err := p.domain(c)
+	if err != nil {
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+	}
+
+	cl := targeting.NewClient(c.Get(fiber.HeaderUserAgent), c.Get(fiber.HeaderAcceptLanguage), queries(c))
+	cl.Visitor = visitor(c)
+	sh, err := p.shSvc.Lookup(c.Context(), dom, c.Params("url"), cl)
+	if err == nil {
+		// the destination may be changed any time, so never tell the client
+		// that it's permanent
+		return c.Redirect(*sh.Shorten, fiber.StatusFound)
+	}
+	switch {
+	case errors.Is(err, shorten_service.ErrNotYetAvailable):
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+	case errors.Is(err, shorten_service.ErrExpired):
+		return resp.ErrorCode(c, fiber.StatusGone, resp.WithErr(err))
+	case !errors.Is(err, shorten_service.ErrNotFound):
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return p.send(c, dom)
+}
+
+// visitor return the visitor key from the cookie, otherwise derive it from
+// the ip of the request then keep it in the cookie.
+func visitor(c *fiber.Ctx) string {
+	if v := c.Cookies(visitorCookie); v != "" && len(v) <= 64 {
+		return v
+	}
+	v := targeting.Visitor(c.IP())
+	c.Cookie(&fiber.Cookie{
+		Name:     visitorCookie,
+		Value:    v,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return v
+}
+
+// queries return the query parameters of the request.
+func queries(c *fiber.Ctx) url.Values {
+	q := make(url.Values)
+	c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+		q.Add(string(k), string(v))
+	})
+	return q
+}
+
+// send serve the file of a Send, support range requests.
+func (p *publicHandler) send(c *fiber.Ctx, dom string) error {
+	f, err := p.sendSvc.Download(c.Context(), dom, c.Params("url"))
+	if err != nil {
+		return downloadError(c, err)
+	}
+
+	return sendFile(c, f)
+}
+
+// File serve a single file of a Send by its name, support range requests.
+func (p *publicHandler) File(c *fiber.Ctx) error {
+	dom, err := p.domain(c)
+	if err != nil {
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+	}
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil {
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(send_service.ErrNotFound))
+	}
+	f, err := p.sendSvc.DownloadFile(c.Context(), dom, c.Params("url"), name)
+	if err != nil {
+		return downloadError(c, err)
+	}
+
+	return sendFile(c, f)
+}
+
+// Archive stream every file of a Send as a single zip archive. The archive is
+// generated on the fly, so it doesn't support range requests.
+func (p *publicHandler) Archive(c *fiber.Ctx) error {
+	dom, err := p.domain(c)
+	if err != nil {
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+	}
+	a, err := p.sendSvc.Archive(c.Context(), dom, c.Params("url"))
+	if err != nil {
+		return downloadError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, contentDisposition("attachment", a.Name))
+	if a.UpdatedAt != nil {
+		c.Set(fiber.HeaderLastModified, a.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// the status is already sent, so nothing else can be done on error
+		a.Write(w)
+		w.Flush()
+	})
+
+	return nil
+}
+
+// Thumbnail serve the thumbnail of an image Send. The thumbnail of a Send
+// never change, so let the client cache it for a while.
+func (p *publicHandler) Thumbnail(c *fiber.Ctx) error {
+	dom, err := p.domain(c)
+	if err != nil {
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+	}
+	f, err := p.sendSvc.Thumbnail(c.Context(), dom, c.Params("url"))
+	if err != nil {
+		return downloadError(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+	return sendFile(c, f)
+}
+
+// Raw serve the text of a Paste as plain text.
+func (p *publicHandler) Raw(c *fiber.Ctx) error {
+	dom, err := p.domain(c)
+	if err != nil {
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+	}
+	pt, err := p.pasteSvc.Raw(c.Context(), dom, c.Params("url"))
+	if err != nil {
+		if errors.Is(err, paste_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	if pt.UpdatedAt != nil {
+		c.Set(fiber.HeaderLastModified, pt.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+
+	return c.SendString(*pt.Paste)
+}
+
+// downloadError respond with the status code that match given error from
+// send_service.
+func downloadError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, send_service.ErrNotFound), errors.Is(err, send_service.ErrNotYetAvailable):
+		return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+	case errors.Is(err, send_service.ErrExpired):
+		return resp.ErrorCode(c, fiber.StatusGone, resp.WithErr(err))
+	case errors.Is(err, send_service.ErrQuarantined):
+		return resp.ErrorCode(c, fiber.StatusForbidden, resp.WithErr(err))
+	}
+	return resp.Error(c, resp.WithErr(err))
+}
+
+// sendFile stream given file as the response body. Only respond with the
+// requested part if there is a single range in the request header, multiple
+// ranges are ignored and served as a whole.
+func sendFile(c *fiber.Ctx, f *res.SendFileResponse) error {
+	// prefer the type that was detected when the file is uploaded
+	c.Type(filepath.Ext(f.Name))
+	if f.MimeType != nil {
+		c.Set(fiber.HeaderContentType, *f.MimeType)
+	}
+	disp := "attachment"
+	if typ, _, _ := mime.ParseMediaType(string(c.Response().Header.ContentType())); inlineTypes[typ] {
+		disp = "inline"
+	}
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(disp, f.Name))
+	// the file is uploaded by anyone, so never let the browser guess its type
+	// or run anything in it
+	c.Set(fiber.HeaderContentSecurityPolicy, "sandbox")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if f.Hash != nil {
+		c.Set(fiber.HeaderETag, `"`+*f.Hash+`"`)
+	}
+	if f.UpdatedAt != nil {
+		c.Set(fiber.HeaderLastModified, f.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	if c.Fresh() {
+		f.File.Close()
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	if c.Get(fiber.HeaderRange) == "" {
+		return c.SendStream(f.File, int(f.Size))
+	}
+	rg, err := c.Range(int(f.Size))
+	if errors.Is(err, fiber.ErrRangeUnsatisfiable) {
+		f.File.Close()
+		c.Set(fiber.HeaderContentRange, "bytes */"+strconv.FormatInt(f.Size, 10))
+		return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+	}
+	if err != nil || rg.Type != "bytes" || len(rg.Ranges) != 1 {
+		return c.SendStream(f.File, int(f.Size))
+	}
+
+	start, end := int64(rg.Ranges[0].Start), int64(rg.Ranges[0].End)
+	if _, err = f.File.Seek(start, io.SeekStart); err != nil {
+		f.File.Close()
+		return resp.Error(c, resp.WithErr(err))
+	}
+	c.Set(fiber.HeaderContentRange, "bytes "+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10)+"/"+strconv.FormatInt(f.Size, 10))
+	c.Status(fiber.StatusPartialContent)
+
+	return c.SendStream(&limitReadCloser{io.LimitReader(f.File, end-start+1), f.File}, int(end-start+1))
+}
+
+// contentDisposition return the Content-Disposition header of given type for
+// given file name, which is quoted and escaped as needed. The name is left
+// out if it can't be written in the header.
+func contentDisposition(typ, name string) string {
+	if v := mime.FormatMediaType(typ, map[string]string{"filename": name}); v != "" {
+		return v
+	}
+	return typ
+}
+
+// limitReadCloser keep the closer of a limited reader, so the file is closed
+// once the response is sent.
+type limitReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,564 @@
+package public_handler_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/public_handler"
+	"github.com/mdanialr/sns_backend/internal/core/service/paste_service"
+	pasteMocks "github.com/mdanialr/sns_backend/internal/core/service/paste_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/send_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/send_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/shorten_service"
+	shMocks "github.com/mdanialr/sns_backend/internal/core/service/shorten_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/helper"
+	"github.com/mdanialr/sns_backend/pkg/targeting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPublicHandler_Send(t *testing.T) {
+	const sampleContent = "0123456789"
+	sampleFile := func(svc *mocks.Mocksend_serviceIService) {
+		svc.EXPECT().
+			Download(mock.Anything, "", "zoom").
+			Return(&responses.SendFileResponse{
+				Name: "zoom.txt",
+				Hash: helper.Ptr("abc"),
+				Size: int64(len(sampleContent)),
+				File: newMemFile(sampleContent),
+			}, nil).
+			Once()
+	}
+
+	testCases := []struct {
+		name           string
+		header         map[string]string
+		setup          func(*mocks.Mocksend_serviceIService)
+		expectCode     int
+		expectHeader   map[string]string
+		expectResponse string
+	}{
+		{
+			name: "Given url that is not exist should return error message send was not found and status code " +
+				"Not Found",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Download(mock.Anything, "", "zoom").
+					Return(nil, send_service.ErrNotFound).
+					Once()
+			},
+			expectCode:     http.StatusNotFound,
+			expectResponse: `{"status":"FAILED","message":"send was not found"}`,
+		},
+		{
+			name: "Given url of quarantined file should return error message send is quarantined and status code " +
+				"Forbidden",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Download(mock.Anything, "", "zoom").
+					Return(nil, send_service.ErrQuarantined).
+					Once()
+			},
+			expectCode:     http.StatusForbidden,
+			expectResponse: `{"status":"FAILED","message":"send is quarantined"}`,
+		},
+		{
+			name:           "Given request without range should return the whole file and status code OK",
+			setup:          sampleFile,
+			expectCode:     http.StatusOK,
+			expectHeader:   map[string]string{"Etag": `"abc"`, "Accept-Ranges": "bytes", "Content-Type": "text/plain"},
+			expectResponse: sampleContent,
+		},
+		{
+			name:  "Given plain text file should be shown inline in a sandbox",
+			setup: sampleFile,
+			expectHeader: map[string]string{
+				"Content-Disposition":     "inline; filename=zoom.txt",
+				"Content-Security-Policy": "sandbox",
+				"X-Content-Type-Options":  "nosniff",
+			},
+			expectCode: http.StatusOK,
+		},
+		{
+			name: "Given html file should be served as an attachment that has its name escaped",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Download(mock.Anything, "", "zoom").
+					Return(&responses.SendFileResponse{
+						Name:     `x"; y=.html`,
+						MimeType: helper.Ptr("text/html; charset=utf-8"),
+						Size:     int64(len(sampleContent)),
+						File:     newMemFile(sampleContent),
+					}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+			expectHeader: map[string]string{
+				"Content-Disposition":     `attachment; filename="x\"; y=.html"`,
+				"Content-Security-Policy": "sandbox",
+			},
+		},
+		{
+			name: "Given file that has detected mime type should use that type instead of the one from extension " +
+				"and status code OK",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Download(mock.Anything, "", "zoom").
+					Return(&responses.SendFileResponse{
+						Name:     "zoom.txt",
+						MimeType: helper.Ptr("application/json"),
+						Size:     int64(len(sampleContent)),
+						File:     newMemFile(sampleContent),
+					}, nil).
+					Once()
+			},
+			expectCode:     http.StatusOK,
+			expectHeader:   map[string]string{"Content-Type": "application/json"},
+			expectResponse: sampleContent,
+		},
+		{
+			name:           "Given single range should return only that part and status code Partial Content",
+			header:         map[string]string{"Range": "bytes=2-5"},
+			setup:          sampleFile,
+			expectCode:     http.StatusPartialContent,
+			expectHeader:   map[string]string{"Content-Range": "bytes 2-5/10", "Content-Length": "4"},
+			expectResponse: "2345",
+		},
+		{
+			name:           "Given suffix range should return the last part and status code Partial Content",
+			header:         map[string]string{"Range": "bytes=-3"},
+			setup:          sampleFile,
+			expectCode:     http.StatusPartialContent,
+			expectHeader:   map[string]string{"Content-Range": "bytes 7-9/10"},
+			expectResponse: "789",
+		},
+		{
+			name:         "Given range beyond the file size should return status code Requested Range Not Satisfiable",
+			header:       map[string]string{"Range": "bytes=20-30"},
+			setup:        sampleFile,
+			expectCode:   http.StatusRequestedRangeNotSatisfiable,
+			expectHeader: map[string]string{"Content-Range": "bytes */10"},
+		},
+		{
+			name:       "Given matching etag should return status code Not Modified",
+			header:     map[string]string{"If-None-Match": `"abc"`},
+			setup:      sampleFile,
+			expectCode: http.StatusNotModified,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest()
+			public_handler.New(h.App, h.Dom, h.Dep.shSvc, h.Dep.sendSvc, h.Dep.pasteSvc)
+			// there is no Shorten with the url, so fallback to Send
+			h.Dep.shSvc.EXPECT().
+				Lookup(mock.Anything, "", "zoom", mock.Anything).
+				Return(nil, shorten_service.ErrNotFound).
+				Once()
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			req := h.setupReq(http.MethodGet, "/zoom", tc.header)
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectResponse != "" {
+				assert.Equal(t, tc.expectResponse, resp.String())
+			}
+		})
+	}
+}
+
+func TestPublicHandler_Thumbnail(t *testing.T) {
+	testCases := []struct {
+		name           string
+		setup          func(*mocks.Mocksend_serviceIService)
+		expectCode     int
+		expectHeader   map[string]string
+		expectResponse string
+	}{
+		{
+			name: "Given url that has no thumbnail yet should return error message send was not found and status " +
+				"code Not Found",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Thumbnail(mock.Anything, "", "zoom").
+					Return(nil, send_service.ErrNotFound).
+					Once()
+			},
+			expectCode:     http.StatusNotFound,
+			expectResponse: `{"status":"FAILED","message":"send was not found"}`,
+		},
+		{
+			name: "Given url that has thumbnail should return the thumbnail with cache headers and status code OK",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Thumbnail(mock.Anything, "", "zoom").
+					Return(&responses.SendFileResponse{
+						Name: "zoom_thumbnail.png",
+						Hash: helper.Ptr("abc-thumbnail"),
+						Size: 5,
+						File: newMemFile("thumb"),
+					}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+			expectHeader: map[string]string{
+				"Cache-Control": "public, max-age=86400",
+				"Content-Type":  "image/png",
+				"Etag":          `"abc-thumbnail"`,
+			},
+			expectResponse: "thumb",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest()
+			public_handler.New(h.App, h.Dom, h.Dep.shSvc, h.Dep.sendSvc, h.Dep.pasteSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			req := h.setupReq(http.MethodGet, "/zoom/thumbnail", nil)
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			assert.Equal(t, tc.expectResponse, resp.String())
+		})
+	}
+}
+
+func TestPublicHandler_File(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		setup          func(*mocks.Mocksend_serviceIService)
+		expectCode     int
+		expectResponse string
+	}{
+		{
+			name: "Given file name that is not exist should return error message send was not found and status " +
+				"code Not Found",
+			path: "/zoom/files/nope.txt",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					DownloadFile(mock.Anything, "", "zoom", "nope.txt").
+					Return(nil, send_service.ErrNotFound).
+					Once()
+			},
+			expectCode:     http.StatusNotFound,
+			expectResponse: `{"status":"FAILED","message":"send was not found"}`,
+		},
+		{
+			name: "Given escaped file name should look for the unescaped one and status code OK",
+			path: "/zoom/files/my%20notes.txt",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					DownloadFile(mock.Anything, "", "zoom", "my notes.txt").
+					Return(&responses.SendFileResponse{
+						Name: "my notes.txt",
+						Size: 5,
+						File: newMemFile("notes"),
+					}, nil).
+					Once()
+			},
+			expectCode:     http.StatusOK,
+			expectResponse: "notes",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest()
+			public_handler.New(h.App, h.Dom, h.Dep.shSvc, h.Dep.sendSvc, h.Dep.pasteSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			req := h.setupReq(http.MethodGet, tc.path, nil)
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			assert.Equal(t, tc.expectResponse, resp.String())
+		})
+	}
+}
+
+func TestPublicHandler_Archive(t *testing.T) {
+	testCases := []struct {
+		name           string
+		setup          func(*mocks.Mocksend_serviceIService)
+		expectCode     int
+		expectHeader   map[string]string
+		expectResponse string
+	}{
+		{
+			name: "Given url of quarantined file should return error message send is quarantined and status code " +
+				"Forbidden",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Archive(mock.Anything, "", "zoom").
+					Return(nil, send_service.ErrQuarantined).
+					Once()
+			},
+			expectCode:     http.StatusForbidden,
+			expectResponse: `{"status":"FAILED","message":"send is quarantined"}`,
+		},
+		{
+			name: "Given existing url should stream the archive and status code OK",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Archive(mock.Anything, "", "zoom").
+					Return(&responses.SendArchiveResponse{
+						Name: "zoom.zip",
+						Write: func(w io.Writer) error {
+							_, err := w.Write([]byte("PK-archive"))
+							return err
+						},
+					}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+			expectHeader: map[string]string{
+				"Content-Type":        "application/zip",
+				"Content-Disposition": "attachment; filename=zoom.zip",
+			},
+			expectResponse: "PK-archive",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest()
+			public_handler.New(h.App, h.Dom, h.Dep.shSvc, h.Dep.sendSvc, h.Dep.pasteSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			req := h.setupReq(http.MethodGet, "/zoom/files", nil)
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			assert.Equal(t, tc.expectResponse, resp.String())
+		})
+	}
+}
+
+func TestPublicHandler_Raw(t *testing.T) {
+	testCases := []struct {
+		name           string
+		setup          func(*pasteMocks.Mockpaste_serviceIService)
+		expectCode     int
+		expectHeader   map[string]string
+		expectResponse string
+	}{
+		{
+			name: "Given url that is not a paste should return error message paste was not found and status code " +
+				"Not Found",
+			setup: func(svc *pasteMocks.Mockpaste_serviceIService) {
+				svc.EXPECT().
+					Raw(mock.Anything, "", "note").
+					Return(nil, paste_service.ErrNotFound).
+					Once()
+			},
+			expectCode:     http.StatusNotFound,
+			expectResponse: `{"status":"FAILED","message":"paste was not found"}`,
+		},
+		{
+			name: "Given url of a paste should return the text as plain text and status code OK",
+			setup: func(svc *pasteMocks.Mockpaste_serviceIService) {
+				svc.EXPECT().
+					Raw(mock.Anything, "", "note").
+					Return(&responses.PasteResponse{Url: "note", Paste: helper.Ptr("<b>hello</b>")}, nil).
+					Once()
+			},
+			expectCode:     http.StatusOK,
+			expectHeader:   map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+			expectResponse: "<b>hello</b>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest()
+			public_handler.New(h.App, h.Dom, h.Dep.shSvc, h.Dep.sendSvc, h.Dep.pasteSvc)
+			tc.setup(h.Dep.pasteSvc)
+
+			// setup request
+			req := h.setupReq(http.MethodGet, "/note/raw", nil)
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			assert.Equal(t, tc.expectResponse, resp.String())
+		})
+	}
+}
+
+func TestPublicHandler_Lookup(t *testing.T) {
+	testCases := []struct {
+		name           string
+		host           string
+		query          string
+		header         map[string]string
+		setup          func(*shMocks.Mockshorten_serviceIService)
+		expectCode     int
+		expectHeader   map[string]string
+		expectResponse string
+	}{
+		{
+			name: "Given url of a Shorten in the primary domain should redirect to its destination with status " +
+				"code Found",
+			setup: func(svc *shMocks.Mockshorten_serviceIService) {
+				svc.EXPECT().
+					Lookup(mock.Anything, "", "yt", mock.Anything).
+					Return(&responses.ShortenResponse{Url: "yt", Shorten: helper.Ptr("https://www.youtube.com/")}, nil).
+					Once()
+			},
+			expectCode:   http.StatusFound,
+			expectHeader: map[string]string{"Location": "https://www.youtube.com/"},
+		},
+		{
+			name: "Given url of a Shorten in a vanity domain should look it up within that domain and redirect " +
+				"with status code Found",
+			host: "Go.Example.com:443",
+			setup: func(svc *shMocks.Mockshorten_serviceIService) {
+				svc.EXPECT().
+					Lookup(mock.Anything, "go.example.com", "yt", mock.Anything).
+					Return(&responses.ShortenResponse{Url: "yt", Shorten: helper.Ptr("https://music.youtube.com/")}, nil).
+					Once()
+			},
+			expectCode:   http.StatusFound,
+			expectHeader: map[string]string{"Location": "https://music.youtube.com/"},
+		},
+		{
+			name: "Given url of a Shorten should look it up using the platform, language, query parameters " +
+				"and visitor cookie of the client",
+			query: "?ref=app",
+			header: map[string]string{
+				"User-Agent":      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)",
+				"Accept-Language": "en;q=0.5, id-ID",
+				"Cookie":          "sns_visitor=abc",
+			},
+			setup: func(svc *shMocks.Mockshorten_serviceIService) {
+				cl := targeting.Client{
+					Platform: targeting.PlatformIOS,
+					Language: "id-id",
+					Query:    url.Values{"ref": {"app"}},
+					Visitor:  "abc",
+				}
+				svc.EXPECT().
+					Lookup(mock.Anything, "", "yt", cl).
+					Return(&responses.ShortenResponse{Url: "yt", Shorten: helper.Ptr("https://apps.apple.com/id/app")}, nil).
+					Once()
+			},
+			expectCode:   http.StatusFound,
+			expectHeader: map[string]string{"Location": "https://apps.apple.com/id/app", "Set-Cookie": ""},
+		},
+		{
+			name: "Given url of a Shorten that is not yet active should return error message and status code " +
+				"Not Found",
+			setup: func(svc *shMocks.Mockshorten_serviceIService) {
+				svc.EXPECT().
+					Lookup(mock.Anything, "", "yt", mock.Anything).
+					Return(nil, shorten_service.ErrNotYetAvailable).
+					Once()
+			},
+			expectCode:     http.StatusNotFound,
+			expectResponse: `{"status":"FAILED","message":"link is not yet available"}`,
+		},
+		{
+			name: "Given url of a Shorten that is already ended should return error message and status code Gone",
+			setup: func(svc *shMocks.Mockshorten_serviceIService) {
+				svc.EXPECT().
+					Lookup(mock.Anything, "", "yt", mock.Anything).
+					Return(nil, shorten_service.ErrExpired).
+					Once()
+			},
+			expectCode:     http.StatusGone,
+			expectResponse: `{"status":"FAILED","message":"link has expired"}`,
+		},
+		{
+			name: "Given host that is not one of the public domains should return error message and status code " +
+				"Not Found",
+			host:           "evil.example.com",
+			setup:          func(*shMocks.Mockshorten_serviceIService) {},
+			expectCode:     http.StatusNotFound,
+			expectResponse: `{"status":"FAILED","message":"host is not one of the public domains"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest()
+			public_handler.New(h.App, h.Dom, h.Dep.shSvc, h.Dep.sendSvc, h.Dep.pasteSvc)
+			tc.setup(h.Dep.shSvc)
+
+			// setup request
+			req := h.setupReq(http.MethodGet, "/yt"+tc.query, tc.header)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			if tc.expectResponse != "" {
+				var resp bytes.Buffer
+				resp.ReadFrom(res.Body)
+				assert.Equal(t, tc.expectResponse, resp.String())
+			}
+		})
+	}
+}
@@ -1,13 +1,16 @@
 package send_handler_test
 
 import (
+	"bytes"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
+	idemMocks "github.com/mdanialr/sns_backend/internal/core/service/idempotency_service/mocks"
 	"github.com/mdanialr/sns_backend/internal/core/service/send_service/mocks"
 	"github.com/spf13/viper"
 )
@@ -18,6 +21,7 @@ type (
 	}
 	sendDeps struct {
 		sendSvc *mocks.Mocksend_serviceIService
+		idemSvc *idemMocks.Mockidempotency_serviceIService
 	}
 	helperSetup struct {
 		App *fiber.App
@@ -35,6 +39,27 @@ func (h *helperSetup) setupJSONReq(method, route string, body io.Reader) *http.R
 	return req
 }
 
+// setupMultipartReq set up request instance that has given fields and a file
+// for each given filename as multipart form. Every file use the same field
+// name `send` and its name as the content.
+func (h *helperSetup) setupMultipartReq(method, route string, fields map[string]string, filenames ...string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	for _, fn := range filenames {
+		fw, _ := mw.CreateFormFile("send", fn)
+		fw.Write([]byte(fn))
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(method, route, &body)
+	req.Header.Add("Content-Type", mw.FormDataContentType())
+
+	return req
+}
+
 func setupHelperTest(v *viper.Viper) *helperSetup {
 	r := sendRoutes{
 		Index:  "/send/",
@@ -44,6 +69,7 @@ func setupHelperTest(v *viper.Viper) *helperSetup {
 	}
 	d := sendDeps{
 		sendSvc: new(mocks.Mocksend_serviceIService),
+		idemSvc: new(idemMocks.Mockidempotency_serviceIService),
 	}
 
 	return &helperSetup{
@@ -1,11 +1,15 @@
 package send_handler
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	md "github.com/mdanialr/sns_backend/internal/app/adapter/http/middleware"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
 	"github.com/mdanialr/sns_backend/internal/core/service/send_service"
 	"github.com/mdanialr/sns_backend/internal/requests"
 	cons "github.com/mdanialr/sns_backend/pkg/constant"
+	"github.com/mdanialr/sns_backend/pkg/etag"
 	resp "github.com/mdanialr/sns_backend/pkg/response"
 	"github.com/spf13/viper"
 )
@@ -14,17 +18,28 @@ type sendHandler struct {
 	v     *viper.Viper
 	route fiber.Router
 	svc   send_service.IService
+	idem  idempotency_service.IService
 }
 
-// New init all endpoints within `/send`.
-func New(r fiber.Router, v *viper.Viper, svc send_service.IService) {
-	sn := &sendHandler{v, r, svc}
+// New init all endpoints within `/send`. Given idempotency service make
+// the create requests safe to retry.
+func New(r fiber.Router, v *viper.Viper, svc send_service.IService, idem idempotency_service.IService) {
+	sn := &sendHandler{v, r, svc, idem}
 
 	api := sn.route.Group("/send", md.JWT(sn.v))
 	api.Get("/", sn.Index)
-	api.Post("/create", sn.Create)
-	api.Post("/update", sn.Update)
-	api.Post("/delete", sn.Delete)
+	api.Post("/", md.Idempotency(sn.idem), sn.Create)
+	api.Get("/:id", sn.Get)
+	api.Put("/:id", md.IfMatch(sn.v), sn.Update)
+	api.Patch("/:id", md.IfMatch(sn.v), sn.Patch)
+	api.Delete("/:id", md.IfMatch(sn.v), sn.Delete)
+	// legacy routes that have the id in the body
+	api.Post("/create", md.Deprecated(), md.Idempotency(sn.idem), sn.Create)
+	api.Post("/update", md.Deprecated(), md.IfMatch(sn.v), sn.Update)
+	api.Post("/delete", md.Deprecated(), md.IfMatch(sn.v), sn.Delete)
+	api.Get("/:id/qr", sn.QR)
+	api.Get("/:id/revisions", sn.Revisions)
+	api.Post("/:id/rollback", md.IfMatch(sn.v), sn.Rollback)
 }
 
 func (s *sendHandler) Index(c *fiber.Ctx) error {
@@ -32,6 +47,10 @@ func (s *sendHandler) Index(c *fiber.Ctx) error {
 	c.QueryParser(req)
 	// set up the query order and sort
 	req.SetQuery()
+	// validate the request
+	if err := req.ValidateQuery(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
 
 	res, err := s.svc.Index(c.Context(), req)
 	if err != nil {
@@ -44,8 +63,11 @@ func (s *sendHandler) Index(c *fiber.Ctx) error {
 func (s *sendHandler) Create(c *fiber.Ctx) error {
 	req := new(requests.Send)
 	c.BodyParser(req)
-	// manually retrieve binary file for 'send' param
-	req.Send, _ = c.FormFile("send")
+	// manually retrieve binary files for 'send' param, it may be repeated to
+	// upload several files at once
+	if form, err := c.MultipartForm(); err == nil {
+		req.Send = form.File["send"]
+	}
 
 	// validate the request
 	if err := req.Validate(); err != nil {
@@ -54,15 +76,43 @@ func (s *sendHandler) Create(c *fiber.Ctx) error {
 
 	res, err := s.svc.Create(c.Context(), req)
 	if err != nil {
+		if errors.Is(err, send_service.ErrUrlTaken) {
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
+	return resp.Success(c, resp.WithData(res))
+}
+
+func (s *sendHandler) Get(c *fiber.Ctx) error {
+	req := new(requests.SendGet)
+	c.ParamsParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.svc.Get(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, send_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
 		return resp.Error(c, resp.WithErr(err))
 	}
 
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
 	return resp.Success(c, resp.WithData(res))
 }
 
 func (s *sendHandler) Update(c *fiber.Ctx) error {
 	req := new(requests.SendUpdate)
 	c.BodyParser(req)
+	// the id in the path take precedence over the one in the body
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
 
 	// validate the request
 	if err := req.Validate(); err != nil {
@@ -71,15 +121,27 @@ func (s *sendHandler) Update(c *fiber.Ctx) error {
 
 	res, err := s.svc.Update(c.Context(), req)
 	if err != nil {
+		switch {
+		case errors.Is(err, send_service.ErrNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, send_service.ErrUrlTaken):
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		case errors.Is(err, send_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
 		return resp.Error(c, resp.WithErr(err))
 	}
 
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
 	return resp.Success(c, resp.WithData(res))
 }
 
 func (s *sendHandler) Delete(c *fiber.Ctx) error {
 	req := new(requests.SendDelete)
 	c.BodyParser(req)
+	// the id in the path take precedence over the one in the body
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
 
 	// validate the request
 	if err := req.Validate(); err != nil {
@@ -87,8 +149,117 @@ func (s *sendHandler) Delete(c *fiber.Ctx) error {
 	}
 
 	if err := s.svc.Delete(c.Context(), req); err != nil {
+		switch {
+		case errors.Is(err, send_service.ErrNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, send_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
 		return resp.Error(c, resp.WithErr(err))
 	}
 
 	return resp.Success(c)
 }
+
+// Patch do update only the given fields of an existing Send instance in
+// DB.
+func (s *sendHandler) Patch(c *fiber.Ctx) error {
+	req := new(requests.SendPatch)
+	c.BodyParser(req)
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
+
+	// validate the request, only the given fields are validated
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.svc.Patch(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, send_service.ErrNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, send_service.ErrUrlTaken):
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		case errors.Is(err, send_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Revisions retrieve the edit history of a Send instance, the newest
+// one first.
+func (s *sendHandler) Revisions(c *fiber.Ctx) error {
+	req := new(requests.Revisions)
+	c.ParamsParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.svc.Revisions(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, send_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Rollback restore a Send instance to the values right after a revision.
+func (s *sendHandler) Rollback(c *fiber.Ctx) error {
+	req := new(requests.Rollback)
+	c.BodyParser(req)
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.svc.Rollback(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, send_service.ErrNotFound), errors.Is(err, send_service.ErrRevisionNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, send_service.ErrUrlTaken):
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		case errors.Is(err, send_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
+	return resp.Success(c, resp.WithData(res))
+}
+
+func (s *sendHandler) QR(c *fiber.Ctx) error {
+	req := new(requests.QR)
+	c.ParamsParser(req)
+	c.QueryParser(req)
+	req.SetDefault()
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.svc.QR(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, send_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.File(c, res.Content, res.MimeType, res.ETag)
+}
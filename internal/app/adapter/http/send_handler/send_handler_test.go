@@ -2,6 +2,7 @@ package send_handler_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -9,8 +10,13 @@ import (
 	"time"
 
 	"github.com/mdanialr/sns_backend/internal/app/adapter/http/send_handler"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
+	idemMocks "github.com/mdanialr/sns_backend/internal/core/service/idempotency_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/send_service"
 	"github.com/mdanialr/sns_backend/internal/core/service/send_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/requests"
 	"github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/actor"
 	"github.com/mdanialr/sns_backend/pkg/helper"
 	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
 	"github.com/spf13/viper"
@@ -32,6 +38,7 @@ func TestSendHandler_Index(t *testing.T) {
 		setupV         func() *viper.Viper
 		jwtToken       string
 		setup          func(*mocks.Mocksend_serviceIService)
+		query          string
 		payload        io.Reader
 		expectCode     int
 		expectResponse string
@@ -82,6 +89,26 @@ func TestSendHandler_Index(t *testing.T) {
 			expectCode:     http.StatusBadRequest,
 			expectResponse: `{"status":"FAILED","message":"unexpected user was found in jwt token"}`,
 		},
+		{
+			name: "Given right jwt token but unknown status in query should return error message Invalid " +
+				"Payload and status code Bad Request",
+			setupV:         defaultViper,
+			jwtToken:       createJWT(jwtDur, jwtSecret),
+			setup:          func(_ *mocks.Mocksend_serviceIService) {},
+			query:          "?status=expired",
+			expectCode:     http.StatusBadRequest,
+			expectResponse: `{"status":"FAILED","message":"Invalid Payload","detail":[{"name":"status","message":"should be one of scheduled active ended"}]}`,
+		},
+		{
+			name: "Given right jwt token but order by something other than the sortable columns should return " +
+				"error message Invalid Payload and status code Bad Request",
+			setupV:         defaultViper,
+			jwtToken:       createJWT(jwtDur, jwtSecret),
+			setup:          func(_ *mocks.Mocksend_serviceIService) {},
+			query:          "?order=id%3BDROP%20TABLE%20sns",
+			expectCode:     http.StatusBadRequest,
+			expectResponse: `{"status":"FAILED","message":"Invalid Payload","detail":[{"name":"order","message":"should be one of id url domain description created_at updated_at active_from active_until"}]}`,
+		},
 		{
 			name: "Given right jwt token but failed to retrieve data from dependency should return error message " +
 				"from service layer dependency and status code Bad Request",
@@ -135,11 +162,11 @@ func TestSendHandler_Index(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// run necessary setup
 			h := setupHelperTest(tc.setupV())
-			send_handler.New(h.App, h.V, h.Dep.sendSvc)
+			send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
 			tc.setup(h.Dep.sendSvc)
 
 			// setup request payload
-			req := h.setupJSONReq(http.MethodGet, h.R.Index, tc.payload)
+			req := h.setupJSONReq(http.MethodGet, h.R.Index+tc.query, tc.payload)
 
 			req.Header.Add("Authorization", "Bearer "+tc.jwtToken)
 			res, _ := h.App.Test(req)
@@ -154,3 +181,719 @@ func TestSendHandler_Index(t *testing.T) {
 		})
 	}
 }
+
+func TestSendHandler_Create(t *testing.T) {
+	fields := map[string]string{"url": "zoom", "description": "files", "permanent": "true"}
+
+	testCases := []struct {
+		name           string
+		filenames      []string
+		setup          func(*mocks.Mocksend_serviceIService)
+		expectCode     int
+		expectResponse string
+	}{
+		{
+			name:       "Given request without any file should return status code Bad Request",
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:      "Given request with several files should pass all of them to the service and status code OK",
+			filenames: []string{"a.txt", "b.txt"},
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Create(mock.Anything, mock.MatchedBy(func(r *requests.Send) bool {
+						return len(r.Send) == 2 && r.Send[0].Filename == "a.txt" && r.Send[1].Filename == "b.txt"
+					})).
+					Return(&responses.SendResponse{
+						ID:  1,
+						Url: "zoom",
+						Files: []*responses.SendFileItem{
+							{Name: "a.txt", FileSize: "5B", Url: "/zoom/files/a.txt"},
+							{Name: "b.txt", FileSize: "5B", Url: "/zoom/files/b.txt"},
+						},
+					}, nil).
+					Once()
+			},
+			expectCode:     http.StatusOK,
+			expectResponse: `{"status":"SUCCESS","data":{"id":1,"url":"zoom","description":"","files":[{"name":"a.txt","file_size":"5B","url":"/zoom/files/a.txt"},{"name":"b.txt","file_size":"5B","url":"/zoom/files/b.txt"}]}}`,
+		},
+		{
+			name:      "Given service that return error should return status code Bad Request",
+			filenames: []string{"a.txt", "a.txt"},
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(nil, errors.New("file name a.txt is duplicated")).
+					Once()
+			},
+			expectCode:     http.StatusBadRequest,
+			expectResponse: `{"status":"FAILED","message":"file name a.txt is duplicated"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request payload
+			req := h.setupMultipartReq(http.MethodPost, h.R.Create, fields, tc.filenames...)
+
+			req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectResponse != "" {
+				assert.Equal(t, tc.expectResponse, resp.String())
+			}
+		})
+	}
+}
+
+func TestSendHandler_QR(t *testing.T) {
+	testCases := []struct {
+		name         string
+		path         string
+		header       map[string]string
+		setup        func(*mocks.Mocksend_serviceIService)
+		expectCode   int
+		expectHeader map[string]string
+		expectBody   string
+	}{
+		{
+			name:       "Given unknown format should return status code Bad Request",
+			path:       "/send/1/qr?format=gif",
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name: "Given id that is not exist should return error message send was not found and status code " +
+				"Not Found",
+			path: "/send/9/qr",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					QR(mock.Anything, mock.Anything).
+					Return(nil, send_service.ErrNotFound).
+					Once()
+			},
+			expectCode: http.StatusNotFound,
+			expectBody: `{"status":"FAILED","message":"send was not found"}`,
+		},
+		{
+			name: "Given options in query should pass them to the service then return the image and status code OK",
+			path: "/send/1/qr?format=svg&size=128&level=h&quiet=0",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					QR(mock.Anything, mock.MatchedBy(func(r *requests.QR) bool {
+						return r.ID == 1 && r.Format == "svg" && r.Size == 128 && r.Level == "H" && *r.Quiet == 0
+					})).
+					Return(&responses.QRResponse{MimeType: "image/svg+xml", Content: []byte("<svg/>"), ETag: "zoom-svg-128-H-0"}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+			expectHeader: map[string]string{
+				"Content-Type":  "image/svg+xml",
+				"Etag":          `"zoom-svg-128-H-0"`,
+				"Cache-Control": "private, max-age=3600",
+			},
+			expectBody: "<svg/>",
+		},
+		{
+			name:   "Given matching etag should return status code Not Modified",
+			path:   "/send/1/qr",
+			header: map[string]string{"If-None-Match": `"zoom-png-256-M-4"`},
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					QR(mock.Anything, mock.Anything).
+					Return(&responses.QRResponse{MimeType: "image/png", Content: []byte("png"), ETag: "zoom-png-256-M-4"}, nil).
+					Once()
+			},
+			expectCode: http.StatusNotModified,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			req := h.setupJSONReq(http.MethodGet, tc.path, nil)
+			req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+			for k, v := range tc.header {
+				req.Header.Add(k, v)
+			}
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody, resp.String())
+			}
+		})
+	}
+}
+
+func TestSendHandler_Rollback(t *testing.T) {
+	testCases := []struct {
+		name       string
+		path       string
+		body       string
+		setup      func(*mocks.Mocksend_serviceIService)
+		expectCode int
+		expectBody string
+	}{
+		{
+			name:       "Given request without revision id should return status code Bad Request",
+			path:       "/send/1/rollback",
+			body:       `{}`,
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name: "Given revision that doesn't belong to the send should return error message revision was not " +
+				"found and status code Not Found",
+			path: "/send/1/rollback",
+			body: `{"revision_id":9}`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Rollback(mock.Anything, mock.Anything).
+					Return(nil, send_service.ErrRevisionNotFound).
+					Once()
+			},
+			expectCode: http.StatusNotFound,
+			expectBody: `{"status":"FAILED","message":"revision was not found"}`,
+		},
+		{
+			name: "Given deletion revision should return error message revision has nothing to restore and " +
+				"status code Bad Request",
+			path: "/send/1/rollback",
+			body: `{"revision_id":3}`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Rollback(mock.Anything, mock.Anything).
+					Return(nil, send_service.ErrNothingToRestore).
+					Once()
+			},
+			expectCode: http.StatusBadRequest,
+			expectBody: `{"status":"FAILED","message":"revision has nothing to restore"}`,
+		},
+		{
+			name: "Given valid request should pass the ids, reason and the actor to the service and return status " +
+				"code OK",
+			path: "/send/1/rollback",
+			body: `{"revision_id":2,"reason":"revert typo"}`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Rollback(
+						mock.MatchedBy(func(ctx context.Context) bool { return actor.From(ctx) == "jwt" }),
+						mock.MatchedBy(func(r *requests.Rollback) bool {
+							return r.ID == 1 && r.RevisionID == 2 && r.Reason == "revert typo"
+						}),
+					).
+					Return(&responses.SendResponse{ID: 1}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			req := h.setupJSONReq(http.MethodPost, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody, resp.String())
+			}
+			h.Dep.sendSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendHandler_Routes(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		setup        func(*mocks.Mocksend_serviceIService)
+		expectCode   int
+		expectHeader map[string]string
+		expectBody   string
+	}{
+		{
+			name: "Given id that is not exist should return error message send was not found and status code " +
+				"Not Found",
+			method: http.MethodGet,
+			path:   "/send/9",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Get(mock.Anything, mock.MatchedBy(func(r *requests.SendGet) bool { return r.ID == 9 })).
+					Return(nil, send_service.ErrNotFound).
+					Once()
+			},
+			expectCode: http.StatusNotFound,
+			expectBody: `{"status":"FAILED","message":"send was not found"}`,
+		},
+		{
+			name:   "Given id in the path and the body should use the one in the path and return status code OK",
+			method: http.MethodPut,
+			path:   "/send/2",
+			body:   `{"id":1,"url":"file","description":"file","permanent":"false"}`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Update(mock.Anything, mock.MatchedBy(func(r *requests.SendUpdate) bool { return r.ID == 2 })).
+					Return(&responses.SendResponse{ID: 2}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"Deprecation": ""},
+		},
+		{
+			name: "Given url that is already used should return error message url already been taken and status " +
+				"code Conflict",
+			method: http.MethodPatch,
+			path:   "/send/2",
+			body:   `{"url":"file"}`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Patch(mock.Anything, mock.Anything).
+					Return(nil, send_service.ErrUrlTaken).
+					Once()
+			},
+			expectCode: http.StatusConflict,
+			expectBody: `{"status":"FAILED","message":"url already been taken"}`,
+		},
+		{
+			name: "Given only some fields should pass only them to the service including the empty description " +
+				"and return status code OK",
+			method: http.MethodPatch,
+			path:   "/send/2",
+			body:   `{"description":""}`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Patch(mock.Anything, mock.MatchedBy(func(r *requests.SendPatch) bool {
+						return r.ID == 2 && r.Description != nil && *r.Description == "" && r.Url == nil &&
+							r.Permanent == nil && r.Tags == nil
+					})).
+					Return(&responses.SendResponse{ID: 2}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "Given invalid value in one of the fields should return status code Bad Request",
+			method:     http.MethodPatch,
+			path:       "/send/2",
+			body:       `{"permanent":"maybe"}`,
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Given id that is not exist should return status code Not Found",
+			method: http.MethodDelete,
+			path:   "/send/9",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Delete(mock.Anything, mock.MatchedBy(func(r *requests.SendDelete) bool { return r.ID == 9 })).
+					Return(send_service.ErrNotFound).
+					Once()
+			},
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:   "Given legacy route should keep working and mark the response as deprecated",
+			method: http.MethodPost,
+			path:   "/send/delete",
+			body:   `{"id":3}`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Delete(mock.Anything, mock.MatchedBy(func(r *requests.SendDelete) bool { return r.ID == 3 })).
+					Return(nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"Deprecation": "true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			var body io.Reader
+			if tc.body != "" {
+				body = bytes.NewBufferString(tc.body)
+			}
+			req := h.setupJSONReq(tc.method, tc.path, body)
+			req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody, resp.String())
+			}
+			h.Dep.sendSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendHandler_IfMatch(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		ifMatch      string
+		strict       bool
+		setup        func(*mocks.Mocksend_serviceIService)
+		expectCode   int
+		expectHeader map[string]string
+		expectBody   string
+	}{
+		{
+			name:   "Given id that is exist should return its version as ETag header",
+			method: http.MethodGet,
+			path:   "/send/2",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Get(mock.Anything, mock.Anything).
+					Return(&responses.SendResponse{ID: 2, Version: 3}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"ETag": `"3"`},
+		},
+		{
+			name:    "Given ETag in If-Match header should pass its version to the service and return the new one",
+			method:  http.MethodPatch,
+			path:    "/send/2",
+			body:    `{"description":"file"}`,
+			ifMatch: `"3"`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Patch(mock.Anything, mock.MatchedBy(func(r *requests.SendPatch) bool { return r.Version == 3 })).
+					Return(&responses.SendResponse{ID: 2, Version: 4}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"ETag": `"4"`},
+		},
+		{
+			name: "Given ETag of an outdated version should return error message link was changed since it was " +
+				"read and status code Precondition Failed",
+			method:  http.MethodDelete,
+			path:    "/send/2",
+			ifMatch: `"2"`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Delete(mock.Anything, mock.MatchedBy(func(r *requests.SendDelete) bool { return r.Version == 2 })).
+					Return(send_service.ErrPreconditionFailed).
+					Once()
+			},
+			expectCode: http.StatusPreconditionFailed,
+			expectBody: `{"status":"FAILED","message":"link was changed since it was read"}`,
+		},
+		{
+			name:       "Given If-Match header that is not made by the server should return status code Precondition Failed",
+			method:     http.MethodPut,
+			path:       "/send/2",
+			body:       `{"url":"file","description":"file","permanent":"false"}`,
+			ifMatch:    `W/"3"`,
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "Given strict mode without If-Match header should return status code Precondition Required",
+			method:     http.MethodPost,
+			path:       "/send/delete",
+			body:       `{"id":2}`,
+			strict:     true,
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusPreconditionRequired,
+			expectBody: `{"status":"FAILED","message":"If-Match header is required"}`,
+		},
+		{
+			name:       "Given strict mode with wildcard If-Match header should return status code Precondition Required",
+			method:     http.MethodDelete,
+			path:       "/send/2",
+			ifMatch:    "*",
+			strict:     true,
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:    "Given wildcard If-Match header should not depend on the version",
+			method:  http.MethodDelete,
+			path:    "/send/2",
+			ifMatch: "*",
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Delete(mock.Anything, mock.MatchedBy(func(r *requests.SendDelete) bool { return r.Version == 0 })).
+					Return(nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:    "Given ETag in If-Match header of a rollback should pass its version to the service",
+			method:  http.MethodPost,
+			path:    "/send/2/rollback",
+			body:    `{"revision_id":1}`,
+			ifMatch: `"3"`,
+			setup: func(svc *mocks.Mocksend_serviceIService) {
+				svc.EXPECT().
+					Rollback(mock.Anything, mock.MatchedBy(func(r *requests.Rollback) bool { return r.Version == 3 })).
+					Return(&responses.SendResponse{ID: 2, Version: 4}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"ETag": `"4"`},
+		},
+		{
+			name:       "Given strict mode without If-Match header of a rollback should return status code Precondition Required",
+			method:     http.MethodPost,
+			path:       "/send/2/rollback",
+			body:       `{"revision_id":1}`,
+			strict:     true,
+			setup:      func(*mocks.Mocksend_serviceIService) {},
+			expectCode: http.StatusPreconditionRequired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			v := defaultViper()
+			v.Set("server.strict_if_match", tc.strict)
+			h := setupHelperTest(v)
+			send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.sendSvc)
+
+			// setup request
+			var body io.Reader
+			if tc.body != "" {
+				body = bytes.NewBufferString(tc.body)
+			}
+			req := h.setupJSONReq(tc.method, tc.path, body)
+			req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+			if tc.ifMatch != "" {
+				req.Header.Add("If-Match", tc.ifMatch)
+			}
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody, resp.String())
+			}
+			h.Dep.sendSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendHandler_Idempotency(t *testing.T) {
+	fields := map[string]string{"url": "zoom", "description": "files", "permanent": "true"}
+
+	testCases := []struct {
+		name         string
+		key          string
+		setup        func(*mocks.Mocksend_serviceIService, *idemMocks.Mockidempotency_serviceIService)
+		expectCode   int
+		expectHeader map[string]string
+		expectBody   string
+	}{
+		{
+			name: "Given key for the first time should create the Send then keep its response",
+			key:  "retry-1",
+			setup: func(svc *mocks.Mocksend_serviceIService, idem *idemMocks.Mockidempotency_serviceIService) {
+				idem.EXPECT().Begin(mock.Anything, "retry-1", mock.Anything).Return(nil, nil).Once()
+				svc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(&responses.SendResponse{ID: 1, Url: "zoom", Version: 1}, nil).
+					Once()
+				idem.EXPECT().
+					Finish(mock.Anything, "retry-1", mock.MatchedBy(func(r *responses.IdempotentResponse) bool {
+						return r.StatusCode == http.StatusOK && r.ETag == `"1"` &&
+							string(r.Body) == `{"status":"SUCCESS","data":{"id":1,"url":"zoom","description":"","version":1}}`
+					})).
+					Return().
+					Once()
+			},
+			expectCode: http.StatusOK,
+		},
+		{
+			name: "Given key of a finished request should replay its response without creating another Send",
+			key:  "retry-1",
+			setup: func(_ *mocks.Mocksend_serviceIService, idem *idemMocks.Mockidempotency_serviceIService) {
+				idem.EXPECT().
+					Begin(mock.Anything, "retry-1", mock.Anything).
+					Return(&responses.IdempotentResponse{
+						StatusCode:  http.StatusOK,
+						ContentType: "application/json",
+						ETag:        `"1"`,
+						Body:        []byte(`{"status":"SUCCESS","data":{"id":1}}`),
+					}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"Idempotent-Replayed": "true", "ETag": `"1"`},
+			expectBody:   `{"status":"SUCCESS","data":{"id":1}}`,
+		},
+		{
+			name: "Given key that was used by a different payload should return status code Unprocessable Entity",
+			key:  "retry-1",
+			setup: func(_ *mocks.Mocksend_serviceIService, idem *idemMocks.Mockidempotency_serviceIService) {
+				idem.EXPECT().
+					Begin(mock.Anything, "retry-1", mock.Anything).
+					Return(nil, idempotency_service.ErrKeyReused).
+					Once()
+			},
+			expectCode: http.StatusUnprocessableEntity,
+			expectBody: `{"status":"FAILED","message":"idempotency key was already used by a different request"}`,
+		},
+		{
+			name: "Given key of a request that is still running should return status code Conflict",
+			key:  "retry-1",
+			setup: func(_ *mocks.Mocksend_serviceIService, idem *idemMocks.Mockidempotency_serviceIService) {
+				idem.EXPECT().
+					Begin(mock.Anything, "retry-1", mock.Anything).
+					Return(nil, idempotency_service.ErrInProgress).
+					Once()
+			},
+			expectCode: http.StatusConflict,
+		},
+		{
+			name: "Given request that failed should release the key, so it may be retried",
+			key:  "retry-1",
+			setup: func(svc *mocks.Mocksend_serviceIService, idem *idemMocks.Mockidempotency_serviceIService) {
+				idem.EXPECT().Begin(mock.Anything, "retry-1", mock.Anything).Return(nil, nil).Once()
+				svc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(nil, send_service.ErrUrlTaken).
+					Once()
+				idem.EXPECT().Release(mock.Anything, "retry-1").Return().Once()
+			},
+			expectCode: http.StatusConflict,
+		},
+		{
+			name: "Given no key should create the Send as usual",
+			setup: func(svc *mocks.Mocksend_serviceIService, _ *idemMocks.Mockidempotency_serviceIService) {
+				svc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(&responses.SendResponse{ID: 1}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.sendSvc, h.Dep.idemSvc)
+
+			// setup request payload
+			req := h.setupMultipartReq(http.MethodPost, h.R.Index, fields, "a.txt")
+			req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+			if tc.key != "" {
+				req.Header.Add("Idempotency-Key", tc.key)
+			}
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody, resp.String())
+			}
+			h.Dep.sendSvc.AssertExpectations(t)
+			h.Dep.idemSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendHandler_IdempotencyFingerprint(t *testing.T) {
+	h := setupHelperTest(defaultViper())
+	send_handler.New(h.App, h.V, h.Dep.sendSvc, h.Dep.idemSvc)
+
+	// keep each fingerprint that is given to the service, while answering as
+	// if the key is in progress, so nothing else is run
+	fps := make(map[string]bool)
+	h.Dep.idemSvc.EXPECT().
+		Begin(mock.Anything, mock.Anything, mock.MatchedBy(func(fp string) bool { fps[fp] = true; return true })).
+		Return(nil, idempotency_service.ErrInProgress)
+
+	send := func(fields map[string]string) {
+		// each multipart request has its own random boundary
+		req := h.setupMultipartReq(http.MethodPost, h.R.Index, fields, "a.txt")
+		req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+		req.Header.Add("Idempotency-Key", "retry-1")
+		res, _ := h.App.Test(req)
+		res.Body.Close()
+	}
+	send(map[string]string{"url": "zoom", "description": "files"})
+	send(map[string]string{"description": "files", "url": "zoom"})
+	assert.Len(t, fps, 1, "the same form should have the same fingerprint")
+
+	send(map[string]string{"url": "zoom", "description": "other files"})
+	assert.Len(t, fps, 2, "a different form should have a different fingerprint")
+}
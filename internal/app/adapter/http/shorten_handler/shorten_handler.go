@@ -1,11 +1,15 @@
 package shorten_handler
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	md "github.com/mdanialr/sns_backend/internal/app/adapter/http/middleware"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
 	"github.com/mdanialr/sns_backend/internal/core/service/shorten_service"
 	"github.com/mdanialr/sns_backend/internal/requests"
 	cons "github.com/mdanialr/sns_backend/pkg/constant"
+	"github.com/mdanialr/sns_backend/pkg/etag"
 	resp "github.com/mdanialr/sns_backend/pkg/response"
 	"github.com/spf13/viper"
 )
@@ -14,17 +18,29 @@ type shortenHandler struct {
 	v     *viper.Viper
 	route fiber.Router
 	shSvc shorten_service.IService
+	idem  idempotency_service.IService
 }
 
-// New init all endpoints within `/shorten`.
-func New(route fiber.Router, v *viper.Viper, svc shorten_service.IService) {
-	sh := &shortenHandler{v, route, svc}
+// New init all endpoints within `/shorten`. Given idempotency service make
+// the create requests safe to retry.
+func New(route fiber.Router, v *viper.Viper, svc shorten_service.IService, idem idempotency_service.IService) {
+	sh := &shortenHandler{v, route, svc, idem}
 
 	api := sh.route.Group("/shorten", md.JWT(sh.v))
 	api.Get("/", sh.Index)
-	api.Post("/create", sh.Create)
-	api.Post("/update", sh.Update)
-	api.Post("/delete", sh.Delete)
+	api.Post("/", md.Idempotency(sh.idem), sh.Create)
+	api.Get("/:id", sh.Get)
+	api.Put("/:id", md.IfMatch(sh.v), sh.Update)
+	api.Patch("/:id", md.IfMatch(sh.v), sh.Patch)
+	api.Delete("/:id", md.IfMatch(sh.v), sh.Delete)
+	// legacy routes that have the id in the body
+	api.Post("/create", md.Deprecated(), md.Idempotency(sh.idem), sh.Create)
+	api.Post("/update", md.Deprecated(), md.IfMatch(sh.v), sh.Update)
+	api.Post("/delete", md.Deprecated(), md.IfMatch(sh.v), sh.Delete)
+	api.Get("/:id/qr", sh.QR)
+	api.Get("/:id/revisions", sh.Revisions)
+	api.Post("/:id/rollback", md.IfMatch(sh.v), sh.Rollback)
+	api.Get("/:id/variants", sh.Variants)
 }
 
 // Index retrieve all data in shorten category.
@@ -33,6 +49,10 @@ func (s *shortenHandler) Index(c *fiber.Ctx) error {
 	c.QueryParser(req)
 	// set up the query order and sort
 	req.SetQuery()
+	// validate the request
+	if err := req.ValidateQuery(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
 
 	res, err := s.shSvc.Index(c.Context(), req)
 	if err != nil {
@@ -54,9 +74,35 @@ func (s *shortenHandler) Create(c *fiber.Ctx) error {
 
 	res, err := s.shSvc.Create(c.Context(), req)
 	if err != nil {
+		if errors.Is(err, shorten_service.ErrUrlTaken) {
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		}
 		return resp.Error(c, resp.WithErr(err))
 	}
 
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Get retrieve a Shorten instance by its id.
+func (s *shortenHandler) Get(c *fiber.Ctx) error {
+	req := new(requests.ShortenGet)
+	c.ParamsParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.shSvc.Get(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, shorten_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
 	return resp.Success(c, resp.WithData(res))
 }
 
@@ -64,6 +110,9 @@ func (s *shortenHandler) Create(c *fiber.Ctx) error {
 func (s *shortenHandler) Update(c *fiber.Ctx) error {
 	req := new(requests.ShortenUpdate)
 	c.BodyParser(req)
+	// the id in the path take precedence over the one in the body
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
 
 	// validate the request
 	if err := req.Validate(); err != nil {
@@ -72,9 +121,18 @@ func (s *shortenHandler) Update(c *fiber.Ctx) error {
 
 	res, err := s.shSvc.Update(c.Context(), req)
 	if err != nil {
+		switch {
+		case errors.Is(err, shorten_service.ErrNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, shorten_service.ErrUrlTaken):
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		case errors.Is(err, shorten_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
 		return resp.Error(c, resp.WithErr(err))
 	}
 
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
 	return resp.Success(c, resp.WithData(res))
 }
 
@@ -82,6 +140,9 @@ func (s *shortenHandler) Update(c *fiber.Ctx) error {
 func (s *shortenHandler) Delete(c *fiber.Ctx) error {
 	req := new(requests.ShortenDelete)
 	c.BodyParser(req)
+	// the id in the path take precedence over the one in the body
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
 
 	// validate the request
 	if err := req.Validate(); err != nil {
@@ -89,8 +150,139 @@ func (s *shortenHandler) Delete(c *fiber.Ctx) error {
 	}
 
 	if err := s.shSvc.Delete(c.Context(), req); err != nil {
+		switch {
+		case errors.Is(err, shorten_service.ErrNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, shorten_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
 		return resp.Error(c, resp.WithErr(err))
 	}
 
 	return resp.Success(c)
 }
+
+// Variants retrieve the variants of a Shorten instance along with their hits.
+func (s *shortenHandler) Variants(c *fiber.Ctx) error {
+	req := new(requests.ShortenVariants)
+	c.ParamsParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.shSvc.Variants(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, shorten_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Patch do update only the given fields of an existing Shorten instance in
+// DB.
+func (s *shortenHandler) Patch(c *fiber.Ctx) error {
+	req := new(requests.ShortenPatch)
+	c.BodyParser(req)
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
+
+	// validate the request, only the given fields are validated
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.shSvc.Patch(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, shorten_service.ErrNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, shorten_service.ErrUrlTaken):
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		case errors.Is(err, shorten_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Revisions retrieve the edit history of a Shorten instance, the newest
+// one first.
+func (s *shortenHandler) Revisions(c *fiber.Ctx) error {
+	req := new(requests.Revisions)
+	c.ParamsParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.shSvc.Revisions(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, shorten_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Rollback restore a Shorten instance to the values right after a revision.
+func (s *shortenHandler) Rollback(c *fiber.Ctx) error {
+	req := new(requests.Rollback)
+	c.BodyParser(req)
+	c.ParamsParser(req)
+	req.Version, _ = etag.Parse(c.Get(fiber.HeaderIfMatch))
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.shSvc.Rollback(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, shorten_service.ErrNotFound), errors.Is(err, shorten_service.ErrRevisionNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, shorten_service.ErrUrlTaken):
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		case errors.Is(err, shorten_service.ErrPreconditionFailed):
+			return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	c.Set(fiber.HeaderETag, etag.Format(res.Version))
+	return resp.Success(c, resp.WithData(res))
+}
+
+// QR render the QR code of the public url of a Shorten instance.
+func (s *shortenHandler) QR(c *fiber.Ctx) error {
+	req := new(requests.QR)
+	c.ParamsParser(req)
+	c.QueryParser(req)
+	req.SetDefault()
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := s.shSvc.QR(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, shorten_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.File(c, res.Content, res.MimeType, res.ETag)
+}
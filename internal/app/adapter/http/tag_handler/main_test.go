@@ -0,0 +1,65 @@
+package tag_handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	idemMocks "github.com/mdanialr/sns_backend/internal/core/service/idempotency_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/tag_service/mocks"
+	"github.com/spf13/viper"
+)
+
+type (
+	tagDeps struct {
+		tagSvc  *mocks.Mocktag_serviceIService
+		idemSvc *idemMocks.Mockidempotency_serviceIService
+	}
+	helperSetup struct {
+		App *fiber.App
+		Dep tagDeps
+		V   *viper.Viper
+	}
+)
+
+// setupJSONReq set up request instance and add JSON request header.
+func (h *helperSetup) setupJSONReq(method, route string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, route, body)
+	req.Header.Add("Content-Type", fiber.MIMEApplicationJSONCharsetUTF8)
+
+	return req
+}
+
+func setupHelperTest(v *viper.Viper) *helperSetup {
+	d := tagDeps{
+		tagSvc:  new(mocks.Mocktag_serviceIService),
+		idemSvc: new(idemMocks.Mockidempotency_serviceIService),
+	}
+
+	return &helperSetup{
+		App: fiber.New(),
+		Dep: d,
+		V:   v,
+	}
+}
+
+func defaultViper() *viper.Viper {
+	v := viper.New()
+	v.Set("jwt.secret", jwtSecret)
+	return v
+}
+
+// createJWT return jwt token based on given duration and secret.
+func createJWT(dur, secret string) string {
+	d, _ := time.ParseDuration(dur)
+	claims := jwt.MapClaims{
+		"user": secret,
+		"exp":  time.Now().Add(d).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, _ := token.SignedString([]byte(secret))
+	return t
+}
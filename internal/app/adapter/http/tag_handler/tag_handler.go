@@ -0,0 +1,116 @@
+package tag_handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	md "github.com/mdanialr/sns_backend/internal/app/adapter/http/middleware"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/tag_service"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	cons "github.com/mdanialr/sns_backend/pkg/constant"
+	resp "github.com/mdanialr/sns_backend/pkg/response"
+	"github.com/spf13/viper"
+)
+
+type tagHandler struct {
+	v     *viper.Viper
+	route fiber.Router
+	svc   tag_service.IService
+	idem  idempotency_service.IService
+}
+
+// New init all endpoints within `/tags`. Given idempotency service make
+// the create requests safe to retry.
+func New(r fiber.Router, v *viper.Viper, svc tag_service.IService, idem idempotency_service.IService) {
+	tg := &tagHandler{v, r, svc, idem}
+
+	api := tg.route.Group("/tags", md.JWT(tg.v))
+	api.Get("/", tg.Index)
+	api.Post("/", md.Idempotency(tg.idem), tg.Create)
+	api.Put("/:id", tg.Update)
+	api.Delete("/:id", tg.Delete)
+	// legacy routes that have the id in the body
+	api.Post("/create", md.Deprecated(), md.Idempotency(tg.idem), tg.Create)
+	api.Post("/update", md.Deprecated(), tg.Update)
+	api.Post("/delete", md.Deprecated(), tg.Delete)
+}
+
+// Index retrieve all tags along with their usage.
+func (t *tagHandler) Index(c *fiber.Ctx) error {
+	res, err := t.svc.Index(c.Context())
+	if err != nil {
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Create save new Tag instance to DB.
+func (t *tagHandler) Create(c *fiber.Ctx) error {
+	req := new(requests.Tag)
+	c.BodyParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := t.svc.Create(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, tag_service.ErrTaken) {
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Update rename an existing Tag instance in DB.
+func (t *tagHandler) Update(c *fiber.Ctx) error {
+	req := new(requests.TagUpdate)
+	c.BodyParser(req)
+	// the id in the path take precedence over the one in the body
+	c.ParamsParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := t.svc.Update(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, tag_service.ErrNotFound):
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		case errors.Is(err, tag_service.ErrTaken):
+			return resp.ErrorCode(c, fiber.StatusConflict, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c, resp.WithData(res))
+}
+
+// Delete remove a Tag instance from DB.
+func (t *tagHandler) Delete(c *fiber.Ctx) error {
+	req := new(requests.TagDelete)
+	c.BodyParser(req)
+	// the id in the path take precedence over the one in the body
+	c.ParamsParser(req)
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	if err := t.svc.Delete(c.Context(), req); err != nil {
+		if errors.Is(err, tag_service.ErrNotFound) {
+			return resp.ErrorCode(c, fiber.StatusNotFound, resp.WithErr(err))
+		}
+		return resp.Error(c, resp.WithErr(err))
+	}
+
+	return resp.Success(c)
+}
@@ -0,0 +1,222 @@
+package tag_handler_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/tag_handler"
+	"github.com/mdanialr/sns_backend/internal/core/service/tag_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/tag_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	"github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	jwtSecret = "secret"
+	jwtDur    = "1m" // 1 minute is enough for every test run
+)
+
+func TestTagHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		noToken      bool
+		setup        func(*mocks.Mocktag_serviceIService)
+		expectCode   int
+		expectHeader map[string]string
+		expectBody   string
+	}{
+		{
+			name:       "Given request without token should return status code Unauthorized",
+			method:     http.MethodGet,
+			path:       "/tags",
+			noToken:    true,
+			setup:      func(*mocks.Mocktag_serviceIService) {},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "Given valid token should return every tag along with its usage and status code OK",
+			method: http.MethodGet,
+			path:   "/tags",
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Index(mock.Anything).
+					Return([]*responses.TagResponse{{ID: 1, Name: "work", Usage: 3}}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+			expectBody: `{"status":"SUCCESS","data":[{"id":1,"name":"work","usage":3}]}`,
+		},
+		{
+			name:   "Given service that fail to retrieve the tags should return the error message and status code Bad Request",
+			method: http.MethodGet,
+			path:   "/tags",
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Index(mock.Anything).
+					Return(nil, errors.New("failed to retrieve all tag data")).
+					Once()
+			},
+			expectCode: http.StatusBadRequest,
+			expectBody: `{"status":"FAILED","message":"failed to retrieve all tag data"}`,
+		},
+		{
+			name:       "Given name that is too long should return status code Bad Request",
+			method:     http.MethodPost,
+			path:       "/tags",
+			body:       `{"name":"` + string(bytes.Repeat([]byte("a"), 65)) + `"}`,
+			setup:      func(*mocks.Mocktag_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Given name that is already used should return error message tag already exists and status code Conflict",
+			method: http.MethodPost,
+			path:   "/tags",
+			body:   `{"name":"work"}`,
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Create(mock.Anything, mock.MatchedBy(func(r *requests.Tag) bool { return r.Name == "work" })).
+					Return(nil, tag_service.ErrTaken).
+					Once()
+			},
+			expectCode: http.StatusConflict,
+			expectBody: `{"status":"FAILED","message":"tag already exists"}`,
+		},
+		{
+			name:   "Given new name should return the newly created tag and status code OK",
+			method: http.MethodPost,
+			path:   "/tags",
+			body:   `{"name":"work"}`,
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(&responses.TagResponse{ID: 1, Name: "work"}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"Deprecation": ""},
+			expectBody:   `{"status":"SUCCESS","data":{"id":1,"name":"work","usage":0}}`,
+		},
+		{
+			name:   "Given id in the path and the body should use the one in the path and return status code OK",
+			method: http.MethodPut,
+			path:   "/tags/2",
+			body:   `{"id":1,"name":"home"}`,
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Update(mock.Anything, mock.MatchedBy(func(r *requests.TagUpdate) bool {
+						return r.ID == 2 && r.Name == "home"
+					})).
+					Return(&responses.TagResponse{ID: 2, Name: "home"}, nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:   "Given id that is not exist should return error message tag was not found and status code Not Found",
+			method: http.MethodPut,
+			path:   "/tags/9",
+			body:   `{"name":"home"}`,
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Update(mock.Anything, mock.Anything).
+					Return(nil, tag_service.ErrNotFound).
+					Once()
+			},
+			expectCode: http.StatusNotFound,
+			expectBody: `{"status":"FAILED","message":"tag was not found"}`,
+		},
+		{
+			name:   "Given name that is used by other tag should return status code Conflict",
+			method: http.MethodPut,
+			path:   "/tags/2",
+			body:   `{"name":"work"}`,
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Update(mock.Anything, mock.Anything).
+					Return(nil, tag_service.ErrTaken).
+					Once()
+			},
+			expectCode: http.StatusConflict,
+		},
+		{
+			name:   "Given id in the path should delete the tag and return status code OK",
+			method: http.MethodDelete,
+			path:   "/tags/3",
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Delete(mock.Anything, mock.MatchedBy(func(r *requests.TagDelete) bool { return r.ID == 3 })).
+					Return(nil).
+					Once()
+			},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:   "Given id that is not exist should return status code Not Found",
+			method: http.MethodDelete,
+			path:   "/tags/9",
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Delete(mock.Anything, mock.Anything).
+					Return(tag_service.ErrNotFound).
+					Once()
+			},
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:   "Given legacy route should keep working and mark the response as deprecated",
+			method: http.MethodPost,
+			path:   "/tags/update",
+			body:   `{"id":4,"name":"home"}`,
+			setup: func(svc *mocks.Mocktag_serviceIService) {
+				svc.EXPECT().
+					Update(mock.Anything, mock.MatchedBy(func(r *requests.TagUpdate) bool { return r.ID == 4 })).
+					Return(&responses.TagResponse{ID: 4, Name: "home"}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"Deprecation": "true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			tag_handler.New(h.App, h.V, h.Dep.tagSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.tagSvc)
+
+			// setup request
+			var body io.Reader
+			if tc.body != "" {
+				body = bytes.NewBufferString(tc.body)
+			}
+			req := h.setupJSONReq(tc.method, tc.path, body)
+			if !tc.noToken {
+				req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+			}
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+
+			// assert the response payload
+			var resp bytes.Buffer
+			resp.ReadFrom(res.Body)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody, resp.String())
+			}
+			h.Dep.tagSvc.AssertExpectations(t)
+		})
+	}
+}
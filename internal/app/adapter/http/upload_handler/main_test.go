@@ -0,0 +1,75 @@
+package upload_handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	idemMocks "github.com/mdanialr/sns_backend/internal/core/service/idempotency_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/upload_service/mocks"
+	"github.com/spf13/viper"
+)
+
+type (
+	uploadRoutes struct {
+		Index string
+	}
+	uploadDeps struct {
+		uploadSvc *mocks.Mockupload_serviceIService
+		idemSvc   *idemMocks.Mockidempotency_serviceIService
+	}
+	helperSetup struct {
+		App *fiber.App
+		Dep uploadDeps
+		R   uploadRoutes
+		V   *viper.Viper
+	}
+)
+
+// setupTusReq set up request instance and add tus and authorization request
+// header.
+func (h *helperSetup) setupTusReq(method, route string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, route, body)
+	req.Header.Add("Tus-Resumable", "1.0.0")
+	req.Header.Add("Authorization", "Bearer "+createJWT(jwtDur, jwtSecret))
+
+	return req
+}
+
+func setupHelperTest(v *viper.Viper) *helperSetup {
+	r := uploadRoutes{
+		Index: "/uploads",
+	}
+	d := uploadDeps{
+		uploadSvc: new(mocks.Mockupload_serviceIService),
+		idemSvc:   new(idemMocks.Mockidempotency_serviceIService),
+	}
+
+	return &helperSetup{
+		App: fiber.New(),
+		Dep: d,
+		R:   r,
+		V:   v,
+	}
+}
+
+func defaultViper() *viper.Viper {
+	v := viper.New()
+	v.Set("jwt.secret", jwtSecret)
+	return v
+}
+
+// createJWT return jwt token based on given duration and secret.
+func createJWT(dur, secret string) string {
+	d, _ := time.ParseDuration(dur)
+	claims := jwt.MapClaims{
+		"user": secret,
+		"exp":  time.Now().Add(d).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, _ := token.SignedString([]byte(secret))
+	return t
+}
@@ -0,0 +1,165 @@
+package upload_handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	md "github.com/mdanialr/sns_backend/internal/app/adapter/http/middleware"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/upload_service"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+	cons "github.com/mdanialr/sns_backend/pkg/constant"
+	resp "github.com/mdanialr/sns_backend/pkg/response"
+	"github.com/spf13/viper"
+)
+
+const (
+	// tusVersion the only supported version of tus protocol.
+	tusVersion = "1.0.0"
+	// tusExtension comma separated supported tus extensions.
+	tusExtension = "creation,expiration,termination"
+	// tusContentType the only content type that is accepted for PATCH.
+	tusContentType = "application/offset+octet-stream"
+)
+
+type uploadHandler struct {
+	v     *viper.Viper
+	route fiber.Router
+	svc   upload_service.IService
+	idem  idempotency_service.IService
+}
+
+// New init all endpoints within `/uploads` that implement tus resumable
+// upload protocol. Ref: https://tus.io/protocols/resumable-upload
+func New(r fiber.Router, v *viper.Viper, svc upload_service.IService, idem idempotency_service.IService) {
+	up := &uploadHandler{v, r, svc, idem}
+
+	api := up.route.Group("/uploads", md.JWT(up.v), up.tusResumable)
+	api.Options("/", up.Options)
+	api.Post("/", md.Idempotency(up.idem), up.Create)
+	api.Head("/:id", up.Head)
+	api.Patch("/:id", up.Patch)
+	api.Delete("/:id", up.Delete)
+}
+
+// tusResumable make sure the client use supported tus version and always
+// include the version in the response.
+func (u *uploadHandler) tusResumable(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	if c.Method() != fiber.MethodOptions && c.Get("Tus-Resumable") != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return resp.ErrorCode(c, fiber.StatusPreconditionFailed, resp.WithErrMsg("unsupported tus version"))
+	}
+	return c.Next()
+}
+
+// Options give information about the server's current configuration.
+func (u *uploadHandler) Options(c *fiber.Ctx) error {
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtension)
+	if max := u.v.GetInt64("upload.max_size"); max > 0 {
+		c.Set("Tus-Max-Size", strconv.FormatInt(max*1024*1024, 10))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Create start a new resumable upload.
+func (u *uploadHandler) Create(c *fiber.Ctx) error {
+	req := new(requests.Upload)
+	c.ReqHeaderParser(req)
+	if err := req.ParseMetadata(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrDetail(err.Error()))
+	}
+
+	// validate the request
+	if err := req.Validate(); err != nil {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrValidation(err))
+	}
+
+	res, err := u.svc.Create(c.Context(), req)
+	if err != nil {
+		return resp.ErrorCode(c, errorCode(err), resp.WithErr(err))
+	}
+
+	c.Location(strings.TrimSuffix(c.BaseURL()+c.Path(), "/") + "/" + res.ID)
+	setUploadHeader(c, res)
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// Head give the current offset of an upload.
+func (u *uploadHandler) Head(c *fiber.Ctx) error {
+	res, err := u.svc.Get(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.SendStatus(errorCode(err))
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("Upload-Length", strconv.FormatInt(res.Length, 10))
+	if res.Metadata != "" {
+		c.Set("Upload-Metadata", res.Metadata)
+	}
+	setUploadHeader(c, res)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// Patch append the request body to an upload starting from the given offset.
+func (u *uploadHandler) Patch(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderContentType) != tusContentType {
+		return resp.ErrorCode(c, fiber.StatusUnsupportedMediaType, resp.WithErrMsg("content type should be "+tusContentType))
+	}
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return resp.Error(c, resp.WithErrMsg(cons.InvalidPayload), resp.WithErrDetail("invalid Upload-Offset header"))
+	}
+
+	res, err := u.svc.Write(c.Context(), c.Params("id"), offset, bytes.NewReader(c.Body()))
+	if err != nil {
+		return resp.ErrorCode(c, errorCode(err), resp.WithErr(err))
+	}
+	setUploadHeader(c, res)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Delete terminate an upload.
+func (u *uploadHandler) Delete(c *fiber.Ctx) error {
+	if err := u.svc.Delete(c.Context(), c.Params("id")); err != nil {
+		return resp.ErrorCode(c, errorCode(err), resp.WithErr(err))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// setUploadHeader add the progress and the expiration of given upload to
+// response header.
+func setUploadHeader(c *fiber.Ctx, up *res.UploadResponse) {
+	c.Set("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+	if up.ExpiresAt != nil && up.Send == nil {
+		c.Set("Upload-Expires", up.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// errorCode map given error from service layer to http status code that is
+// expected by tus protocol.
+func errorCode(err error) int {
+	switch {
+	case errors.Is(err, upload_service.ErrNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, upload_service.ErrOffsetMismatch):
+		return fiber.StatusConflict
+	case errors.Is(err, upload_service.ErrTooLarge):
+		return fiber.StatusRequestEntityTooLarge
+	case errors.Is(err, upload_service.ErrFinalize):
+		// tus clients retry the request on server errors
+		return fiber.StatusServiceUnavailable
+	}
+	return fiber.StatusBadRequest
+}
@@ -0,0 +1,291 @@
+package upload_handler_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/upload_handler"
+	idemMocks "github.com/mdanialr/sns_backend/internal/core/service/idempotency_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/upload_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/upload_service/mocks"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	"github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	jwtSecret = "secret"
+	jwtDur    = "1m" // 1 minute is enough for every test run
+)
+
+func TestUploadHandler(t *testing.T) {
+	exp, _ := time.Parse(time.RFC3339, "2021-04-28T18:27:45+07:00")
+	// base64 of 'file.zip', 'zoom', 'desc' and 'true'
+	const sampleMeta = "filename ZmlsZS56aXA=,url em9vbQ==,description ZGVzYw==,permanent dHJ1ZQ=="
+
+	testCases := []struct {
+		name         string
+		method       string
+		route        string
+		header       map[string]string
+		setup        func(*mocks.Mockupload_serviceIService)
+		expectCode   int
+		expectHeader map[string]string
+	}{
+		{
+			name:         "Given unsupported tus version should return status code Precondition Failed",
+			method:       http.MethodHead,
+			route:        "/uploads/abc",
+			header:       map[string]string{"Tus-Resumable": "0.2.2"},
+			setup:        func(_ *mocks.Mockupload_serviceIService) {},
+			expectCode:   http.StatusPreconditionFailed,
+			expectHeader: map[string]string{"Tus-Version": "1.0.0"},
+		},
+		{
+			name:         "Given OPTIONS request should return supported extensions and status code No Content",
+			method:       http.MethodOptions,
+			route:        "/uploads",
+			setup:        func(_ *mocks.Mockupload_serviceIService) {},
+			expectCode:   http.StatusNoContent,
+			expectHeader: map[string]string{"Tus-Extension": "creation,expiration,termination"},
+		},
+		{
+			name:   "Given creation request without url in metadata should return status code Bad Request",
+			method: http.MethodPost,
+			route:  "/uploads",
+			header: map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": "filename ZmlsZS56aXA=,description ZGVzYw==,permanent dHJ1ZQ==",
+			},
+			setup:      func(_ *mocks.Mockupload_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Given creation request with a path in the filename should return status code Bad Request",
+			method: http.MethodPost,
+			route:  "/uploads",
+			header: map[string]string{
+				"Upload-Length": "10",
+				// base64 of '../../.bashrc'
+				"Upload-Metadata": "filename Li4vLi4vLmJhc2hyYw==,url em9vbQ==,description ZGVzYw==,permanent dHJ1ZQ==",
+			},
+			setup:      func(_ *mocks.Mockupload_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Given creation request with CRLF in the filename should return status code Bad Request",
+			method: http.MethodPost,
+			route:  "/uploads",
+			header: map[string]string{
+				"Upload-Length": "10",
+				// base64 of 'a\r\nb.txt'
+				"Upload-Metadata": "filename YQ0KYi50eHQ=,url em9vbQ==,description ZGVzYw==,permanent dHJ1ZQ==",
+			},
+			setup:      func(_ *mocks.Mockupload_serviceIService) {},
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Given valid creation request should return the upload location and status code Created",
+			method: http.MethodPost,
+			route:  "/uploads",
+			header: map[string]string{"Upload-Length": "10", "Upload-Metadata": sampleMeta},
+			setup: func(svc *mocks.Mockupload_serviceIService) {
+				req := &requests.Upload{
+					Length:      10,
+					Metadata:    sampleMeta,
+					Filename:    "file.zip",
+					Url:         "zoom",
+					Description: "desc",
+					Permanent:   "true",
+				}
+				svc.EXPECT().
+					Create(mock.Anything, req).
+					Return(&responses.UploadResponse{ID: "abc", Length: 10, ExpiresAt: &exp}, nil).
+					Once()
+			},
+			expectCode: http.StatusCreated,
+			expectHeader: map[string]string{
+				"Location":       "http://example.com/uploads/abc",
+				"Upload-Offset":  "0",
+				"Upload-Expires": "Wed, 28 Apr 2021 11:27:45 GMT",
+			},
+		},
+		{
+			name:   "Given upload that is not exist should return status code Not Found",
+			method: http.MethodHead,
+			route:  "/uploads/abc",
+			setup: func(svc *mocks.Mockupload_serviceIService) {
+				svc.EXPECT().
+					Get(mock.Anything, "abc").
+					Return(nil, upload_service.ErrNotFound).
+					Once()
+			},
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:   "Given existing upload should return the current offset and status code OK",
+			method: http.MethodHead,
+			route:  "/uploads/abc",
+			setup: func(svc *mocks.Mockupload_serviceIService) {
+				svc.EXPECT().
+					Get(mock.Anything, "abc").
+					Return(&responses.UploadResponse{ID: "abc", Length: 10, Offset: 4}, nil).
+					Once()
+			},
+			expectCode:   http.StatusOK,
+			expectHeader: map[string]string{"Upload-Offset": "4", "Upload-Length": "10", "Cache-Control": "no-store"},
+		},
+		{
+			name:       "Given chunk with wrong content type should return status code Unsupported Media Type",
+			method:     http.MethodPatch,
+			route:      "/uploads/abc",
+			header:     map[string]string{"Upload-Offset": "0", "Content-Type": "application/json"},
+			setup:      func(_ *mocks.Mockupload_serviceIService) {},
+			expectCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "Given chunk with offset that does not match should return status code Conflict",
+			method: http.MethodPatch,
+			route:  "/uploads/abc",
+			header: map[string]string{"Upload-Offset": "3", "Content-Type": "application/offset+octet-stream"},
+			setup: func(svc *mocks.Mockupload_serviceIService) {
+				svc.EXPECT().
+					Write(mock.Anything, "abc", int64(3), mock.Anything).
+					Return(nil, upload_service.ErrOffsetMismatch).
+					Once()
+			},
+			expectCode: http.StatusConflict,
+		},
+		{
+			name:   "Given last chunk of upload that fail to be finalized should return status code Service Unavailable",
+			method: http.MethodPatch,
+			route:  "/uploads/abc",
+			header: map[string]string{"Upload-Offset": "10", "Content-Type": "application/offset+octet-stream"},
+			setup: func(svc *mocks.Mockupload_serviceIService) {
+				svc.EXPECT().
+					Write(mock.Anything, "abc", int64(10), mock.Anything).
+					Return(nil, upload_service.ErrFinalize).
+					Once()
+			},
+			expectCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "Given valid chunk should return the new offset and status code No Content",
+			method: http.MethodPatch,
+			route:  "/uploads/abc",
+			header: map[string]string{"Upload-Offset": "4", "Content-Type": "application/offset+octet-stream"},
+			setup: func(svc *mocks.Mockupload_serviceIService) {
+				svc.EXPECT().
+					Write(mock.Anything, "abc", int64(4), mock.Anything).
+					Return(&responses.UploadResponse{ID: "abc", Length: 10, Offset: 8}, nil).
+					Once()
+			},
+			expectCode:   http.StatusNoContent,
+			expectHeader: map[string]string{"Upload-Offset": "8"},
+		},
+		{
+			name:   "Given termination request should return status code No Content",
+			method: http.MethodDelete,
+			route:  "/uploads/abc",
+			setup: func(svc *mocks.Mockupload_serviceIService) {
+				svc.EXPECT().
+					Delete(mock.Anything, "abc").
+					Return(nil).
+					Once()
+			},
+			expectCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			upload_handler.New(h.App, h.V, h.Dep.uploadSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.uploadSvc)
+
+			// setup request
+			req := h.setupTusReq(tc.method, tc.route, bytes.NewBufferString("chunk"))
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+			h.Dep.uploadSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUploadHandler_Idempotency(t *testing.T) {
+	testCases := []struct {
+		name         string
+		setup        func(*mocks.Mockupload_serviceIService, *idemMocks.Mockidempotency_serviceIService)
+		expectCode   int
+		expectHeader map[string]string
+	}{
+		{
+			name: "Given key for the first time should start the upload then keep its location",
+			setup: func(svc *mocks.Mockupload_serviceIService, idem *idemMocks.Mockidempotency_serviceIService) {
+				idem.EXPECT().Begin(mock.Anything, "retry-1", mock.Anything).Return(nil, nil).Once()
+				svc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(&responses.UploadResponse{ID: "abc", Length: 10}, nil).
+					Once()
+				idem.EXPECT().
+					Finish(mock.Anything, "retry-1", mock.MatchedBy(func(r *responses.IdempotentResponse) bool {
+						return r.StatusCode == http.StatusCreated && r.Location == "http://example.com/uploads/abc"
+					})).
+					Return().
+					Once()
+			},
+			expectCode:   http.StatusCreated,
+			expectHeader: map[string]string{"Location": "http://example.com/uploads/abc"},
+		},
+		{
+			name: "Given key of a finished request should replay its location without starting another upload",
+			setup: func(_ *mocks.Mockupload_serviceIService, idem *idemMocks.Mockidempotency_serviceIService) {
+				idem.EXPECT().
+					Begin(mock.Anything, "retry-1", mock.Anything).
+					Return(&responses.IdempotentResponse{
+						StatusCode: http.StatusCreated,
+						Location:   "http://example.com/uploads/abc",
+					}, nil).
+					Once()
+			},
+			expectCode:   http.StatusCreated,
+			expectHeader: map[string]string{"Idempotent-Replayed": "true", "Location": "http://example.com/uploads/abc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// run necessary setup
+			h := setupHelperTest(defaultViper())
+			upload_handler.New(h.App, h.V, h.Dep.uploadSvc, h.Dep.idemSvc)
+			tc.setup(h.Dep.uploadSvc, h.Dep.idemSvc)
+
+			// setup request
+			req := h.setupTusReq(http.MethodPost, "/uploads", nil)
+			req.Header.Set("Upload-Length", "10")
+			req.Header.Set("Upload-Metadata", "filename ZmlsZS56aXA=,url em9vbQ==,description ZGVzYw==,permanent dHJ1ZQ==")
+			req.Header.Set("Idempotency-Key", "retry-1")
+			res, _ := h.App.Test(req)
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			for k, v := range tc.expectHeader {
+				assert.Equal(t, v, res.Header.Get(k))
+			}
+			h.Dep.uploadSvc.AssertExpectations(t)
+			h.Dep.idemSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -3,15 +3,34 @@ package app
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/mdanialr/sns_backend/internal/app/adapter/http/auth_handler"
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/paste_handler"
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/public_handler"
 	"github.com/mdanialr/sns_backend/internal/app/adapter/http/send_handler"
 	"github.com/mdanialr/sns_backend/internal/app/adapter/http/shorten_handler"
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/tag_handler"
+	"github.com/mdanialr/sns_backend/internal/app/adapter/http/upload_handler"
+	"github.com/mdanialr/sns_backend/internal/core/repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/blob_repository"
 	"github.com/mdanialr/sns_backend/internal/core/repository/otp_repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/revision_repository"
 	"github.com/mdanialr/sns_backend/internal/core/repository/sns_repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/tag_repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/upload_repository"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
 	"github.com/mdanialr/sns_backend/internal/core/service/otp_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/paste_service"
 	"github.com/mdanialr/sns_backend/internal/core/service/send_service"
 	"github.com/mdanialr/sns_backend/internal/core/service/shorten_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/tag_service"
+	"github.com/mdanialr/sns_backend/internal/core/service/upload_service"
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	"github.com/mdanialr/sns_backend/pkg/linkcheck"
 	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/mdanialr/sns_backend/pkg/openapi"
+	"github.com/mdanialr/sns_backend/pkg/scanner"
 	"github.com/mdanialr/sns_backend/pkg/storage"
+	"github.com/mdanialr/sns_backend/pkg/thumbnail"
+	"github.com/mdanialr/sns_backend/pkg/urlpolicy"
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
@@ -23,6 +42,24 @@ type HttpHandlers struct {
 	Config  *viper.Viper
 	Log     logger.Writer
 	Storage storage.IStorage
+	// Scanner antivirus scanner for uploaded files, nil if it's disabled.
+	Scanner scanner.IScanner
+	// Thumbnail background pool to generate image thumbnails, nil if it's
+	// disabled.
+	Thumbnail *thumbnail.Pool
+	// Domains the public domains that serve the public endpoints.
+	Domains *domains.Domains
+	// UrlPolicy decide which destination is accepted for a Shorten.
+	UrlPolicy *urlpolicy.Policy
+	// LinkCheck periodically check the destination of each Shorten, nil if
+	// it's disabled.
+	LinkCheck *linkcheck.Checker
+	// Idempotency keep the responses of create requests that are retried
+	// using the same idempotency key.
+	Idempotency idempotency_service.IService
+	// Public router without any prefix for endpoints that are accessed by
+	// anyone such as downloading a Send.
+	Public fiber.Router
 }
 
 func (h *HttpHandlers) SetupRouter() {
@@ -32,20 +69,44 @@ func (h *HttpHandlers) SetupRouter() {
 	h.R.Get("/", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).SendString("SNS Backend API")
 	})
+	// the document of every route, which is described in spec
+	docs := spec()
+	h.R.Get("/openapi.json", docs.Handler())
+	h.R.Get("/docs", openapi.UI(docs.Title, "/api/openapi.json", openapi.Script{
+		Src:       h.Config.GetString("docs.redoc_src"),
+		Integrity: h.Config.GetString("docs.redoc_integrity"),
+	}))
 	// currently use v1
 	apiV1 := h.R.Group("/v1")
 
 	// init repositories
 	otpRepo := otp_repository.New(h.DB)
 	snsRepo := sns_repository.New(h.DB)
+	blobRepo := blob_repository.New(h.DB)
+	uploadRepo := upload_repository.New(h.DB)
+	revRepo := revision_repository.New(h.DB)
+	tagRepo := tag_repository.New(h.DB)
+	tx := repository.NewTransactor(h.DB)
 
 	// init services
 	otpSvc := otp_service.New(h.Config, h.Log, otpRepo)
-	snsSvc := shorten_service.New(h.Log, snsRepo)
-	sendSvc := send_service.New(h.Log, h.Storage, h.Config, snsRepo)
+	snsSvc := shorten_service.New(h.Log, snsRepo, revRepo, tx, h.Domains, h.UrlPolicy, h.LinkCheck)
+	sendSvc := send_service.New(h.Log, h.Storage, h.Config, snsRepo, blobRepo, revRepo, tx, h.Scanner, h.Thumbnail, h.Domains)
+	pasteSvc := paste_service.New(h.Log, snsRepo, h.Domains)
+	tagSvc := tag_service.New(h.Log, tagRepo)
+	uploadSvc := upload_service.New(h.Log, h.Storage, h.Config, uploadRepo, snsRepo, sendSvc, h.Domains)
+	idemSvc := h.Idempotency
+
+	// start checking the destination of each Shorten in the background
+	h.LinkCheck.Start(snsSvc.CheckLinks)
 
 	// init handlers
-	auth_handler.New(apiV1, otpSvc)              // /auth/*
-	shorten_handler.New(apiV1, h.Config, snsSvc) // /shorten/*
-	send_handler.New(apiV1, h.Config, sendSvc)   // /send/*
+	auth_handler.New(apiV1, otpSvc)                         // /auth/*
+	shorten_handler.New(apiV1, h.Config, snsSvc, idemSvc)   // /shorten/*
+	send_handler.New(apiV1, h.Config, sendSvc, idemSvc)     // /send/*
+	upload_handler.New(apiV1, h.Config, uploadSvc, idemSvc) // /uploads/*
+	paste_handler.New(apiV1, h.Config, pasteSvc, idemSvc)   // /paste/*
+	tag_handler.New(apiV1, h.Config, tagSvc, idemSvc)       // /tags/*
+	// public handlers should be the last since it catch any path
+	public_handler.New(h.Public, h.Domains, snsSvc, sendSvc, pasteSvc) // /:url/*
 }
@@ -0,0 +1,442 @@
+package app
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/openapi"
+	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
+	resp "github.com/mdanialr/sns_backend/pkg/response"
+)
+
+// v1 the prefix of every route of the current version of the API.
+const v1 = "/api/v1"
+
+// the request headers that are read by the middlewares instead of parsed by
+// the handlers.
+type (
+	ifMatch struct {
+		IfMatch string `reqHeader:"If-Match"`
+	}
+	idempotencyKey struct {
+		Key string `reqHeader:"Idempotency-Key" validate:"omitempty,max=255"`
+	}
+	tusResumable struct {
+		Version string `reqHeader:"Tus-Resumable" validate:"required,oneof=1.0.0"`
+	}
+	tusCreate struct {
+		tusResumable
+		idempotencyKey
+		requests.Upload
+	}
+	tusPatch struct {
+		tusResumable
+		Offset int64 `reqHeader:"Upload-Offset" validate:"required,min=0"`
+	}
+)
+
+// link the request and response types of a kind of link that share the same
+// routes, which are Shorten and Send.
+type link struct {
+	// path the path of the routes within v1.
+	path string
+	// tag the name of the kind of link.
+	tag string
+	// bodyType the media type of the request body to create or update it.
+	bodyType string
+	// the request of each route.
+	index, create, get, update, patch, delete any
+	// data & list the link and the list of links in the response.
+	data, list any
+}
+
+// spec describe every route in the API, a route can't be registered without
+// being described here.
+func spec() *openapi.Spec {
+	success, failed := resp.Schemas()
+	s := &openapi.Spec{
+		Title:       "SNS Backend API",
+		Description: "Shorten, send and paste links that are managed by a single user.",
+		Version:     "1.0.0",
+		Success:     success,
+		Error:       failed,
+		Operations: map[string]openapi.Operation{
+			openapi.Key(fiber.MethodGet, "/api"): {
+				Summary: "Greet the client",
+				Tag:     "App",
+				Content: []string{fiber.MIMETextPlain},
+			},
+			openapi.Key(fiber.MethodGet, "/api/health"): {
+				Summary: "Check whether the API is up",
+				Tag:     "App",
+				Content: []string{fiber.MIMETextPlain},
+			},
+			openapi.Key(fiber.MethodGet, "/api/openapi.json"): {
+				Summary: "Retrieve this document",
+				Tag:     "App",
+				Content: []string{fiber.MIMEApplicationJSON},
+			},
+			openapi.Key(fiber.MethodGet, "/api/docs"): {
+				Summary: "Read this document in the browser",
+				Tag:     "App",
+				Content: []string{fiber.MIMETextHTML},
+			},
+			openapi.Key(fiber.MethodPost, v1+"/auth/otp"): {
+				Summary: "Exchange a TOTP code with a JWT",
+				Tag:     "Auth",
+				Body:    requests.OTP{},
+				Data:    map[string]string{},
+				Errors:  []int{fiber.StatusBadRequest},
+			},
+		},
+	}
+
+	ops := []map[string]openapi.Operation{
+		linkOperations(link{
+			path:   "/shorten",
+			tag:    "Shorten",
+			index:  requests.Shorten{},
+			create: requests.Shorten{},
+			get:    requests.ShortenGet{},
+			update: requests.ShortenUpdate{},
+			patch:  requests.ShortenPatch{},
+			delete: requests.ShortenDelete{},
+			data:   res.ShortenResponse{},
+			list:   []res.ShortenResponse{},
+		}),
+		linkOperations(link{
+			path:     "/send",
+			tag:      "Send",
+			bodyType: fiber.MIMEMultipartForm,
+			index:    requests.Send{},
+			create:   requests.Send{},
+			get:      requests.SendGet{},
+			update:   requests.SendUpdate{},
+			patch:    requests.SendPatch{},
+			delete:   requests.SendDelete{},
+			data:     res.SendResponse{},
+			list:     []res.SendResponse{},
+		}),
+		shortenOperations(),
+		pasteOperations(),
+		tagOperations(),
+		uploadOperations(),
+		publicOperations(),
+	}
+	for _, op := range ops {
+		for k, o := range op {
+			s.Operations[k] = o
+		}
+	}
+
+	return s
+}
+
+// linkOperations describe the routes of given kind of link.
+func linkOperations(l link) map[string]openapi.Operation {
+	p := v1 + l.path
+	create := openapi.Operation{
+		Summary:         "Create a " + l.tag,
+		Tag:             l.tag,
+		Secured:         true,
+		Header:          idempotencyKey{},
+		Body:            l.create,
+		BodyType:        l.bodyType,
+		Data:            l.data,
+		ResponseHeaders: []string{fiber.HeaderETag},
+		Errors:          []int{fiber.StatusBadRequest, fiber.StatusConflict, fiber.StatusUnprocessableEntity},
+	}
+	update := openapi.Operation{
+		Summary:         "Replace a " + l.tag,
+		Tag:             l.tag,
+		Secured:         true,
+		Header:          ifMatch{},
+		Body:            l.update,
+		BodyType:        l.bodyType,
+		Data:            l.data,
+		ResponseHeaders: []string{fiber.HeaderETag},
+		Errors:          []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusPreconditionFailed, fiber.StatusPreconditionRequired},
+	}
+	del := openapi.Operation{
+		Summary: "Delete a " + l.tag,
+		Tag:     l.tag,
+		Secured: true,
+		Header:  ifMatch{},
+		Body:    l.delete,
+		Errors:  []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusPreconditionFailed, fiber.StatusPreconditionRequired},
+	}
+	patch := update
+	patch.Summary = "Change some fields of a " + l.tag
+	patch.Body = l.patch
+
+	return map[string]openapi.Operation{
+		openapi.Key(fiber.MethodGet, p): {
+			Summary: "List every " + l.tag,
+			Tag:     l.tag,
+			Secured: true,
+			Query:   l.index,
+			Data:    l.list,
+			Meta:    paginate.M{},
+			Errors:  []int{fiber.StatusBadRequest},
+		},
+		openapi.Key(fiber.MethodPost, p): create,
+		openapi.Key(fiber.MethodGet, p+"/:id"): {
+			Summary:         "Retrieve a " + l.tag,
+			Tag:             l.tag,
+			Secured:         true,
+			Params:          l.get,
+			Data:            l.data,
+			ResponseHeaders: []string{fiber.HeaderETag},
+			Errors:          []int{fiber.StatusBadRequest, fiber.StatusNotFound},
+		},
+		openapi.Key(fiber.MethodPut, p+"/:id"):     update,
+		openapi.Key(fiber.MethodPatch, p+"/:id"):   patch,
+		openapi.Key(fiber.MethodDelete, p+"/:id"):  del,
+		openapi.Key(fiber.MethodPost, p+"/create"): legacy(create),
+		openapi.Key(fiber.MethodPost, p+"/update"): legacy(update),
+		openapi.Key(fiber.MethodPost, p+"/delete"): legacy(del),
+		openapi.Key(fiber.MethodGet, p+"/:id/qr"): {
+			Summary:  "Render the QR code of the public url of a " + l.tag,
+			Tag:      l.tag,
+			Secured:  true,
+			Query:    requests.QR{},
+			Params:   requests.QR{},
+			Content:  []string{"image/png", "image/svg+xml"},
+			Statuses: []int{fiber.StatusNotModified},
+			Errors:   []int{fiber.StatusBadRequest, fiber.StatusNotFound},
+		},
+		openapi.Key(fiber.MethodGet, p+"/:id/revisions"): {
+			Summary: "List every revision of a " + l.tag,
+			Tag:     l.tag,
+			Secured: true,
+			Params:  requests.Revisions{},
+			Data:    []res.RevisionResponse{},
+			Errors:  []int{fiber.StatusBadRequest, fiber.StatusNotFound},
+		},
+		openapi.Key(fiber.MethodPost, p+"/:id/rollback"): {
+			Summary:         "Restore a " + l.tag + " to the values right after a revision",
+			Tag:             l.tag,
+			Secured:         true,
+			Header:          ifMatch{},
+			Body:            requests.Rollback{},
+			Data:            l.data,
+			ResponseHeaders: []string{fiber.HeaderETag},
+			Errors: []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusPreconditionFailed,
+				fiber.StatusPreconditionRequired},
+		},
+	}
+}
+
+// shortenOperations describe the routes that only Shorten has.
+func shortenOperations() map[string]openapi.Operation {
+	return map[string]openapi.Operation{
+		openapi.Key(fiber.MethodGet, v1+"/shorten/:id/variants"): {
+			Summary: "List the variants of a Shorten along with their hits",
+			Tag:     "Shorten",
+			Secured: true,
+			Params:  requests.ShortenVariants{},
+			Data:    []res.ShortenVariantItem{},
+			Errors:  []int{fiber.StatusBadRequest, fiber.StatusNotFound},
+		},
+	}
+}
+
+// pasteOperations describe the routes of Paste.
+func pasteOperations() map[string]openapi.Operation {
+	p := v1 + "/paste"
+	return map[string]openapi.Operation{
+		openapi.Key(fiber.MethodGet, p): {
+			Summary: "List every Paste",
+			Tag:     "Paste",
+			Secured: true,
+			Query:   requests.Paste{},
+			Data:    []res.PasteResponse{},
+			Meta:    paginate.M{},
+			Errors:  []int{fiber.StatusBadRequest},
+		},
+		openapi.Key(fiber.MethodPost, p+"/create"): {
+			Summary: "Create a Paste",
+			Tag:     "Paste",
+			Secured: true,
+			Header:  idempotencyKey{},
+			Body:    requests.Paste{},
+			Data:    res.PasteResponse{},
+			Errors:  []int{fiber.StatusBadRequest, fiber.StatusConflict, fiber.StatusUnprocessableEntity},
+		},
+		openapi.Key(fiber.MethodPost, p+"/update"): {
+			Summary: "Replace a Paste",
+			Tag:     "Paste",
+			Secured: true,
+			Body:    requests.PasteUpdate{},
+			Data:    res.PasteResponse{},
+			Errors:  []int{fiber.StatusBadRequest},
+		},
+		openapi.Key(fiber.MethodPost, p+"/delete"): {
+			Summary: "Delete a Paste",
+			Tag:     "Paste",
+			Secured: true,
+			Body:    requests.PasteDelete{},
+			Errors:  []int{fiber.StatusBadRequest},
+		},
+	}
+}
+
+// tagOperations describe the routes of tags.
+func tagOperations() map[string]openapi.Operation {
+	p := v1 + "/tags"
+	create := openapi.Operation{
+		Summary: "Create a tag",
+		Tag:     "Tags",
+		Secured: true,
+		Header:  idempotencyKey{},
+		Body:    requests.Tag{},
+		Data:    res.TagResponse{},
+		Errors:  []int{fiber.StatusBadRequest, fiber.StatusConflict, fiber.StatusUnprocessableEntity},
+	}
+	update := openapi.Operation{
+		Summary: "Rename a tag",
+		Tag:     "Tags",
+		Secured: true,
+		Body:    requests.TagUpdate{},
+		Data:    res.TagResponse{},
+		Errors:  []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusConflict},
+	}
+	del := openapi.Operation{
+		Summary: "Delete a tag and remove it from every link",
+		Tag:     "Tags",
+		Secured: true,
+		Body:    requests.TagDelete{},
+		Errors:  []int{fiber.StatusBadRequest, fiber.StatusNotFound},
+	}
+
+	return map[string]openapi.Operation{
+		openapi.Key(fiber.MethodGet, p): {
+			Summary: "List every tag along with how many links use it",
+			Tag:     "Tags",
+			Secured: true,
+			Data:    []res.TagResponse{},
+		},
+		openapi.Key(fiber.MethodPost, p):           create,
+		openapi.Key(fiber.MethodPut, p+"/:id"):     update,
+		openapi.Key(fiber.MethodDelete, p+"/:id"):  del,
+		openapi.Key(fiber.MethodPost, p+"/create"): legacy(create),
+		openapi.Key(fiber.MethodPost, p+"/update"): legacy(update),
+		openapi.Key(fiber.MethodPost, p+"/delete"): legacy(del),
+	}
+}
+
+// legacy mark given operation of a legacy route that has the id in the body
+// as deprecated.
+func legacy(op openapi.Operation) openapi.Operation {
+	op.Deprecated = true
+	op.Description = "Deprecated, use the route that has the id in the path instead."
+	return op
+}
+
+// uploadOperations describe the routes of tus resumable upload.
+func uploadOperations() map[string]openapi.Operation {
+	p := v1 + "/uploads"
+	errs := []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusPreconditionFailed}
+	return map[string]openapi.Operation{
+		openapi.Key(fiber.MethodOptions, p): {
+			Summary:         "Retrieve the tus configuration of the server",
+			Tag:             "Uploads",
+			Secured:         true,
+			Status:          fiber.StatusNoContent,
+			Empty:           true,
+			ResponseHeaders: []string{"Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size"},
+		},
+		openapi.Key(fiber.MethodPost, p): {
+			Summary:         "Start a resumable upload of a Send",
+			Description:     "Upload-Metadata should have the filename, url, description and permanent of the Send.",
+			Tag:             "Uploads",
+			Secured:         true,
+			Header:          tusCreate{},
+			Status:          fiber.StatusCreated,
+			Empty:           true,
+			ResponseHeaders: []string{fiber.HeaderLocation, "Upload-Offset", "Upload-Expires"},
+			Errors:          []int{fiber.StatusBadRequest, fiber.StatusPreconditionFailed, fiber.StatusRequestEntityTooLarge},
+		},
+		openapi.Key(fiber.MethodHead, p+"/:id"): {
+			Summary:         "Retrieve the offset of an upload",
+			Tag:             "Uploads",
+			Secured:         true,
+			Header:          tusResumable{},
+			Empty:           true,
+			ResponseHeaders: []string{"Upload-Offset", "Upload-Length", "Upload-Metadata", "Upload-Expires"},
+			Errors:          errs,
+		},
+		openapi.Key(fiber.MethodPatch, p+"/:id"): {
+			Summary:         "Append a chunk to an upload",
+			Description:     "The Send is created once the last chunk is appended. If it fails with 503, the upload is kept and may be finalized again by appending an empty chunk at the final offset.",
+			Tag:             "Uploads",
+			Secured:         true,
+			Header:          tusPatch{},
+			Body:            []byte{},
+			BodyType:        "application/offset+octet-stream",
+			Status:          fiber.StatusNoContent,
+			Empty:           true,
+			ResponseHeaders: []string{"Upload-Offset", "Upload-Expires"},
+			Errors:          append(errs, fiber.StatusConflict, fiber.StatusRequestEntityTooLarge, fiber.StatusUnsupportedMediaType, fiber.StatusServiceUnavailable),
+		},
+		openapi.Key(fiber.MethodDelete, p+"/:id"): {
+			Summary: "Terminate an upload",
+			Tag:     "Uploads",
+			Secured: true,
+			Header:  tusResumable{},
+			Status:  fiber.StatusNoContent,
+			Empty:   true,
+			Errors:  errs,
+		},
+	}
+}
+
+// publicOperations describe the routes that serve the links to anyone within
+// the public domains.
+func publicOperations() map[string]openapi.Operation {
+	file := []string{fiber.HeaderETag, fiber.HeaderLastModified, fiber.HeaderContentDisposition, fiber.HeaderAcceptRanges,
+		fiber.HeaderContentSecurityPolicy, fiber.HeaderXContentTypeOptions}
+	ranges := []int{fiber.StatusPartialContent, fiber.StatusNotModified, fiber.StatusRequestedRangeNotSatisfiable}
+	gone := []int{fiber.StatusBadRequest, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusGone}
+	return map[string]openapi.Operation{
+		openapi.Key(fiber.MethodGet, "/:url"): {
+			Summary:         "Visit a link",
+			Description:     "Redirect to the destination of a Shorten, otherwise serve the file of a Send.",
+			Tag:             "Public",
+			Content:         []string{fiber.MIMEOctetStream},
+			ResponseHeaders: file,
+			Statuses:        append([]int{fiber.StatusFound}, ranges...),
+			Errors:          gone,
+		},
+		openapi.Key(fiber.MethodGet, "/:url/thumbnail"): {
+			Summary:  "Retrieve the thumbnail of an image Send",
+			Tag:      "Public",
+			Content:  []string{"image/jpeg", "image/png"},
+			Statuses: ranges,
+			Errors:   gone,
+		},
+		openapi.Key(fiber.MethodGet, "/:url/files"): {
+			Summary:         "Download every file of a Send as a zip archive",
+			Tag:             "Public",
+			Content:         []string{"application/zip"},
+			ResponseHeaders: []string{fiber.HeaderLastModified, fiber.HeaderContentDisposition},
+			Errors:          gone,
+		},
+		openapi.Key(fiber.MethodGet, "/:url/files/:name"): {
+			Summary:         "Download a single file of a Send",
+			Tag:             "Public",
+			Content:         []string{fiber.MIMEOctetStream},
+			ResponseHeaders: file,
+			Statuses:        ranges,
+			Errors:          gone,
+		},
+		openapi.Key(fiber.MethodGet, "/:url/raw"): {
+			Summary:         "Read the text of a Paste",
+			Tag:             "Public",
+			Content:         []string{fiber.MIMETextPlainCharsetUTF8},
+			ResponseHeaders: []string{fiber.HeaderLastModified},
+			Statuses:        []int{fiber.StatusNotModified},
+			Errors:          []int{fiber.StatusBadRequest, fiber.StatusNotFound},
+		},
+	}
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupApp return the app that has every route registered just like the
+// server does, but without any real dependency.
+func setupApp() *fiber.App {
+	app := fiber.New()
+	h := HttpHandlers{
+		R:      app.Group("/api"),
+		Public: app,
+		Config: viper.New(),
+		Log:    logger.NewStdOut(),
+	}
+	h.SetupRouter()
+
+	return app
+}
+
+func TestSpec_EveryRouteIsDescribed(t *testing.T) {
+	routes := setupApp().GetRoutes(true)
+	s := spec()
+
+	assert.Empty(t, s.Missing(routes), "every route should be described in spec")
+	assert.Empty(t, s.Unused(routes), "every operation in spec should have a route")
+}
+
+func TestHttpHandlers_OpenAPI(t *testing.T) {
+	app := setupApp()
+
+	testCases := []struct {
+		name        string
+		route       string
+		contentType string
+	}{
+		{
+			name:        "Given request to the document should respond with json",
+			route:       "/api/openapi.json",
+			contentType: fiber.MIMEApplicationJSON,
+		},
+		{
+			name:        "Given request to the docs page should respond with html",
+			route:       "/api/docs",
+			contentType: fiber.MIMETextHTMLCharsetUTF8,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := app.Test(httptest.NewRequest(fiber.MethodGet, tc.route, nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusOK, res.StatusCode)
+			assert.Equal(t, tc.contentType, res.Header.Get(fiber.HeaderContentType))
+		})
+	}
+
+	t.Run("Given the document should describe the routes of every kind of link", func(t *testing.T) {
+		res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/openapi.json", nil))
+		require.NoError(t, err)
+		b, _ := io.ReadAll(res.Body)
+
+		var doc struct {
+			OpenAPI string                    `json:"openapi"`
+			Paths   map[string]map[string]any `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal(b, &doc))
+		assert.Equal(t, "3.0.3", doc.OpenAPI)
+		for _, p := range []string{"/api/v1/shorten/{id}", "/api/v1/send/{id}", "/api/v1/paste", "/api/v1/tags", "/api/v1/uploads/{id}", "/{url}"} {
+			assert.Contains(t, doc.Paths, p)
+		}
+		assert.Contains(t, doc.Paths["/api/v1/uploads/{id}"], "head")
+		assert.NotContains(t, doc.Paths["/api/v1/shorten/{id}"], "head")
+	})
+}
@@ -0,0 +1,65 @@
+package blob_repository
+
+import (
+	"context"
+
+	r "github.com/mdanialr/sns_backend/internal/core/repository"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type blobRepo struct {
+	db *gorm.DB
+}
+
+// New return implementation that can be used to interact with object
+// domain.Blob.
+func New(db *gorm.DB) IRepository {
+	return &blobRepo{db}
+}
+
+func (b *blobRepo) Acquire(ctx context.Context, blob *domain.Blob) (*domain.Blob, bool, error) {
+	var isNew bool
+	err := r.DB(ctx, b.db).Transaction(func(tx *gorm.DB) error {
+		blob.RefCount = 1
+		// insert as a new blob, leave it as is if the hash is already there
+		q := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(blob)
+		if q.Error != nil {
+			return q.Error
+		}
+		if isNew = q.RowsAffected > 0; isNew {
+			return nil
+		}
+		// otherwise add the reference to the existing one
+		return tx.Model(blob).
+			Clauses(clause.Returning{}).
+			Where("hash = ?", blob.Hash).
+			Update("ref_count", gorm.Expr("ref_count + 1")).Error
+	})
+
+	return blob, isNew, err
+}
+
+func (b *blobRepo) Release(ctx context.Context, hash string) (*domain.Blob, error) {
+	var blob domain.Blob
+	err := r.DB(ctx, b.db).Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&blob).
+			Clauses(clause.Returning{}).
+			Where("hash = ? AND ref_count > 0", hash).
+			Update("ref_count", gorm.Expr("ref_count - 1"))
+		if q.Error != nil {
+			return q.Error
+		}
+		if q.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// purge the blob once nothing refers to it anymore
+		if blob.RefCount == 0 {
+			return tx.Delete(&blob).Error
+		}
+		return nil
+	})
+
+	return &blob, err
+}
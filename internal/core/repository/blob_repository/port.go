@@ -0,0 +1,22 @@
+package blob_repository
+
+import (
+	"context"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// IRepository an interface that should be used when dealing with object
+// domain.Blob. Every method join the transaction carried by the context, if
+// any.
+type IRepository interface {
+	// Acquire increment the reference count of the domain.Blob that has the
+	// same hash as given blob or save given blob with reference count 1 if
+	// there is none yet. Return the stored domain.Blob and true if it's newly
+	// created.
+	Acquire(ctx context.Context, blob *domain.Blob) (*domain.Blob, bool, error)
+	// Release decrement the reference count of the domain.Blob that has given
+	// hash and delete it once there is no reference left. Return the released
+	// domain.Blob that has zero RefCount if it's already deleted.
+	Release(ctx context.Context, hash string) (*domain.Blob, error)
+}
@@ -0,0 +1,49 @@
+package idempotency_repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepo struct {
+	db *gorm.DB
+}
+
+// New return implementation that can be used to interact with object
+// domain.IdempotencyKey.
+func New(db *gorm.DB) IRepository {
+	return &idempotencyRepo{db}
+}
+
+func (i *idempotencyRepo) GetByKey(ctx context.Context, key string) (*domain.IdempotencyKey, error) {
+	k := domain.IdempotencyKey{Key: key}
+	return &k, i.db.WithContext(ctx).First(&k).Error
+}
+
+func (i *idempotencyRepo) Create(ctx context.Context, k *domain.IdempotencyKey) error {
+	// concurrent requests that use the same key race here, only one of them
+	// may insert it
+	res := i.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(k)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrExists
+	}
+	return res.Error
+}
+
+func (i *idempotencyRepo) Update(ctx context.Context, k *domain.IdempotencyKey) error {
+	return i.db.WithContext(ctx).
+		Select("status_code", "content_type", "etag", "location", "body", "expires_at").
+		Updates(k).Error
+}
+
+func (i *idempotencyRepo) DeleteByKey(ctx context.Context, key string) error {
+	return i.db.WithContext(ctx).Delete(&domain.IdempotencyKey{Key: key}).Error
+}
+
+func (i *idempotencyRepo) DeleteExpired(ctx context.Context, t time.Time) error {
+	return i.db.WithContext(ctx).Where("expires_at <= ?", t).Delete(&domain.IdempotencyKey{}).Error
+}
@@ -0,0 +1,51 @@
+package idempotency_repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRun return db that only build the statements, the last one is given to
+// fn.
+func dryRun(t *testing.T, fn func(*gorm.Statement)) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:statement", func(tx *gorm.DB) {
+		fn(tx.Statement)
+	}))
+	return db
+}
+
+func TestIdempotencyRepo_Update(t *testing.T) {
+	var stmt *gorm.Statement
+	repo := New(dryRun(t, func(s *gorm.Statement) { stmt = s }))
+	exp := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	k := &domain.IdempotencyKey{
+		Key:         "k3y",
+		StatusCode:  201,
+		ContentType: "application/json",
+		ETag:        `"e7a9"`,
+		Location:    "/sns/1",
+		Body:        []byte(`{"id":1}`),
+		ExpiresAt:   &exp,
+	}
+
+	require.NoError(t, repo.Update(context.Background(), k))
+	require.NotNil(t, stmt)
+	// every part of the response is kept so a retry is answered the same way
+	assert.Equal(t, `UPDATE "idempotency_keys" SET "status_code"=$1,"content_type"=$2,"etag"=$3,"location"=$4,"body"=$5,"expires_at"=$6,"updated_at"=$7 WHERE "key" = $8`, stmt.SQL.String())
+	require.Len(t, stmt.Vars, 8)
+	assert.Equal(t, []any{201, "application/json", `"e7a9"`, "/sns/1", []byte(`{"id":1}`), &exp}, stmt.Vars[:6])
+	assert.Equal(t, "k3y", stmt.Vars[7])
+}
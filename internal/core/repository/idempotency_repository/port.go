@@ -0,0 +1,29 @@
+package idempotency_repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// ErrExists the key is already used by other request.
+var ErrExists = errors.New("idempotency key already exists")
+
+// IRepository an interface that should be used when dealing with object
+// domain.IdempotencyKey.
+type IRepository interface {
+	// GetByKey retrieve a domain.IdempotencyKey by given key, also return
+	// error if any including record not found.
+	GetByKey(ctx context.Context, key string) (*domain.IdempotencyKey, error)
+	// Create save given key. Return ErrExists if the key is already saved.
+	Create(ctx context.Context, k *domain.IdempotencyKey) error
+	// Update save the response in given key.
+	Update(ctx context.Context, k *domain.IdempotencyKey) error
+	// DeleteByKey delete an object that's has given key as their primary key.
+	DeleteByKey(ctx context.Context, key string) error
+	// DeleteExpired delete all domain.IdempotencyKey that already expired at
+	// given time.
+	DeleteExpired(ctx context.Context, t time.Time) error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"strings"
+
 	"github.com/mdanialr/sns_backend/pkg/pagination"
 	"gorm.io/gorm"
 )
@@ -13,12 +15,20 @@ type IOptions interface {
 
 type (
 	columns struct{ cols []string }
+	omit    struct{ cols []string }
 	order   struct{ order []string }
 	where   struct{ cons []string }
+	bound   struct {
+		query string
+		args  []any
+	}
+	preload struct{ assocs []string }
 )
 
 func (c *columns) Set(db *gorm.DB) *gorm.DB { return db.Select(c.cols) }
 
+func (o *omit) Set(db *gorm.DB) *gorm.DB { return db.Omit(o.cols...) }
+
 func (o *order) Set(db *gorm.DB) *gorm.DB {
 	for _, ord := range o.order {
 		db = db.Order(ord)
@@ -33,12 +43,28 @@ func (w *where) Set(db *gorm.DB) *gorm.DB {
 	return db
 }
 
+func (b *bound) Set(db *gorm.DB) *gorm.DB { return db.Where(b.query, b.args...) }
+
+func (p *preload) Set(db *gorm.DB) *gorm.DB {
+	for _, assoc := range p.assocs {
+		db = db.Preload(assoc)
+	}
+	return db
+}
+
 // Cols add query Select.
 // Example:
 //
 //	repository.Cols("id","created_at","updated_at")
 func Cols(cols ...string) IOptions { return &columns{cols} }
 
+// Omit add query Omit, mostly to keep columns that are set automatically such
+// as updated_at from being updated.
+// Example:
+//
+//	repository.Omit("updated_at")
+func Omit(cols ...string) IOptions { return &omit{cols} }
+
 // Order add query Order.
 //
 // Example:
@@ -54,7 +80,34 @@ func Order(orders ...string) IOptions { return &order{orders} }
 //	repository.Cons("id IS NULL"), repository.Cons("name IS NOT NULL")
 func Cons(cons ...string) IOptions { return &where{cons} }
 
-// Paginate add query Limit & Offset accordingly by given paginate.M.
+// Where add query Where with given args bound to the placeholders in given
+// query. Use it instead of Cons whenever the condition has a value.
+//
+// Example:
+//
+//	repository.Where("url LIKE ?", "%"+search+"%")
+func Where(query string, args ...any) IOptions { return &bound{query, args} }
+
+// likeEscaper escape the wildcards of LIKE along with its escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escape given s, so it's matched literally when it's part of the
+// pattern of LIKE.
+//
+// Example:
+//
+//	repository.Where("url LIKE ?", "%"+repository.EscapeLike(search)+"%")
+func EscapeLike(s string) string { return likeEscaper.Replace(s) }
+
+// Preload load each given association along with the main object.
+//
+// Example:
+//
+//	repository.Preload("Files")
+func Preload(assocs ...string) IOptions { return &preload{assocs} }
+
+// Paginate add query Limit & Offset accordingly by given paginate.M, or the
+// keyset conditions instead if it uses paginate.ModeCursor.
 //
 // Example:
 //
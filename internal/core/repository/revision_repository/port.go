@@ -0,0 +1,22 @@
+package revision_repository
+
+import (
+	"context"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// IRepository an interface that should be used when dealing with object
+// domain.SNSRevision. Revisions are append-only, so there is no update nor
+// delete.
+type IRepository interface {
+	// FindBySNS retrieve all revisions of the SNS that has given id, the
+	// newest one first.
+	FindBySNS(ctx context.Context, snsID uint) ([]*domain.SNSRevision, error)
+	// GetByID retrieve a domain.SNSRevision by given id that belong to the
+	// SNS that has given sns id, also return error if any including record
+	// not found.
+	GetByID(ctx context.Context, snsID, id uint) (*domain.SNSRevision, error)
+	// Create save given revision. Return the newly saved object.
+	Create(ctx context.Context, rv *domain.SNSRevision) (*domain.SNSRevision, error)
+}
@@ -0,0 +1,33 @@
+package revision_repository
+
+import (
+	"context"
+
+	repo "github.com/mdanialr/sns_backend/internal/core/repository"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type revisionRepo struct {
+	db *gorm.DB
+}
+
+// New return implementation that can be used to interact with object
+// domain.SNSRevision.
+func New(db *gorm.DB) IRepository {
+	return &revisionRepo{db}
+}
+
+func (r *revisionRepo) FindBySNS(ctx context.Context, snsID uint) ([]*domain.SNSRevision, error) {
+	var rvs []*domain.SNSRevision
+	return rvs, repo.DB(ctx, r.db).Where("sns_id = ?", snsID).Order("id DESC").Find(&rvs).Error
+}
+
+func (r *revisionRepo) GetByID(ctx context.Context, snsID, id uint) (*domain.SNSRevision, error) {
+	rv := domain.SNSRevision{ID: id}
+	return &rv, repo.DB(ctx, r.db).Where("sns_id = ?", snsID).First(&rv).Error
+}
+
+func (r *revisionRepo) Create(ctx context.Context, rv *domain.SNSRevision) (*domain.SNSRevision, error) {
+	return rv, repo.DB(ctx, r.db).Create(rv).Error
+}
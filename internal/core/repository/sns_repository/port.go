@@ -2,11 +2,16 @@ package sns_repository
 
 import (
 	"context"
+	"errors"
 
 	r "github.com/mdanialr/sns_backend/internal/core/repository"
 	"github.com/mdanialr/sns_backend/internal/domain"
 )
 
+// ErrStale the domain.SNS doesn't have the expected version anymore since it's
+// changed by other request.
+var ErrStale = errors.New("sns was changed by other request")
+
 // IRepository an interface that may be used when dealing with object
 // domain.SNS.
 type IRepository interface {
@@ -14,19 +19,40 @@ type IRepository interface {
 	FindShorten(ctx context.Context, opts ...r.IOptions) ([]*domain.SNS, error)
 	// FindSend retrieve all send data.
 	FindSend(ctx context.Context, opts ...r.IOptions) ([]*domain.SNS, error)
+	// FindPaste retrieve all paste data.
+	FindPaste(ctx context.Context, opts ...r.IOptions) ([]*domain.SNS, error)
 	// GetByID retrieve a domain.SNS by given id and optionally select which
 	// columns to be retrieved. Returned domain.SNS should be nil even if there
 	// is any error.
 	GetByID(ctx context.Context, id uint, opts ...r.IOptions) (*domain.SNS, error)
-	// GetByUrl same as GetByID but use the url field instead. Returned
-	// domain.SNS should be nil even if there is any error.
-	GetByUrl(ctx context.Context, url string, opts ...r.IOptions) (*domain.SNS, error)
+	// GetByUrl same as GetByID but use the url within given domain instead.
+	// Empty domain means the primary public domain. Returned domain.SNS
+	// should be nil even if there is any error.
+	GetByUrl(ctx context.Context, dom, url string, opts ...r.IOptions) (*domain.SNS, error)
 	// Create save given sns. Return the newly saved object that's the primary
 	// key should be filled already.
 	Create(ctx context.Context, sns *domain.SNS) (*domain.SNS, error)
 	// Update do update given sns using given cons if any. Default is using
-	// provided primary key in sns param as the conditions.
+	// provided primary key in sns param as the conditions. If sns has a
+	// Version, the row is only updated if it still has that version which is
+	// then moved on, otherwise return ErrStale.
 	Update(ctx context.Context, sns *domain.SNS, opts ...r.IOptions) (*domain.SNS, error)
+	// ReplaceRules replace every rule of the Shorten that has given id with
+	// given rules.
+	ReplaceRules(ctx context.Context, id uint, rules []*domain.ShortenRule) error
+	// ReplaceVariants replace every variant of the Shorten that has given id
+	// with given variants. The ones that have an id are updated in place
+	// without touching their hits, the rest are created, while the existing
+	// ones that are not given are deleted.
+	ReplaceVariants(ctx context.Context, id uint, variants []*domain.ShortenVariant) error
+	// ReplaceTags replace every tag of the SNS that has given id with given
+	// tags, where the tags that don't exist yet are created by their name.
+	ReplaceTags(ctx context.Context, id uint, tags []*domain.Tag) error
+	// HitVariant add a hit to the variant that has given id.
+	HitVariant(ctx context.Context, id uint) error
 	// DeleteByID delete an object that's has given id as their primary key.
 	DeleteByID(ctx context.Context, id uint) error
+	// DeleteByVersion same as DeleteByID but only if the object still has
+	// given version, otherwise return ErrStale.
+	DeleteByVersion(ctx context.Context, id uint, version int) error
 }
@@ -2,10 +2,13 @@ package sns_repository
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	r "github.com/mdanialr/sns_backend/internal/core/repository"
 	"github.com/mdanialr/sns_backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type snsRepo struct {
@@ -18,21 +21,57 @@ func New(db *gorm.DB) IRepository {
 	return &snsRepo{db}
 }
 
+// Status return option that filter domain.SNS that has given status at given
+// time, either domain.StatusScheduled, domain.StatusActive or
+// domain.StatusEnded. Any other status doesn't filter anything.
+func Status(status string, now time.Time) r.IOptions {
+	switch status {
+	case domain.StatusScheduled:
+		return r.Where("active_from > ?", now)
+	case domain.StatusActive:
+		return r.Where("(active_from IS NULL OR active_from <= ?) AND (active_until IS NULL OR active_until > ?)", now, now)
+	case domain.StatusEnded:
+		return r.Where("active_until <= ?", now)
+	}
+	return r.Cons()
+}
+
+// Tagged return option that filter domain.SNS that has any of given tag
+// names, or all of them if given all is true. Empty names doesn't filter
+// anything.
+func Tagged(names []string, all bool) r.IOptions {
+	if len(names) == 0 {
+		return r.Cons()
+	}
+	q := "SELECT sns_tags.sns_id FROM sns_tags JOIN tags ON tags.id = sns_tags.tag_id WHERE tags.name IN ?"
+	if !all {
+		return r.Where("id IN ("+q+")", names)
+	}
+	q += " GROUP BY sns_tags.sns_id HAVING COUNT(DISTINCT tags.id) = ?"
+	return r.Where("id IN ("+q+")", names, len(names))
+}
+
 func (s *snsRepo) FindShorten(ctx context.Context, opts ...r.IOptions) ([]*domain.SNS, error) {
 	// prepend condition to first element
-	opts = append([]r.IOptions{r.Cons("send IS NULL")}, opts...)
+	opts = append([]r.IOptions{r.Cons("kind = '" + domain.KindShorten + "'")}, opts...)
 	return s.findSNS(ctx, opts...)
 }
 
 func (s *snsRepo) FindSend(ctx context.Context, opts ...r.IOptions) ([]*domain.SNS, error) {
 	// prepend condition to first element
-	opts = append([]r.IOptions{r.Cons("shorten IS NULL")}, opts...)
+	opts = append([]r.IOptions{r.Cons("kind = '" + domain.KindSend + "'")}, opts...)
+	return s.findSNS(ctx, opts...)
+}
+
+func (s *snsRepo) FindPaste(ctx context.Context, opts ...r.IOptions) ([]*domain.SNS, error) {
+	// prepend condition to first element
+	opts = append([]r.IOptions{r.Cons("kind = '" + domain.KindPaste + "'")}, opts...)
 	return s.findSNS(ctx, opts...)
 }
 
 // findSNS general method that may be used to retrieve all domain.SNS data.
 func (s *snsRepo) findSNS(ctx context.Context, opts ...r.IOptions) ([]*domain.SNS, error) {
-	q := s.db.WithContext(ctx).Model(&domain.SNS{})
+	q := r.DB(ctx, s.db).Model(&domain.SNS{})
 
 	for _, opt := range opts {
 		q = opt.Set(q)
@@ -43,7 +82,7 @@ func (s *snsRepo) findSNS(ctx context.Context, opts ...r.IOptions) ([]*domain.SN
 }
 
 func (s *snsRepo) GetByID(ctx context.Context, id uint, opts ...r.IOptions) (*domain.SNS, error) {
-	q := s.db.WithContext(ctx)
+	q := r.DB(ctx, s.db)
 
 	for _, opt := range opts {
 		q = opt.Set(q)
@@ -53,31 +92,151 @@ func (s *snsRepo) GetByID(ctx context.Context, id uint, opts ...r.IOptions) (*do
 	return &sns, q.First(&sns).Error
 }
 
-func (s *snsRepo) GetByUrl(ctx context.Context, url string, opts ...r.IOptions) (*domain.SNS, error) {
-	q := s.db.WithContext(ctx)
+func (s *snsRepo) GetByUrl(ctx context.Context, dom, url string, opts ...r.IOptions) (*domain.SNS, error) {
+	q := r.DB(ctx, s.db)
 
 	for _, opt := range opts {
 		q = opt.Set(q)
 	}
 
-	sns := domain.SNS{Url: url}
-	return &sns, q.Where(&sns, "Url").First(&sns).Error
+	sns := domain.SNS{Domain: dom, Url: url}
+	// name the fields, so the empty domain is not skipped
+	return &sns, q.Where(&sns, "Domain", "Url").First(&sns).Error
 }
 
 func (s *snsRepo) Create(ctx context.Context, sns *domain.SNS) (*domain.SNS, error) {
-	return sns, s.db.WithContext(ctx).Create(&sns).Error
+	return sns, r.DB(ctx, s.db).Create(&sns).Error
 }
 
 func (s *snsRepo) Update(ctx context.Context, sns *domain.SNS, opts ...r.IOptions) (*domain.SNS, error) {
-	q := s.db.WithContext(ctx)
+	q := r.DB(ctx, s.db)
 
 	for _, opt := range opts {
 		q = opt.Set(q)
 	}
+	if sns.Version == 0 {
+		return sns, q.Updates(&sns).Error
+	}
+
+	// only update the row that still has the version, then move it on along
+	// with the named columns if any
+	q = q.Where("version = ?", sns.Version)
+	if len(q.Statement.Selects) > 0 {
+		q = q.Select(q.Statement.Selects, "version")
+	}
+	sns.Version++
+	res := q.Updates(&sns)
+	if res.Error == nil && res.RowsAffected == 0 {
+		sns.Version--
+		return sns, ErrStale
+	}
+
+	return sns, res.Error
+}
 
-	return sns, q.Updates(&sns).Error
+func (s *snsRepo) ReplaceRules(ctx context.Context, id uint, rules []*domain.ShortenRule) error {
+	return r.DB(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sns_id = ?", id).Delete(&domain.ShortenRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		for _, rl := range rules {
+			rl.SNSID = id
+		}
+		return tx.Create(&rules).Error
+	})
+}
+
+func (s *snsRepo) ReplaceVariants(ctx context.Context, id uint, variants []*domain.ShortenVariant) error {
+	return r.DB(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		// delete the ones that are not given anymore
+		var keep []uint
+		for _, v := range variants {
+			if v.ID != 0 {
+				keep = append(keep, v.ID)
+			}
+		}
+		q := tx.Where("sns_id = ?", id)
+		if len(keep) > 0 {
+			q = q.Where("id NOT IN ?", keep)
+		}
+		if err := q.Delete(&domain.ShortenVariant{}).Error; err != nil {
+			return err
+		}
+
+		for _, v := range variants {
+			v.SNSID = id
+			if v.ID == 0 {
+				if err := tx.Create(v).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			// never write the hits since they may be counted meanwhile
+			err := tx.Model(v).Where("sns_id = ?", id).Select("position", "destination", "weight").Updates(v).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *snsRepo) ReplaceTags(ctx context.Context, id uint, tags []*domain.Tag) error {
+	return r.DB(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		assoc := tx.Model(&domain.SNS{ID: id}).Association("Tags")
+		if len(tags) == 0 {
+			return assoc.Clear()
+		}
+
+		// create the missing tags while others may be creating the same ones,
+		// then fill given tags with the saved ones
+		names := make([]string, 0, len(tags))
+		rows := make([]*domain.Tag, 0, len(tags))
+		for _, t := range tags {
+			names = append(names, t.Name)
+			rows = append(rows, &domain.Tag{Name: t.Name})
+		}
+		err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+			Create(&rows).Error
+		if err != nil {
+			return err
+		}
+		var saved []*domain.Tag
+		if err = tx.Where("name IN ?", names).Find(&saved).Error; err != nil {
+			return err
+		}
+		byName := make(map[string]*domain.Tag, len(saved))
+		for _, t := range saved {
+			byName[t.Name] = t
+		}
+		for _, t := range tags {
+			if byName[t.Name] == nil {
+				return fmt.Errorf("tag %s was not saved", t.Name)
+			}
+			*t = *byName[t.Name]
+		}
+
+		return assoc.Replace(tags)
+	})
+}
+
+func (s *snsRepo) HitVariant(ctx context.Context, id uint) error {
+	return r.DB(ctx, s.db).
+		Model(&domain.ShortenVariant{ID: id}).
+		UpdateColumn("hits", gorm.Expr("hits + 1")).Error
 }
 
 func (s *snsRepo) DeleteByID(ctx context.Context, id uint) error {
-	return s.db.WithContext(ctx).Delete(&domain.SNS{ID: id}).Error
+	return r.DB(ctx, s.db).Delete(&domain.SNS{ID: id}).Error
+}
+
+func (s *snsRepo) DeleteByVersion(ctx context.Context, id uint, version int) error {
+	res := r.DB(ctx, s.db).Where("version = ?", version).Delete(&domain.SNS{ID: id})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrStale
+	}
+	return res.Error
 }
@@ -0,0 +1,61 @@
+package sns_repository
+
+import (
+	"testing"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func dryRun(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func TestTagged(t *testing.T) {
+	const sub = `SELECT sns_tags.sns_id FROM sns_tags JOIN tags ON tags.id = sns_tags.tag_id WHERE tags.name IN ($1,$2)`
+
+	testCases := []struct {
+		name       string
+		names      []string
+		all        bool
+		expectSQL  string
+		expectVars []any
+	}{
+		{
+			name:       "Given no names should not filter anything",
+			expectSQL:  `SELECT * FROM "sns" WHERE "sns"."deleted_at" IS NULL`,
+			expectVars: []any{},
+		},
+		{
+			name:       "Given names should bind them to filter the ones that has any of them",
+			names:      []string{"work", "o'reilly"},
+			expectSQL:  `SELECT * FROM "sns" WHERE id IN (` + sub + `) AND "sns"."deleted_at" IS NULL`,
+			expectVars: []any{"work", "o'reilly"},
+		},
+		{
+			name:       "Given names that should all match should also bind the number of names",
+			names:      []string{"work", "home"},
+			all:        true,
+			expectSQL:  `SELECT * FROM "sns" WHERE id IN (` + sub + ` GROUP BY sns_tags.sns_id HAVING COUNT(DISTINCT tags.id) = $3) AND "sns"."deleted_at" IS NULL`,
+			expectVars: []any{"work", "home", 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out []*domain.SNS
+			tx := Tagged(tc.names, tc.all).Set(dryRun(t).Model(&domain.SNS{})).Find(&out)
+			require.NoError(t, tx.Error)
+			assert.Equal(t, tc.expectSQL, tx.Statement.SQL.String())
+			assert.Equal(t, tc.expectVars, tx.Statement.Vars)
+		})
+	}
+}
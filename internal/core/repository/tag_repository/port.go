@@ -0,0 +1,28 @@
+package tag_repository
+
+import (
+	"context"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// IRepository an interface that should be used when dealing with object
+// domain.Tag.
+type IRepository interface {
+	// FindWithUsage retrieve all tags ordered by their name along with the
+	// number of SNS that use each of them, the deleted SNS are not counted.
+	FindWithUsage(ctx context.Context) ([]*domain.Tag, error)
+	// GetByID retrieve a domain.Tag by given id, also return error if any
+	// including record not found.
+	GetByID(ctx context.Context, id uint) (*domain.Tag, error)
+	// GetByName same as GetByID but use the name instead.
+	GetByName(ctx context.Context, name string) (*domain.Tag, error)
+	// Create save given tag. Return the newly saved object that's the primary
+	// key should be filled already.
+	Create(ctx context.Context, t *domain.Tag) (*domain.Tag, error)
+	// Update do update given tag using its primary key as the condition.
+	Update(ctx context.Context, t *domain.Tag) (*domain.Tag, error)
+	// DeleteByID delete a tag that has given id along with its usage by any
+	// SNS.
+	DeleteByID(ctx context.Context, id uint) error
+}
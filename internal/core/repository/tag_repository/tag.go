@@ -0,0 +1,57 @@
+package tag_repository
+
+import (
+	"context"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type tagRepo struct {
+	db *gorm.DB
+}
+
+// New return implementation that can be used to interact with object
+// domain.Tag.
+func New(db *gorm.DB) IRepository {
+	return &tagRepo{db}
+}
+
+func (t *tagRepo) FindWithUsage(ctx context.Context) ([]*domain.Tag, error) {
+	var tags []*domain.Tag
+	return tags, t.db.WithContext(ctx).
+		Model(&domain.Tag{}).
+		Select("tags.*, COUNT(sns.id) AS usage").
+		Joins("LEFT JOIN sns_tags ON sns_tags.tag_id = tags.id").
+		Joins("LEFT JOIN sns ON sns.id = sns_tags.sns_id AND sns.deleted_at IS NULL").
+		Group("tags.id").
+		Order("tags.name").
+		Find(&tags).Error
+}
+
+func (t *tagRepo) GetByID(ctx context.Context, id uint) (*domain.Tag, error) {
+	tag := domain.Tag{ID: id}
+	return &tag, t.db.WithContext(ctx).First(&tag).Error
+}
+
+func (t *tagRepo) GetByName(ctx context.Context, name string) (*domain.Tag, error) {
+	var tag domain.Tag
+	return &tag, t.db.WithContext(ctx).Where("name = ?", name).First(&tag).Error
+}
+
+func (t *tagRepo) Create(ctx context.Context, tag *domain.Tag) (*domain.Tag, error) {
+	return tag, t.db.WithContext(ctx).Create(tag).Error
+}
+
+func (t *tagRepo) Update(ctx context.Context, tag *domain.Tag) (*domain.Tag, error) {
+	return tag, t.db.WithContext(ctx).Model(tag).Update("name", tag.Name).Error
+}
+
+func (t *tagRepo) DeleteByID(ctx context.Context, id uint) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM sns_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Tag{ID: id}).Error
+	})
+}
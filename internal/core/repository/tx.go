@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ITransactor run several calls to the repository layer as one unit.
+type ITransactor interface {
+	// Transaction run given fn in a database transaction that is carried by
+	// the context given to fn, so every repository that is called using that
+	// context join the transaction. The transaction is committed if fn return
+	// nil, otherwise it's rolled back.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor return ITransactor that run the transactions using given db.
+func NewTransactor(db *gorm.DB) ITransactor {
+	return &transactor{db}
+}
+
+func (t *transactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return DB(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// DB return the transaction that is carried by given context if any,
+// otherwise given db. Either way it's bound to given context. Repositories
+// should use this instead of their own db, so they join the transaction
+// that is started by ITransactor.
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
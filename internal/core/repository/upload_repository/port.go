@@ -0,0 +1,25 @@
+package upload_repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// IRepository an interface that should be used when dealing with object
+// domain.Upload.
+type IRepository interface {
+	// GetByID retrieve a domain.Upload by given id, also return error if any
+	// including record not found.
+	GetByID(ctx context.Context, id string) (*domain.Upload, error)
+	// FindExpired retrieve all domain.Upload that already expired at given
+	// time.
+	FindExpired(ctx context.Context, t time.Time) ([]*domain.Upload, error)
+	// Create save given upload. Return the newly saved object.
+	Create(ctx context.Context, up *domain.Upload) (*domain.Upload, error)
+	// UpdateOffset set the offset of a domain.Upload that has given id.
+	UpdateOffset(ctx context.Context, id string, offset int64) error
+	// DeleteByID delete an object that's has given id as their primary key.
+	DeleteByID(ctx context.Context, id string) error
+}
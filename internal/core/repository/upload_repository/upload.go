@@ -0,0 +1,41 @@
+package upload_repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type uploadRepo struct {
+	db *gorm.DB
+}
+
+// New return implementation that can be used to interact with object
+// domain.Upload.
+func New(db *gorm.DB) IRepository {
+	return &uploadRepo{db}
+}
+
+func (u *uploadRepo) GetByID(ctx context.Context, id string) (*domain.Upload, error) {
+	up := domain.Upload{ID: id}
+	return &up, u.db.WithContext(ctx).First(&up).Error
+}
+
+func (u *uploadRepo) FindExpired(ctx context.Context, t time.Time) ([]*domain.Upload, error) {
+	var ups []*domain.Upload
+	return ups, u.db.WithContext(ctx).Where("expires_at <= ?", t).Find(&ups).Error
+}
+
+func (u *uploadRepo) Create(ctx context.Context, up *domain.Upload) (*domain.Upload, error) {
+	return up, u.db.WithContext(ctx).Create(up).Error
+}
+
+func (u *uploadRepo) UpdateOffset(ctx context.Context, id string, offset int64) error {
+	return u.db.WithContext(ctx).Model(&domain.Upload{ID: id}).Update("offset", offset).Error
+}
+
+func (u *uploadRepo) DeleteByID(ctx context.Context, id string) error {
+	return u.db.WithContext(ctx).Delete(&domain.Upload{ID: id}).Error
+}
@@ -0,0 +1,108 @@
+package idempotency_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/core/repository/idempotency_repository"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+type idempotencySvc struct {
+	log  logger.Writer
+	v    *viper.Viper
+	repo idempotency_repository.IRepository
+}
+
+// New return implementation of core business logic for idempotency key
+// service layer. The response of each key is kept for `idempotency.window`
+// minutes, while a key whose request never finished is freed after
+// `idempotency.lease` minutes.
+func New(l logger.Writer, v *viper.Viper, r idempotency_repository.IRepository) IService {
+	v.SetDefault("idempotency.window", 1440)
+	v.SetDefault("idempotency.lease", 5)
+	return &idempotencySvc{log: l, v: v, repo: r}
+}
+
+func (i *idempotencySvc) Begin(ctx context.Context, key, fingerprint string) (*res.IdempotentResponse, error) {
+	now := time.Now()
+	k, err := i.repo.GetByKey(ctx, key)
+	switch {
+	case err != nil:
+		// the key is not claimed yet
+	case k.ExpiresAt != nil && !k.ExpiresAt.After(now):
+		// the key is over, so it's free to be claimed again
+		if err = i.repo.DeleteByKey(ctx, key); err != nil {
+			errMsg := "failed to release expired idempotency key"
+			i.log.Err(errMsg+":", err)
+			return nil, errors.New(errMsg)
+		}
+	case k.Fingerprint != fingerprint:
+		return nil, ErrKeyReused
+	case !k.Done():
+		return nil, ErrInProgress
+	default:
+		return &res.IdempotentResponse{
+			StatusCode:  k.StatusCode,
+			ContentType: k.ContentType,
+			ETag:        k.ETag,
+			Location:    k.Location,
+			Body:        k.Body,
+		}, nil
+	}
+
+	// claim the key only for a short lease, so a request that never finished
+	// because of a crash doesn't block its retries for the whole window. The
+	// expiry is extended to the window once it's finished
+	exp := now.Add(time.Duration(i.v.GetInt("idempotency.lease")) * time.Minute)
+	k = &domain.IdempotencyKey{Key: key, Fingerprint: fingerprint, ExpiresAt: &exp}
+	if err = i.repo.Create(ctx, k); err != nil {
+		if errors.Is(err, idempotency_repository.ErrExists) {
+			return nil, ErrInProgress
+		}
+		errMsg := "failed to save idempotency key"
+		i.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	return nil, nil
+}
+
+func (i *idempotencySvc) Finish(ctx context.Context, key string, r *res.IdempotentResponse) {
+	exp := time.Now().Add(i.window())
+	k := &domain.IdempotencyKey{
+		Key:         key,
+		StatusCode:  r.StatusCode,
+		ContentType: r.ContentType,
+		ETag:        r.ETag,
+		Location:    r.Location,
+		Body:        r.Body,
+		ExpiresAt:   &exp,
+	}
+	// the request itself is already done, so failing here only means its
+	// retries are run again
+	if err := i.repo.Update(ctx, k); err != nil {
+		i.log.Err("failed to save the response of idempotency key", key, ":", err)
+	}
+}
+
+func (i *idempotencySvc) Release(ctx context.Context, key string) {
+	if err := i.repo.DeleteByKey(ctx, key); err != nil {
+		i.log.Err("failed to release idempotency key", key, ":", err)
+	}
+}
+
+// window return how long the response of a key is kept.
+func (i *idempotencySvc) window() time.Duration {
+	return time.Duration(i.v.GetInt("idempotency.window")) * time.Minute
+}
+
+func (i *idempotencySvc) PurgeExpired(ctx context.Context) {
+	if err := i.repo.DeleteExpired(ctx, time.Now()); err != nil {
+		i.log.Err("failed to remove expired idempotency keys:", err)
+	}
+}
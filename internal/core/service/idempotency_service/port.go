@@ -0,0 +1,36 @@
+package idempotency_service
+
+import (
+	"context"
+	"errors"
+
+	res "github.com/mdanialr/sns_backend/internal/responses"
+)
+
+// ErrInProgress the request that use the same key is not finished yet.
+var ErrInProgress = errors.New("request with the same idempotency key is still in progress")
+
+// ErrKeyReused the key was already used by a request that has different
+// payload.
+var ErrKeyReused = errors.New("idempotency key was already used by a different request")
+
+// IService an interface that should be used when dealing with idempotency
+// keys of create requests.
+type IService interface {
+	// Begin claim given key for a request that has given fingerprint, so the
+	// request is only run once. Return the kept response if the key was
+	// claimed by the same request that is already finished, or nil if the
+	// request should be run. Return ErrKeyReused if the key was claimed by a
+	// request that has different fingerprint or ErrInProgress if it's not
+	// finished yet.
+	Begin(ctx context.Context, key, fingerprint string) (*res.IdempotentResponse, error)
+	// Finish keep given response of the request that claimed given key, so
+	// it's replayed to the retries until the window in config is over.
+	Finish(ctx context.Context, key string, r *res.IdempotentResponse)
+	// Release forget given key, so the request that claimed it may be retried
+	// as if it was never run.
+	Release(ctx context.Context, key string)
+	// PurgeExpired remove every key whose response is no longer kept or whose
+	// request never finished within its lease.
+	PurgeExpired(ctx context.Context)
+}
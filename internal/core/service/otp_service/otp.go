@@ -59,8 +59,13 @@ func (o *otpSvc) ValidateOTP(ctx context.Context, req *req.OTP) bool {
 
 func (o *otpSvc) GetJWT(_ context.Context) (string, error) {
 	dur, _ := time.ParseDuration(o.v.GetString("jwt.duration") + "m")
+	sub := o.v.GetString("jwt.subject")
+	if sub == "" {
+		sub = "otp"
+	}
 	claims := jwt.MapClaims{
 		"user": o.v.GetString("jwt.secret"),
+		"sub":  sub,
 		"exp":  time.Now().Add(dur).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -0,0 +1,172 @@
+package paste_service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	repo "github.com/mdanialr/sns_backend/internal/core/repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/sns_repository"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	req "github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	h "github.com/mdanialr/sns_backend/pkg/helper"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
+)
+
+type pasteSvc struct {
+	log  logger.Writer
+	repo sns_repository.IRepository
+	dom  *domains.Domains
+}
+
+// New return implementation of core business logic for Paste service layer.
+// Given public domains are used to resolve the public urls of each Paste.
+func New(l logger.Writer, repo sns_repository.IRepository, d *domains.Domains) IService {
+	return &pasteSvc{l, repo, d}
+}
+
+func (p *pasteSvc) Index(ctx context.Context, pt *req.Paste) (*res.PasteIndexResponse, error) {
+	// set up repo options, conditions go first so they are counted by the
+	// pagination
+	var opts []repo.IOptions
+	// additionally add search option
+	if pt.Search != "" {
+		opts = append(opts, repo.Where("url LIKE ?", "%"+repo.EscapeLike(pt.Search)+"%"))
+	}
+	pt.OrderBy(pt.Order, pt.Sort)
+	opts = append(opts, repo.Paginate(&pt.M))
+
+	// query Paste data using options above
+	pastes, err := p.repo.FindPaste(ctx, opts...)
+	if errors.Is(err, paginate.ErrInvalidCursor) {
+		return nil, err
+	}
+	if err != nil {
+		errMsg := "failed to retrieve all paste data"
+		p.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	pastes = paginate.Cut(&pt.M, pastes, func(o *domain.SNS) uint { return o.ID })
+	r := &res.PasteIndexResponse{Pagination: &pt.M}
+	r.Pagination.Paginate()
+	r.FromDomain(pastes, p.dom)
+
+	return r, nil
+}
+
+func (p *pasteSvc) Create(ctx context.Context, req *req.Paste) (*res.PasteResponse, error) {
+	dom, err := p.dom.Normalize(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	// make sure given url is not used yet in the domain
+	o, _ := p.repo.GetByUrl(ctx, dom, req.Url, repo.Cols("id"))
+	if o.ID != 0 {
+		return nil, errors.New("url already been taken")
+	}
+
+	// prepare new object to be saved to DB
+	pt := &domain.SNS{
+		Kind:        domain.KindPaste,
+		Domain:      dom,
+		Url:         req.Url,
+		Description: req.Description,
+		Paste:       &req.Paste,
+		IsPermanent: h.Ptr(req.PermanentToBool()),
+	}
+	if req.Language != "" {
+		pt.Language = &req.Language
+	}
+	if _, err := p.repo.Create(ctx, pt); err != nil {
+		errMsg := "failed to create new Paste"
+		p.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	// adapt data from domain.SNS to required PasteResponse
+	var r res.PasteResponse
+	r.FromDomain(pt, p.dom)
+
+	return &r, nil
+}
+
+func (p *pasteSvc) Update(ctx context.Context, req *req.PasteUpdate) (*res.PasteResponse, error) {
+	dom, err := p.dom.Normalize(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	// make sure given id is a paste
+	sns, err := p.repo.GetByID(ctx, req.ID, repo.Cols("url", "domain", "kind"))
+	if err != nil || sns.Kind != domain.KindPaste {
+		return nil, errors.New("paste with id " + strconv.Itoa(int(req.ID)) + " was not found")
+	}
+	// make sure given url is not used yet in the domain if it's changed
+	if sns.Url != req.Url || sns.Domain != dom {
+		o, _ := p.repo.GetByUrl(ctx, dom, req.Url, repo.Cols("id"))
+		if o.ID != 0 {
+			return nil, errors.New("url already been taken")
+		}
+	}
+
+	// prepare new object to be updated to DB
+	pt := &domain.SNS{
+		ID:          req.ID,
+		Domain:      dom,
+		Url:         req.Url,
+		Description: req.Description,
+		Paste:       req.Paste,
+		Language:    req.Language,
+		IsPermanent: h.Ptr(req.PermanentToBool()),
+	}
+	// name the columns, so moving to the primary domain is not skipped.
+	// language is kept as it is if it's not given
+	cols := []string{"domain", "url", "description", "paste", "is_permanent"}
+	if req.Language != nil {
+		cols = append(cols, "language")
+	}
+	newPt, err := p.repo.Update(ctx, pt, repo.Cols(cols...))
+	if err != nil {
+		errMsg := "failed to update Paste with id " + strconv.Itoa(int(req.ID))
+		p.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	var r res.PasteResponse
+	r.FromDomain(newPt, p.dom)
+
+	return &r, nil
+}
+
+func (p *pasteSvc) Raw(ctx context.Context, dom, url string) (*res.PasteResponse, error) {
+	pt, err := p.repo.GetByUrl(ctx, dom, url, repo.Cols("domain", "url", "kind", "paste", "language", "updated_at"))
+	if err != nil || pt.Kind != domain.KindPaste || pt.Paste == nil {
+		return nil, ErrNotFound
+	}
+
+	var r res.PasteResponse
+	r.FromDomain(pt, p.dom)
+
+	return &r, nil
+}
+
+func (p *pasteSvc) Delete(ctx context.Context, req *req.PasteDelete) error {
+	// check first if given id is exists in DB and is a paste
+	pt, err := p.repo.GetByID(ctx, req.ID, repo.Cols("id", "kind"))
+	if err != nil || pt.Kind != domain.KindPaste {
+		errMsg := "data with id " + strconv.Itoa(int(req.ID)) + " was not found"
+		p.log.Err(errMsg+":", err)
+		return errors.New(errMsg)
+	}
+
+	// then delete it using the id from query
+	if err = p.repo.DeleteByID(ctx, pt.ID); err != nil {
+		errMsg := "failed to delete SNS data with id " + strconv.Itoa(int(req.ID))
+		p.log.Err(errMsg+":", err)
+		return errors.New(errMsg)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package paste_service
+
+import (
+	"context"
+	"errors"
+
+	req "github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+)
+
+// ErrNotFound the requested Paste is not exist.
+var ErrNotFound = errors.New("paste was not found")
+
+// IService an interface that should be used when dealing with paste.
+type IService interface {
+	// Index retrieve all paste data with a pagination if provided from
+	// request query params.
+	Index(context.Context, *req.Paste) (*res.PasteIndexResponse, error)
+	// Create save a new Paste instance based on given request. Return the
+	// newly created Paste back along with error if any.
+	Create(context.Context, *req.Paste) (*res.PasteResponse, error)
+	// Update do update an existing Paste instance based on ID in given
+	// request. Return the recently updated Paste back along with error if
+	// any.
+	Update(context.Context, *req.PasteUpdate) (*res.PasteResponse, error)
+	// Raw retrieve a Paste that has given url within given domain, where
+	// empty domain means the primary public domain. Return ErrNotFound if
+	// there is no Paste with given url in the domain.
+	Raw(ctx context.Context, dom, url string) (*res.PasteResponse, error)
+	// Delete remove an SNS data from DB using given id as the condition.
+	Delete(context.Context, *req.PasteDelete) error
+}
@@ -2,22 +2,109 @@ package send_service
 
 import (
 	"context"
+	"errors"
 
 	req "github.com/mdanialr/sns_backend/internal/requests"
 	res "github.com/mdanialr/sns_backend/internal/responses"
 )
 
+// ErrNotFound the requested Send is not exist.
+var ErrNotFound = errors.New("send was not found")
+
+// ErrQuarantined the requested Send is quarantined by the antivirus scanner, so
+// it should not be served.
+var ErrQuarantined = errors.New("send is quarantined")
+
+// ErrUrlTaken the requested url is already used by other link in the same
+// domain.
+var ErrUrlTaken = errors.New("url already been taken")
+
+// ErrInvalidWindow the activation window of the Send would end before it
+// starts.
+var ErrInvalidWindow = errors.New("active_until should be after active_from")
+
+// ErrNotYetAvailable the requested Send is scheduled to be active later.
+var ErrNotYetAvailable = errors.New("link is not yet available")
+
+// ErrExpired the requested Send is not active anymore.
+var ErrExpired = errors.New("link has expired")
+
+// ErrPreconditionFailed the Send was changed since the version that is given
+// in the request.
+var ErrPreconditionFailed = errors.New("link was changed since it was read")
+
+// ErrRevisionNotFound the requested revision is not exist or doesn't belong to
+// the Send.
+var ErrRevisionNotFound = errors.New("revision was not found")
+
+// ErrNothingToRestore the requested revision is a deletion, so there is
+// nothing to restore.
+var ErrNothingToRestore = errors.New("revision has nothing to restore")
+
 type IService interface {
 	// Index retrieve all send data with a pagination if provided from
 	// request query params.
 	Index(context.Context, *req.Send) (*res.SendIndexResponse, error)
 	// Create save a new Send instance based on given request. Return the newly
-	// created Send back along with error if any.
+	// created Send back along with error if any, ErrUrlTaken if the url is
+	// already used.
 	Create(ctx context.Context, req *req.Send) (*res.SendResponse, error)
+	// Attach save a new Send instance that use the file which is already in
+	// Storage with given name, such as a finished resumable upload, instead of
+	// the multipart file. Return the newly created Send back along with error
+	// if any.
+	Attach(ctx context.Context, req *req.Upload, name string) (*res.SendResponse, error)
+	// Get retrieve a Send that has the ID in given request. Return
+	// ErrNotFound if there is no Send with given ID.
+	Get(context.Context, *req.SendGet) (*res.SendResponse, error)
 	// Update do update an existing Send instance based on ID in given
 	// request. Return the recently updated Send back along with error if
-	// any.
+	// any, ErrNotFound if there is no Send with given ID, ErrUrlTaken if
+	// the url is already used or ErrPreconditionFailed if it was changed
+	// since the version in given request.
 	Update(context.Context, *req.SendUpdate) (*res.SendResponse, error)
+	// Patch do update only the given fields of an existing Send instance
+	// based on ID in given request, the other fields are left as they are.
+	// Return ErrNotFound if there is no Send with given ID, ErrUrlTaken if
+	// the url is already used, ErrInvalidWindow if the activation window
+	// would end before it starts or ErrPreconditionFailed if it was changed
+	// since the version in given request.
+	Patch(context.Context, *req.SendPatch) (*res.SendResponse, error)
+	// Revisions retrieve every revision of a Send that has the ID in given
+	// request, the newest one first. Return ErrNotFound if there is no Send
+	// with given ID.
+	Revisions(context.Context, *req.Revisions) ([]*res.RevisionResponse, error)
+	// Rollback restore a Send that has the ID in given request to the values
+	// right after given revision, which is recorded as a new revision. The
+	// files are never restored. Return ErrNotFound if there is no Send with
+	// given ID, ErrRevisionNotFound if the revision doesn't belong to it,
+	// ErrNothingToRestore if the revision is a deletion or
+	// ErrPreconditionFailed if it was changed since the version in given
+	// request or by other request meanwhile.
+	Rollback(context.Context, *req.Rollback) (*res.SendResponse, error)
+	// Download open the file of a Send that has given url within given
+	// domain for reading, where empty domain means the primary public domain.
+	// Return ErrNotFound if there is no Send with given url in the domain,
+	// ErrNotYetAvailable or ErrExpired if it's outside its activation window,
+	// or ErrQuarantined if the file is quarantined by the antivirus scanner.
+	// The caller is responsible for closing the file.
+	Download(ctx context.Context, dom, url string) (*res.SendFileResponse, error)
+	// DownloadFile same as Download but open the file of a Send that has
+	// given name instead of the main one.
+	DownloadFile(ctx context.Context, dom, url, name string) (*res.SendFileResponse, error)
+	// Archive prepare every file of a Send that has given url within given
+	// domain to be served as a single zip archive. Return the same errors as
+	// Download.
+	Archive(ctx context.Context, dom, url string) (*res.SendArchiveResponse, error)
+	// Thumbnail same as Download but open the thumbnail of the file instead.
+	// Return ErrNotFound if the thumbnail is not generated yet.
+	Thumbnail(ctx context.Context, dom, url string) (*res.SendFileResponse, error)
+	// QR render the QR code of the public url of a Send that has the ID in
+	// given request. Return ErrNotFound if there is no Send with given ID.
+	QR(context.Context, *req.QR) (*res.QRResponse, error)
 	// Delete remove an SNS data from DB using given id as the condition.
+	// Return ErrNotFound if there is no Send with given ID or
+	// ErrPreconditionFailed if it was changed since the version in given
+	// request.
 	Delete(ctx context.Context, req *req.SendDelete) error
 }
@@ -1,70 +1,186 @@
 package send_service
 
 import (
+	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	repo "github.com/mdanialr/sns_backend/internal/core/repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/blob_repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/revision_repository"
 	"github.com/mdanialr/sns_backend/internal/core/repository/sns_repository"
 	"github.com/mdanialr/sns_backend/internal/domain"
 	req "github.com/mdanialr/sns_backend/internal/requests"
 	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/actor"
+	"github.com/mdanialr/sns_backend/pkg/domains"
 	h "github.com/mdanialr/sns_backend/pkg/helper"
 	"github.com/mdanialr/sns_backend/pkg/logger"
+	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
+	"github.com/mdanialr/sns_backend/pkg/scanner"
+	"github.com/mdanialr/sns_backend/pkg/sniff"
 	"github.com/mdanialr/sns_backend/pkg/storage"
+	"github.com/mdanialr/sns_backend/pkg/thumbnail"
 	"github.com/spf13/viper"
 )
 
+// updateCols the columns that are written by a full update.
+var updateCols = []string{"domain", "url", "description", "is_permanent", "active_from", "active_until"}
+
 type sendSvc struct {
-	log  logger.Writer
-	st   storage.IStorage
-	v    *viper.Viper
-	repo sns_repository.IRepository
+	log      logger.Writer
+	st       storage.IStorage
+	v        *viper.Viper
+	repo     sns_repository.IRepository
+	blobRepo blob_repository.IRepository
+	rev      revision_repository.IRepository
+	tx       repo.ITransactor
+	sc       scanner.IScanner
+	thumbs   *thumbnail.Pool
+	dom      *domains.Domains
 }
 
 // New return implementation of core business logic for Send service layer.
-func New(l logger.Writer, s storage.IStorage, v *viper.Viper, r sns_repository.IRepository) IService {
-	return &sendSvc{l, s, v, r}
+// Given scanner may be nil which means uploaded files are not scanned, so does
+// given thumbnail pool which means thumbnails are not generated. Given public
+// domains are used to resolve the public urls of each Send. Every change is
+// recorded as a revision using given revision repository in the same
+// transaction that is run by given transactor.
+func New(l logger.Writer, s storage.IStorage, v *viper.Viper, r sns_repository.IRepository, br blob_repository.IRepository, rev revision_repository.IRepository, tx repo.ITransactor, sc scanner.IScanner, tp *thumbnail.Pool, d *domains.Domains) IService {
+	return &sendSvc{l, s, v, r, br, rev, tx, sc, tp, d}
 }
 
 func (s *sendSvc) Index(ctx context.Context, sn *req.Send) (*res.SendIndexResponse, error) {
-	// set up repo options
-	opts := []repo.IOptions{repo.Paginate(&sn.M), repo.Order(sn.Order + " " + sn.Sort)}
+	// set up repo options, conditions go first so they are counted by the
+	// pagination
+	var opts []repo.IOptions
 	// additionally add search option
 	if sn.Search != "" {
-		q := "url LIKE '%" + sn.Search + "%'"
-		opts = append(opts, repo.Cons(q))
+		opts = append(opts, repo.Where("url LIKE ?", "%"+repo.EscapeLike(sn.Search)+"%"))
+	}
+	// additionally filter by the activation window
+	if sn.Status != "" {
+		opts = append(opts, sns_repository.Status(sn.Status, time.Now()))
+	}
+	// additionally filter by the tags
+	if tags := domain.TagNames(sn.Tagged); len(tags) > 0 {
+		opts = append(opts, sns_repository.Tagged(tags, sn.Match == req.MatchAll))
 	}
+	sn.OrderBy(sn.Order, sn.Sort)
+	opts = append(opts, repo.Paginate(&sn.M), repo.Preload("Files", "Tags"))
 
 	// query Send data using options above
 	shortens, err := s.repo.FindSend(ctx, opts...)
+	if errors.Is(err, paginate.ErrInvalidCursor) {
+		return nil, err
+	}
 	if err != nil {
 		errMsg := "failed to retrieve all send data"
 		s.log.Err(errMsg+":", err)
 		return nil, errors.New(errMsg)
 	}
 
+	shortens = paginate.Cut(&sn.M, shortens, func(o *domain.SNS) uint { return o.ID })
 	r := &res.SendIndexResponse{Pagination: &sn.M}
 	r.Pagination.Paginate()
-	r.FromDomain(shortens)
+	r.FromDomain(shortens, s.dom)
 
 	return r, nil
 }
 
 func (s *sendSvc) Create(ctx context.Context, req *req.Send) (*res.SendResponse, error) {
-	// make sure given url is not used yet in the db
-	o, _ := s.repo.GetByUrl(ctx, req.Url, repo.Cols("id"))
+	dom, err := s.dom.Normalize(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	// make sure given url is not used yet in the domain
+	o, _ := s.repo.GetByUrl(ctx, dom, req.Url, repo.Cols("id"))
 	if o.ID != 0 {
-		return nil, errors.New("url already been taken")
+		return nil, ErrUrlTaken
+	}
+
+	// each file is going to be accessed by its name, so it should be unique
+	names := make(map[string]struct{}, len(req.Send))
+	for _, f := range req.Send {
+		if _, ok := names[f.Filename]; ok {
+			return nil, errors.New("file name " + f.Filename + " is duplicated")
+		}
+		names[f.Filename] = struct{}{}
 	}
 
-	// save multipart to Storage
-	fn, err := s.saveFile(req.Send)
+	// prepare new object to be saved to DB
+	from, until := req.Times()
+	sn := &domain.SNS{
+		Domain:      dom,
+		Url:         req.Url,
+		Description: req.Description,
+		FileSize:    h.Ptr(h.BytesToHumanize(req.Size())),
+		IsPermanent: h.Ptr(req.PermanentToBool()),
+		ActiveFrom:  from,
+		ActiveUntil: until,
+		Tags:        domain.NewTags(req.Tags),
+	}
+	// save each multipart to Storage, the first one is the main file
+	var worst *scanner.Result
+	for _, f := range req.Send {
+		sf, err := s.saveFile(ctx, f)
+		if err != nil {
+			// give back the files that are already saved
+			s.releaseFile(ctx, sn)
+		}
+		if isRejected(err) {
+			return nil, err
+		}
+		if err != nil {
+			errMsg := "failed to save uploaded file"
+			s.log.Err(errMsg+":", err)
+			return nil, errors.New(errMsg)
+		}
+		if sn.Hash == nil {
+			sf.apply(sn)
+		}
+		sn.Files = append(sn.Files, sf.file(f.Filename, f.Size))
+		if sf.scan != nil && sf.scan.Worse(worst) {
+			worst = sf.scan
+		}
+	}
+	// the whole Send has the worst scan result of its files
+	if worst != nil {
+		sn.ScanStatus, sn.ScanDetail = &worst.Status, &worst.Detail
+		sn.IsQuarantined = &worst.Quarantined
+	}
+
+	return s.create(ctx, sn, req.Reason)
+}
+
+func (s *sendSvc) Attach(ctx context.Context, req *req.Upload, name string) (*res.SendResponse, error) {
+	dom, err := s.dom.Normalize(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	// make sure given url is not used yet in the domain
+	o, _ := s.repo.GetByUrl(ctx, dom, req.Url, repo.Cols("id"))
+	if o.ID != 0 {
+		return nil, ErrUrlTaken
+	}
+
+	// move the file that's already in Storage to its content-addressed name
+	sf, err := s.attachFile(ctx, name, req.Filename, req.Length)
+	if isRejected(err) {
+		return nil, err
+	}
 	if err != nil {
 		errMsg := "failed to save uploaded file"
 		s.log.Err(errMsg+":", err)
@@ -73,101 +189,757 @@ func (s *sendSvc) Create(ctx context.Context, req *req.Send) (*res.SendResponse,
 
 	// prepare new object to be saved to DB
 	sn := &domain.SNS{
+		Domain:      dom,
 		Url:         req.Url,
 		Description: req.Description,
-		Send:        &fn,
-		FileSize:    h.Ptr(h.BytesToHumanize(req.Send.Size)),
+		FileSize:    h.Ptr(h.BytesToHumanize(req.Length)),
 		IsPermanent: h.Ptr(req.PermanentToBool()),
 	}
-	if _, err = s.repo.Create(ctx, sn); err != nil {
+	sf.apply(sn)
+	sn.Files = []*domain.SendFile{sf.file(req.Filename, req.Length)}
+
+	return s.create(ctx, sn, "")
+}
+
+// create save given sn to DB along with its tags then adapt it to
+// SendResponse. The creation is recorded along with given reason in the same
+// transaction.
+func (s *sendSvc) create(ctx context.Context, sn *domain.SNS, reason string) (*res.SendResponse, error) {
+	sn.Kind = domain.KindSend
+	// tags are saved separately, so the existing ones are reused by their name
+	tags := sn.Tags
+	sn.Tags = nil
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.Create(ctx, sn); err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := s.repo.ReplaceTags(ctx, sn.ID, tags); err != nil {
+				return fmt.Errorf("tags: %w", err)
+			}
+		}
+		sn.Tags = tags
+		return s.record(ctx, sn.ID, domain.ActionCreate, reason, nil, sn)
+	})
+	if err != nil {
 		errMsg := "failed to create new Send"
 		s.log.Err(errMsg+":", err)
+		// give back the reference that was taken by this upload
+		s.releaseFile(ctx, sn)
 		return nil, errors.New(errMsg)
 	}
+	s.queueThumbnail(sn)
 
 	// adapt data from domain.SNS to required SendResponse
 	var r res.SendResponse
-	r.FromDomain(sn)
+	r.FromDomain(sn, s.dom)
+
+	return &r, nil
+}
+
+func (s *sendSvc) Get(ctx context.Context, req *req.SendGet) (*res.SendResponse, error) {
+	sn, err := s.repo.GetByID(ctx, req.ID, repo.Preload("Files", "Tags"))
+	if err != nil || sn.Kind != domain.KindSend {
+		return nil, ErrNotFound
+	}
+
+	var r res.SendResponse
+	r.FromDomain(sn, s.dom)
 
 	return &r, nil
 }
 
 func (s *sendSvc) Update(ctx context.Context, req *req.SendUpdate) (*res.SendResponse, error) {
-	// make sure given url is not used yet in the db -
-	sns, _ := s.repo.GetByID(ctx, req.ID, repo.Cols("url"))
-	if sns.Url != req.Url {
-		// - if given id has different url from url in request
-		o, _ := s.repo.GetByUrl(ctx, req.Url, repo.Cols("id"))
-		if o.ID != 0 {
-			return nil, errors.New("url already been taken")
-		}
+	before, err := s.current(ctx, req.ID, req.Version)
+	if err != nil {
+		return nil, err
 	}
 
 	// prepare new object to be updated to DB
+	from, until := req.Times()
 	sn := &domain.SNS{
 		ID:          req.ID,
+		Domain:      req.Domain,
 		Url:         req.Url,
 		Description: req.Description,
 		IsPermanent: h.Ptr(req.PermanentToBool()),
+		ActiveFrom:  from,
+		ActiveUntil: until,
+		Tags:        domain.NewTags(req.Tags),
 	}
-	newSn, err := s.repo.Update(ctx, sn)
+
+	return s.save(ctx, before, sn, updateCols, domain.ActionUpdate, req.Reason)
+}
+
+func (s *sendSvc) Patch(ctx context.Context, req *req.SendPatch) (*res.SendResponse, error) {
+	before, err := s.current(ctx, req.ID, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// start from the current values then only change the given fields, while
+	// the tags are left as they are unless given
+	sn := *before
+	sn.Tags = nil
+	var cols []string
+	if req.Url != nil {
+		sn.Url, cols = *req.Url, append(cols, "url")
+	}
+	if req.Domain != nil {
+		sn.Domain, cols = *req.Domain, append(cols, "domain")
+	}
+	if req.Description != nil {
+		sn.Description, cols = *req.Description, append(cols, "description")
+	}
+	if req.Permanent != nil {
+		b, _ := strconv.ParseBool(*req.Permanent)
+		sn.IsPermanent, cols = &b, append(cols, "is_permanent")
+	}
+	from, until := req.Times()
+	if req.ActiveFrom != nil {
+		sn.ActiveFrom, cols = from, append(cols, "active_from")
+	}
+	if req.ActiveUntil != nil {
+		sn.ActiveUntil, cols = until, append(cols, "active_until")
+	}
+	if sn.ActiveFrom != nil && sn.ActiveUntil != nil && !sn.ActiveUntil.After(*sn.ActiveFrom) {
+		return nil, ErrInvalidWindow
+	}
+	if req.Tags != nil {
+		sn.Tags = domain.NewTags(req.Tags)
+	}
+
+	return s.save(ctx, before, &sn, cols, domain.ActionUpdate, req.Reason)
+}
+
+func (s *sendSvc) Revisions(ctx context.Context, req *req.Revisions) ([]*res.RevisionResponse, error) {
+	sn, err := s.repo.GetByID(ctx, req.ID, repo.Cols("id", "kind"))
+	if err != nil || sn.Kind != domain.KindSend {
+		return nil, ErrNotFound
+	}
+
+	rvs, err := s.rev.FindBySNS(ctx, req.ID)
 	if err != nil {
-		errMsg := "failed to update Send with id " + strconv.Itoa(int(req.ID))
+		errMsg := "failed to retrieve the revisions of Send with id " + strconv.Itoa(int(req.ID))
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	return res.NewRevisionResponses(rvs), nil
+}
+
+func (s *sendSvc) Rollback(ctx context.Context, req *req.Rollback) (*res.SendResponse, error) {
+	before, err := s.current(ctx, req.ID, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := s.rev.GetByID(ctx, req.ID, req.RevisionID)
+	if err != nil {
+		return nil, ErrRevisionNotFound
+	}
+	ss, err := rv.Snapshot()
+	if err != nil {
+		errMsg := "failed to read revision with id " + strconv.Itoa(int(req.RevisionID))
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+	if ss == nil {
+		return nil, ErrNothingToRestore
+	}
+
+	sn := &domain.SNS{ID: req.ID}
+	ss.Apply(sn)
+
+	return s.save(ctx, before, sn, updateCols, domain.ActionRollback, req.Reason)
+}
+
+// current retrieve every value of the Send that has given id that may be
+// changed, including its tags. Return ErrNotFound if there is no Send with
+// given id or ErrPreconditionFailed if it doesn't have given version, where
+// zero means any version.
+func (s *sendSvc) current(ctx context.Context, id uint, version int) (*domain.SNS, error) {
+	cols := repo.Cols("id", "kind", "domain", "url", "description", "is_permanent", "active_from", "active_until",
+		"version")
+	sn, err := s.repo.GetByID(ctx, id, cols, repo.Preload("Tags"))
+	if err != nil || sn.Kind != domain.KindSend {
+		return nil, ErrNotFound
+	}
+	if version != 0 && version != sn.Version {
+		return nil, ErrPreconditionFailed
+	}
+	return sn, nil
+}
+
+// save write given cols of given sn over given before along with its tags,
+// then record the change as given action, all in one transaction. The tags in
+// before are kept if sn doesn't have them loaded. Return ErrPreconditionFailed if the version of
+// before is not the current one anymore.
+func (s *sendSvc) save(ctx context.Context, before, sn *domain.SNS, cols []string, action, reason string) (*res.SendResponse, error) {
+	var err error
+	if sn.Domain, err = s.dom.Normalize(sn.Domain); err != nil {
+		return nil, err
+	}
+	// make sure given url is not used yet in the domain if it's changed
+	if before.Url != sn.Url || before.Domain != sn.Domain {
+		o, _ := s.repo.GetByUrl(ctx, sn.Domain, sn.Url, repo.Cols("id"))
+		if o.ID != 0 {
+			return nil, ErrUrlTaken
+		}
+	}
+
+	// take the tags out, so they are not saved along with it
+	tags := sn.Tags
+	sn.Tags = nil
+	// the row is always touched, so the version moves on even if only the
+	// tags are changed
+	if len(cols) == 0 {
+		cols = []string{"updated_at"}
+	}
+	// name the columns, so moving to the primary domain, removing the
+	// activation window or emptying the description is not skipped
+	sn.Version = before.Version
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.Update(ctx, sn, repo.Cols(cols...)); err != nil {
+			return err
+		}
+		// tags are not part of the revisions, so a rollback keeps them as
+		// they are
+		if tags == nil {
+			tags = before.Tags
+		} else if err := s.repo.ReplaceTags(ctx, sn.ID, tags); err != nil {
+			return fmt.Errorf("tags: %w", err)
+		}
+		sn.Tags = tags
+		return s.record(ctx, sn.ID, action, reason, before, sn)
+	})
+	if errors.Is(err, sns_repository.ErrStale) {
+		return nil, ErrPreconditionFailed
+	}
+	if err != nil {
+		errMsg := "failed to update Send with id " + strconv.Itoa(int(sn.ID))
 		s.log.Err(errMsg+":", err)
 		return nil, errors.New(errMsg)
 	}
 
 	var r res.SendResponse
-	r.FromDomain(newSn)
+	r.FromDomain(sn, s.dom)
 
 	return &r, nil
 }
 
-func (s *sendSvc) Delete(ctx context.Context, req *req.SendDelete) error {
-	// check first if given id is exists in DB
-	sn, err := s.repo.GetByID(ctx, req.ID, repo.Cols("id", "send"))
+// record save the change of the Send that has given id as a revision along
+// with the actor in given context. It should be called in the same
+// transaction as the change, so there is no change without its revision.
+func (s *sendSvc) record(ctx context.Context, id uint, action, reason string, before, after *domain.SNS) error {
+	rv, err := domain.NewRevision(id, action, actor.From(ctx), reason, before, after)
 	if err != nil {
-		errMsg := "data with id " + strconv.Itoa(int(req.ID)) + " was not found"
+		return err
+	}
+	if _, err = s.rev.Create(ctx, rv); err != nil {
+		return fmt.Errorf("%s revision: %w", action, err)
+	}
+	return nil
+}
+
+func (s *sendSvc) Download(ctx context.Context, dom, url string) (*res.SendFileResponse, error) {
+	sn, err := s.repo.GetByUrl(ctx, dom, url, repo.Cols("url", "send", "hash", "mime_type", "is_quarantined", "active_from", "active_until", "updated_at"))
+	if err != nil || sn.Send == nil {
+		return nil, ErrNotFound
+	}
+	if err = available(sn); err != nil {
+		return nil, err
+	}
+
+	return s.openFile(sn, *sn.Send, sn.Url+filepath.Ext(*sn.Send))
+}
+
+func (s *sendSvc) DownloadFile(ctx context.Context, dom, url, name string) (*res.SendFileResponse, error) {
+	sn, err := s.repo.GetByUrl(ctx, dom, url, repo.Cols("id", "url", "send", "hash", "mime_type", "is_quarantined", "active_from", "active_until", "updated_at"), repo.Preload("Files"))
+	if err != nil || sn.Send == nil {
+		return nil, ErrNotFound
+	}
+	if err = available(sn); err != nil {
+		return nil, err
+	}
+
+	for _, f := range sendFiles(sn) {
+		if f.Name != name {
+			continue
+		}
+		fr, err := s.openFile(sn, f.Send, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		fr.Hash, fr.MimeType = &f.Hash, &f.MimeType
+		return fr, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *sendSvc) Archive(ctx context.Context, dom, url string) (*res.SendArchiveResponse, error) {
+	sn, err := s.repo.GetByUrl(ctx, dom, url, repo.Cols("id", "url", "send", "hash", "mime_type", "is_quarantined", "active_from", "active_until", "updated_at"), repo.Preload("Files"))
+	if err != nil || sn.Send == nil {
+		return nil, ErrNotFound
+	}
+	if err = available(sn); err != nil {
+		return nil, err
+	}
+
+	files := sendFiles(sn)
+	return &res.SendArchiveResponse{
+		Name:      sn.Url + ".zip",
+		UpdatedAt: sn.UpdatedAt,
+		Write: func(w io.Writer) error {
+			err := s.writeArchive(w, files)
+			if err != nil {
+				s.log.Err("failed to write archive of Send "+url+":", err)
+			}
+			return err
+		},
+	}, nil
+}
+
+// writeArchive write given files to w as a zip archive. Each file is copied
+// straight from Storage, so only a single file is opened at a time.
+func (s *sendSvc) writeArchive(w io.Writer, files []*domain.SendFile) error {
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool, len(files))
+	for _, f := range files {
+		zh := &zip.FileHeader{Name: archiveName(f.Name, used), Method: zip.Deflate}
+		if f.CreatedAt != nil {
+			zh.Modified = *f.CreatedAt
+		}
+		fw, err := zw.CreateHeader(zh)
+		if err != nil {
+			return err
+		}
+
+		fl, err := s.st.Open(s.filePath(f.Send))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, fl)
+		fl.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// archiveName return given file name as a plain name of an entry in a zip
+// archive that is not in given used names yet, then mark it as used. Any path
+// is dropped and any character other than letters, digits and a few common
+// punctuations is replaced, so the entry can't be extracted outside of the
+// target directory.
+func archiveName(name string, used map[string]bool) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" .-_()+,", r) {
+			return r
+		}
+		return '_'
+	}, name)
+	if strings.Trim(name, ".") == "" {
+		name = "file"
+	}
+
+	// number the duplicated names just like a file manager does
+	ext := filepath.Ext(name)
+	base, out := strings.TrimSuffix(name, ext), name
+	for i := 2; used[out]; i++ {
+		out = base + " (" + strconv.Itoa(i) + ")" + ext
+	}
+	used[out] = true
+
+	return out
+}
+
+func (s *sendSvc) Thumbnail(ctx context.Context, dom, url string) (*res.SendFileResponse, error) {
+	sn, err := s.repo.GetByUrl(ctx, dom, url, repo.Cols("url", "hash", "thumbnail", "is_quarantined", "active_from", "active_until", "updated_at"))
+	if err != nil || sn.Thumbnail == nil {
+		return nil, ErrNotFound
+	}
+	if err = available(sn); err != nil {
+		return nil, err
+	}
+
+	f, err := s.openFile(sn, *sn.Thumbnail, sn.Url+"_thumbnail"+filepath.Ext(*sn.Thumbnail))
+	if err != nil {
+		return nil, err
+	}
+	// the thumbnail has different content from the original file
+	if f.Hash != nil {
+		f.Hash = h.Ptr(*f.Hash + "-thumbnail")
+	}
+	f.MimeType = nil
+
+	return f, nil
+}
+
+// available return ErrNotYetAvailable or ErrExpired if given sn is outside
+// its activation window, or ErrQuarantined if it's quarantined by the
+// antivirus scanner.
+func available(sn *domain.SNS) error {
+	switch sn.Status(time.Now()) {
+	case domain.StatusScheduled:
+		return ErrNotYetAvailable
+	case domain.StatusEnded:
+		return ErrExpired
+	}
+	if sn.IsQuarantined != nil && *sn.IsQuarantined {
+		return ErrQuarantined
+	}
+	return nil
+}
+
+// openFile open given file name in Storage that belong to given sn then
+// present it using given name.
+func (s *sendSvc) openFile(sn *domain.SNS, fn, name string) (*res.SendFileResponse, error) {
+	fl, err := s.st.Open(s.filePath(fn))
+	if err != nil {
+		errMsg := "failed to open file of Send " + sn.Url
 		s.log.Err(errMsg+":", err)
-		return errors.New(errMsg)
+		return nil, errors.New(errMsg)
+	}
+	// the size in DB is humanized, so ask the file instead
+	size, err := fl.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = fl.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		fl.Close()
+		errMsg := "failed to read file of Send " + sn.Url
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
 	}
 
-	// then delete it using the id from query
-	if err = s.repo.DeleteByID(ctx, sn.ID); err != nil {
+	return &res.SendFileResponse{
+		Name:      name,
+		Hash:      sn.Hash,
+		MimeType:  sn.MimeType,
+		Size:      size,
+		UpdatedAt: sn.UpdatedAt,
+		File:      fl,
+	}, nil
+}
+
+func (s *sendSvc) QR(ctx context.Context, req *req.QR) (*res.QRResponse, error) {
+	sn, err := s.repo.GetByID(ctx, req.ID, repo.Cols("domain", "url", "kind"))
+	if err != nil || sn.Kind != domain.KindSend {
+		return nil, ErrNotFound
+	}
+
+	r, err := res.NewQRResponse(sn.Url, s.dom.Url(sn.Domain, sn.Url), req.Options())
+	if err != nil {
+		errMsg := "failed to render QR code of Send " + sn.Url
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	return r, nil
+}
+
+func (s *sendSvc) Delete(ctx context.Context, req *req.SendDelete) error {
+	// check first if given id is exists in DB
+	cols := repo.Cols("id", "kind", "send", "hash", "thumbnail", "domain", "url", "description", "is_permanent",
+		"active_from", "active_until", "version")
+	sn, err := s.repo.GetByID(ctx, req.ID, cols, repo.Preload("Files"))
+	if err != nil || sn.Kind != domain.KindSend {
+		return ErrNotFound
+	}
+	if req.Version != 0 && req.Version != sn.Version {
+		return ErrPreconditionFailed
+	}
+
+	// then delete it using the id from query as long as it's not changed
+	// meanwhile
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.DeleteByVersion(ctx, sn.ID, sn.Version); err != nil {
+			return err
+		}
+		return s.record(ctx, sn.ID, domain.ActionDelete, req.Reason, sn, nil)
+	})
+	if errors.Is(err, sns_repository.ErrStale) {
+		return ErrPreconditionFailed
+	}
+	if err != nil {
 		errMsg := "failed to delete SNS data with id " + strconv.Itoa(int(req.ID))
 		s.log.Err(errMsg+":", err)
 		return errors.New(errMsg)
 	}
 
-	// then delete the file
-	go s.removeFile(*sn.Send)
+	// then delete the file if nothing else refers to it
+	s.releaseFile(ctx, sn)
 
 	return nil
 }
 
-// saveFile save given multipart to Storage after prepend it with target file
-// path from config and random string as the filename.
-func (s *sendSvc) saveFile(f *multipart.FileHeader) (string, error) {
+// storedFile the file of a Send that is already saved to Storage along with
+// everything that is found while inspecting its content.
+type storedFile struct {
+	blob     *domain.Blob
+	mimeType string
+	scan     *scanner.Result
+}
+
+// apply set the file information to given sn.
+func (f *storedFile) apply(sn *domain.SNS) {
+	sn.Send = &f.blob.Name
+	sn.Hash = &f.blob.Hash
+	sn.MimeType = &f.mimeType
+	if f.scan != nil {
+		sn.ScanStatus = &f.scan.Status
+		sn.ScanDetail = &f.scan.Detail
+		sn.IsQuarantined = &f.scan.Quarantined
+	}
+}
+
+// file return domain.SendFile of this file using given original filename and
+// size.
+func (f *storedFile) file(name string, size int64) *domain.SendFile {
+	sf := &domain.SendFile{
+		Name:     name,
+		Send:     f.blob.Name,
+		Hash:     f.blob.Hash,
+		MimeType: f.mimeType,
+		Size:     size,
+	}
+	if f.scan != nil {
+		sf.ScanStatus = &f.scan.Status
+	}
+	return sf
+}
+
+// saveFile save given multipart to Storage using the SHA-256 hash of the
+// content as the filename. The content is written under a pending name first,
+// so the domain.Blob is only committed once its bytes are safely in Storage.
+// Any subsequent upload that has identical content will only add reference to
+// the existing domain.Blob.
+func (s *sendSvc) saveFile(ctx context.Context, f *multipart.FileHeader) (*storedFile, error) {
 	fl, err := f.Open()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer fl.Close()
+	sf, err := s.inspect(ctx, fl, f.Filename)
+	if err != nil {
+		return nil, err
+	}
 
-	// set up the target path
-	pt := strings.TrimSuffix(s.v.GetString("storage.path"), "/") + "/" // make sure to manually append slice
-	// generate random name then append it with the file extension
-	fn := uuid.NewString() + filepath.Ext(f.Filename)
+	// save in place since the multipart file is gone once the request is done
+	pending := uuid.NewString() + pendingSuffix
+	if _, err = s.st.Append(fl, s.filePath(pending)); err != nil {
+		s.st.Remove(s.filePath(pending))
+		return nil, err
+	}
+
+	if sf, err = s.commitFile(ctx, sf, pending, f.Filename, f.Size); err != nil {
+		s.st.Remove(s.filePath(pending))
+		return nil, err
+	}
+
+	return sf, nil
+}
+
+// attachFile add reference to the domain.Blob that has the same content as
+// the file in Storage with given name, which is moved to its
+// content-addressed name afterward.
+func (s *sendSvc) attachFile(ctx context.Context, name, filename string, size int64) (*storedFile, error) {
+	fl, err := s.st.Open(s.filePath(name))
+	if err != nil {
+		return nil, err
+	}
+	sf, err := s.inspect(ctx, fl, filename)
+	fl.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.commitFile(ctx, sf, name, filename, size)
+}
+
+// commitFile add reference to the domain.Blob of given inspected file whose
+// content is already written to Storage with given name, then move the file to
+// its content-addressed name. The file is moved even if the domain.Blob is
+// already there since the content is identical, which makes sure the bytes
+// exist whenever the reference does.
+func (s *sendSvc) commitFile(ctx context.Context, sf *storedFile, name, filename string, size int64) (*storedFile, error) {
+	// use the hash along with the file extension as the name
+	bl := &domain.Blob{Hash: sf.blob.Hash, Name: sf.blob.Hash + filepath.Ext(filename), Size: size}
+	bl, _, err := s.blobRepo.Acquire(ctx, bl)
+	if err != nil {
+		return nil, err
+	}
+	sf.blob = bl
+
+	if err = s.st.Move(s.filePath(name), s.filePath(bl.Name)); err != nil {
+		s.releaseBlob(ctx, bl.Hash)
+		return nil, err
+	}
 
-	// save using separate goroutine
-	go s.st.Save(fl, pt+fn)
+	return sf, nil
+}
+
+// pendingSuffix suffix of the file that is still being written to Storage
+// and isn't referred by any domain.Blob yet.
+const pendingSuffix = ".part"
+
+// inspect detect the MIME type of given file content and make sure it's
+// accepted by the policy in config, then hash and scan the whole content.
+// The file is rewound afterward, so it can be read again. The returned
+// storedFile only has the hash of its domain.Blob.
+func (s *sendSvc) inspect(ctx context.Context, rs io.ReadSeeker, filename string) (*storedFile, error) {
+	mt, err := sniff.Detect(rs)
+	if err != nil {
+		return nil, err
+	}
+	if err = sniff.NewPolicyWithConfig(s.v).Check(mt, filename); err != nil {
+		return nil, err
+	}
+	if _, err = rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	sum, err := hashContent(rs)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sf := &storedFile{blob: &domain.Blob{Hash: sum}, mimeType: mt.String()}
+
+	if s.sc == nil {
+		return sf, nil
+	}
+	vd, scanErr := s.sc.Scan(ctx, rs)
+	if scanErr != nil {
+		s.log.Err("failed to scan uploaded file", filename, ":", scanErr)
+	}
+	if sf.scan, err = scanner.NewPolicyWithConfig(s.v).Apply(vd, scanErr); err != nil {
+		return nil, err
+	}
+	if _, err = rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
 
-	return fn, nil
+	return sf, nil
 }
 
-// removeFile delete given filename after append it with Storage path from
+// releaseFile give back the reference of every file in given sns and delete
+// the file once there is no domain.SNS that refer to it anymore. File that is
+// uploaded before content-addressed storage is introduced is deleted right
+// away.
+func (s *sendSvc) releaseFile(ctx context.Context, sn *domain.SNS) {
+	if sn.Hash == nil {
+		if sn.Send != nil {
+			go s.st.Remove(s.filePath(*sn.Send))
+		}
+		return
+	}
+
+	// Send that is created before it may have several files only has the
+	// main file
+	hashes := []string{*sn.Hash}
+	if len(sn.Files) > 0 {
+		hashes = hashes[:0]
+		for _, f := range sn.Files {
+			hashes = append(hashes, f.Hash)
+		}
+	}
+
+	for _, hs := range hashes {
+		if s.releaseBlob(ctx, hs) && hs == *sn.Hash && sn.Thumbnail != nil {
+			go s.st.Remove(s.filePath(*sn.Thumbnail))
+		}
+	}
+}
+
+// releaseBlob remove a reference from the domain.Blob that has given hash and
+// remove its file once nothing refers to it anymore. Return true if the file
+// is removed.
+func (s *sendSvc) releaseBlob(ctx context.Context, hash string) bool {
+	var removed bool
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		bl, err := s.blobRepo.Release(ctx, hash)
+		if err != nil || bl.RefCount > 0 {
+			return err
+		}
+		// remove the file before the deletion of the blob is committed, since
+		// a concurrent Acquire of the same content waits for the commit before
+		// it moves its own file into place
+		s.st.Remove(s.filePath(bl.Name))
+		removed = true
+		return nil
+	})
+	if err != nil {
+		s.log.Err("failed to release blob with hash", hash, ":", err)
+		return false
+	}
+
+	return removed
+}
+
+// queueThumbnail submit a job to generate the thumbnail of the file in given
+// sn if it's an image. The thumbnail is shared by every domain.SNS that has
+// the same content, so it's named after the hash.
+func (s *sendSvc) queueThumbnail(sn *domain.SNS) {
+	if sn.Hash == nil || sn.MimeType == nil || !thumbnail.Supported(*sn.MimeType) {
+		return
+	}
+
+	name := *sn.Hash + "_thumbnail" + thumbnail.Ext(*sn.MimeType)
+	id := sn.ID
+	s.thumbs.Submit(thumbnail.Job{
+		Src: s.filePath(*sn.Send),
+		Dst: s.filePath(name),
+		Done: func() {
+			// the request is already done by now, so don't use its context
+			if _, err := s.repo.Update(context.Background(), &domain.SNS{ID: id, Thumbnail: &name}); err != nil {
+				s.log.Err("failed to save thumbnail of Send with id", id, ":", err)
+			}
+		},
+	})
+}
+
+// filePath return given filename after prepend it with Storage path from
 // config.
-func (s *sendSvc) removeFile(fn string) {
+func (s *sendSvc) filePath(fn string) string {
 	pt := strings.TrimSuffix(s.v.GetString("storage.path"), "/") + "/" // make sure to manually append slice
-	s.st.Remove(pt + fn)
+	return pt + fn
+}
+
+// isRejected whether given error is caused by the uploaded file being rejected
+// by one of the policies, so the error can be shown as it is.
+func isRejected(err error) bool {
+	return errors.Is(err, sniff.ErrRejected) || errors.Is(err, scanner.ErrRejected)
+}
+
+// sendFiles return every file of given sn. Send that is created before it may
+// have several files only has the main file, so use that one instead.
+func sendFiles(sn *domain.SNS) []*domain.SendFile {
+	if len(sn.Files) > 0 || sn.Send == nil {
+		return sn.Files
+	}
+
+	f := &domain.SendFile{Name: sn.Url + filepath.Ext(*sn.Send), Send: *sn.Send, CreatedAt: sn.UpdatedAt}
+	if sn.Hash != nil {
+		f.Hash = *sn.Hash
+	}
+	if sn.MimeType != nil {
+		f.MimeType = *sn.MimeType
+	}
+	return []*domain.SendFile{f}
+}
+
+// hashContent return hex encoded SHA-256 of everything in given reader.
+func hashContent(r io.Reader) (string, error) {
+	hs := sha256.New()
+	if _, err := io.Copy(hs, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hs.Sum(nil)), nil
 }
@@ -0,0 +1,252 @@
+package send_service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/core/repository/blob_repository/mocks"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	h "github.com/mdanialr/sns_backend/pkg/helper"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/mdanialr/sns_backend/pkg/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const content = "hello world"
+
+// noTx run each transaction right away without any database.
+type noTx struct{}
+
+func (noTx) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// setupSvc return sendSvc that save files in a temporary directory, which is
+// returned as well.
+func setupSvc(t *testing.T, br *mocks.Mockblob_repositoryIRepository) (*sendSvc, string) {
+	l := logger.NewStdOut()
+	l.Init()
+	dir := t.TempDir()
+	v := viper.New()
+	v.Set("storage.path", dir)
+
+	return &sendSvc{log: l, st: storage.NewFile(l), v: v, blobRepo: br, tx: noTx{}}, dir
+}
+
+// fileHeader return multipart.FileHeader of given filename and content.
+func fileHeader(t *testing.T, filename, content string) *multipart.FileHeader {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("send", filename)
+	require.NoError(t, err)
+	fw.Write([]byte(content))
+	require.NoError(t, mw.Close())
+
+	form, err := multipart.NewReader(&buf, mw.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["send"][0]
+}
+
+// listDir return the name of every file in given directory.
+func listDir(t *testing.T, dir string) []string {
+	ents, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range ents {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSendSvc_saveFile(t *testing.T) {
+	hash := hashOf(content)
+	isBlob := mock.MatchedBy(func(b *domain.Blob) bool {
+		return b.Hash == hash && b.Name == hash+".txt" && b.Size == int64(len(content))
+	})
+
+	testCases := []struct {
+		name        string
+		setup       func(*mocks.Mockblob_repositoryIRepository)
+		expectErr   bool
+		expectBlob  *domain.Blob
+		expectFiles []string
+	}{
+		{
+			name: "Given new content should save it under the content-addressed name",
+			setup: func(br *mocks.Mockblob_repositoryIRepository) {
+				br.EXPECT().
+					Acquire(mock.Anything, isBlob).
+					Return(&domain.Blob{Hash: hash, Name: hash + ".txt", RefCount: 1}, true, nil).
+					Once()
+			},
+			expectBlob:  &domain.Blob{Hash: hash, Name: hash + ".txt", RefCount: 1},
+			expectFiles: []string{hash + ".txt"},
+		},
+		{
+			name: "Given content that is already stored should only add the reference to the existing blob",
+			setup: func(br *mocks.Mockblob_repositoryIRepository) {
+				br.EXPECT().
+					Acquire(mock.Anything, isBlob).
+					Return(&domain.Blob{ID: 7, Hash: hash, Name: hash + ".txt", RefCount: 2}, false, nil).
+					Once()
+			},
+			expectBlob:  &domain.Blob{ID: 7, Hash: hash, Name: hash + ".txt", RefCount: 2},
+			expectFiles: []string{hash + ".txt"},
+		},
+		{
+			name: "Given repository that fail to acquire the blob should return error and leave no file behind",
+			setup: func(br *mocks.Mockblob_repositoryIRepository) {
+				br.EXPECT().Acquire(mock.Anything, mock.Anything).Return(nil, false, errors.New("db down")).Once()
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			br := new(mocks.Mockblob_repositoryIRepository)
+			tc.setup(br)
+			svc, dir := setupSvc(t, br)
+
+			sf, err := svc.saveFile(context.Background(), fileHeader(t, "note.txt", content))
+			br.AssertExpectations(t)
+			assert.Equal(t, tc.expectFiles, listDir(t, dir))
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectBlob, sf.blob)
+
+			b, err := os.ReadFile(filepath.Join(dir, sf.blob.Name))
+			require.NoError(t, err)
+			assert.Equal(t, content, string(b))
+		})
+	}
+}
+
+func TestSendSvc_releaseFile(t *testing.T) {
+	testCases := []struct {
+		name        string
+		sn          *domain.SNS
+		setup       func(*mocks.Mockblob_repositoryIRepository)
+		expectGone  string
+		expectFiles []string
+	}{
+		{
+			name: "Given blob that is still referred by other Send should keep its file",
+			sn:   &domain.SNS{Hash: h.Ptr("a"), Send: h.Ptr("a.txt"), Thumbnail: h.Ptr("a.thumb.jpg")},
+			setup: func(br *mocks.Mockblob_repositoryIRepository) {
+				br.EXPECT().Release(mock.Anything, "a").Return(&domain.Blob{Hash: "a", Name: "a.txt", RefCount: 1}, nil).Once()
+			},
+			expectFiles: []string{"a.thumb.jpg", "a.txt", "b.txt"},
+		},
+		{
+			name: "Given the last reference of the blob should remove its file right away along with the thumbnail",
+			sn:   &domain.SNS{Hash: h.Ptr("a"), Send: h.Ptr("a.txt"), Thumbnail: h.Ptr("a.thumb.jpg")},
+			setup: func(br *mocks.Mockblob_repositoryIRepository) {
+				br.EXPECT().Release(mock.Anything, "a").Return(&domain.Blob{Hash: "a", Name: "a.txt"}, nil).Once()
+			},
+			expectGone:  "a.txt",
+			expectFiles: []string{"b.txt"},
+		},
+		{
+			name: "Given repository that fail to release the blob should keep its file",
+			sn:   &domain.SNS{Hash: h.Ptr("a"), Send: h.Ptr("a.txt")},
+			setup: func(br *mocks.Mockblob_repositoryIRepository) {
+				br.EXPECT().Release(mock.Anything, "a").Return(nil, errors.New("db down")).Once()
+			},
+			expectFiles: []string{"a.thumb.jpg", "a.txt", "b.txt"},
+		},
+		{
+			name: "Given Send that has several files should release each of them",
+			sn: &domain.SNS{Hash: h.Ptr("a"), Send: h.Ptr("a.txt"), Files: []*domain.SendFile{
+				{Hash: "a", Send: "a.txt"},
+				{Hash: "b", Send: "b.txt"},
+			}},
+			setup: func(br *mocks.Mockblob_repositoryIRepository) {
+				br.EXPECT().Release(mock.Anything, "a").Return(&domain.Blob{Hash: "a", Name: "a.txt", RefCount: 1}, nil).Once()
+				br.EXPECT().Release(mock.Anything, "b").Return(&domain.Blob{Hash: "b", Name: "b.txt"}, nil).Once()
+			},
+			expectFiles: []string{"a.thumb.jpg", "a.txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			br := new(mocks.Mockblob_repositoryIRepository)
+			tc.setup(br)
+			svc, dir := setupSvc(t, br)
+			for _, fn := range []string{"a.txt", "a.thumb.jpg", "b.txt"} {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, fn), []byte(fn), 0666))
+			}
+
+			svc.releaseFile(context.Background(), tc.sn)
+			br.AssertExpectations(t)
+			if tc.expectGone != "" {
+				assert.NoFileExists(t, filepath.Join(dir, tc.expectGone))
+			}
+			// the thumbnail is removed in the background
+			assert.Eventually(t, func() bool {
+				return assert.ObjectsAreEqual(tc.expectFiles, listDir(t, dir))
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		names  []string
+		expect []string
+	}{
+		{
+			name:   "Given plain names should keep them as is",
+			names:  []string{"report 2024.pdf", "photo_(1).jpg"},
+			expect: []string{"report 2024.pdf", "photo_(1).jpg"},
+		},
+		{
+			name:   "Given names that have a path should only keep the base name",
+			names:  []string{"../../.bashrc", `..\..\evil.exe`, "/etc/passwd"},
+			expect: []string{".bashrc", "evil.exe", "passwd"},
+		},
+		{
+			name:   "Given names that have unsafe characters should replace them",
+			names:  []string{"a\"b\r\n.txt", "..", ""},
+			expect: []string{"a_b__.txt", "file", "file (2)"},
+		},
+		{
+			name:   "Given duplicated names should number them",
+			names:  []string{"a.txt", "a.txt", "dir/a.txt"},
+			expect: []string{"a.txt", "a (2).txt", "a (3).txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			used := make(map[string]bool)
+			var got []string
+			for _, n := range tc.names {
+				got = append(got, archiveName(n, used))
+			}
+			assert.Equal(t, tc.expect, got)
+		})
+	}
+}
@@ -2,23 +2,100 @@ package shorten_service
 
 import (
 	"context"
+	"errors"
 
 	req "github.com/mdanialr/sns_backend/internal/requests"
 	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/targeting"
 )
 
+// ErrNotFound the requested Shorten is not exist.
+var ErrNotFound = errors.New("shorten was not found")
+
+// ErrUrlTaken the requested url is already used by other link in the same
+// domain.
+var ErrUrlTaken = errors.New("url already been taken")
+
+// ErrInvalidWindow the activation window of the Shorten would end before it
+// starts.
+var ErrInvalidWindow = errors.New("active_until should be after active_from")
+
+// ErrNotYetAvailable the requested Shorten is scheduled to be active later.
+var ErrNotYetAvailable = errors.New("link is not yet available")
+
+// ErrExpired the requested Shorten is not active anymore.
+var ErrExpired = errors.New("link has expired")
+
+// ErrPreconditionFailed the Shorten was changed since the version that is given
+// in the request.
+var ErrPreconditionFailed = errors.New("link was changed since it was read")
+
+// ErrRevisionNotFound the requested revision is not exist or doesn't belong to
+// the Shorten.
+var ErrRevisionNotFound = errors.New("revision was not found")
+
+// ErrNothingToRestore the requested revision is a deletion, so there is
+// nothing to restore.
+var ErrNothingToRestore = errors.New("revision has nothing to restore")
+
 // IService an interface that should be used when dealing with sns.
 type IService interface {
 	// Index retrieve all shorten data with a pagination if provided from
 	// request query params.
 	Index(context.Context, *req.Shorten) (*res.ShortenIndexResponse, error)
 	// Create save a new Shorten instance based on given request. Return the
-	// newly created Shorten back along with error if any.
+	// newly created Shorten back along with error if any, ErrUrlTaken if the
+	// url is already used.
 	Create(context.Context, *req.Shorten) (*res.ShortenResponse, error)
+	// Get retrieve a Shorten that has the ID in given request. Return
+	// ErrNotFound if there is no Shorten with given ID.
+	Get(context.Context, *req.ShortenGet) (*res.ShortenResponse, error)
 	// Update do update an existing Shorten instance based on ID in given
 	// request. Return the recently updated Shorten back along with error if
-	// any.
+	// any, ErrNotFound if there is no Shorten with given ID, ErrUrlTaken if
+	// the url is already used or ErrPreconditionFailed if it was changed
+	// since the version in given request.
 	Update(context.Context, *req.ShortenUpdate) (*res.ShortenResponse, error)
+	// Patch do update only the given fields of an existing Shorten instance
+	// based on ID in given request, the other fields are left as they are.
+	// Return ErrNotFound if there is no Shorten with given ID, ErrUrlTaken if
+	// the url is already used, ErrInvalidWindow if the activation window
+	// would end before it starts or ErrPreconditionFailed if it was changed
+	// since the version in given request.
+	Patch(context.Context, *req.ShortenPatch) (*res.ShortenResponse, error)
+	// Revisions retrieve every revision of a Shorten that has the ID in given
+	// request, the newest one first. Return ErrNotFound if there is no
+	// Shorten with given ID.
+	Revisions(context.Context, *req.Revisions) ([]*res.RevisionResponse, error)
+	// Rollback restore a Shorten that has the ID in given request to the
+	// values right after given revision, which is recorded as a new revision.
+	// Return ErrNotFound if there is no Shorten with given ID,
+	// ErrRevisionNotFound if the revision doesn't belong to it,
+	// ErrNothingToRestore if the revision is a deletion or
+	// ErrPreconditionFailed if it was changed since the version in given
+	// request or by other request meanwhile.
+	Rollback(context.Context, *req.Rollback) (*res.ShortenResponse, error)
+	// Lookup retrieve a Shorten that has given url within given domain, where
+	// empty domain means the primary public domain. Shorten of the returned
+	// one is the destination for given client according to its rules, which
+	// may not be the default one. Return ErrNotFound if there is no Shorten
+	// with given url in the domain, or ErrNotYetAvailable or ErrExpired if
+	// it's outside its activation window.
+	Lookup(ctx context.Context, dom, url string, cl targeting.Client) (*res.ShortenResponse, error)
+	// QR render the QR code of the public url of a Shorten that has the ID
+	// in given request. Return ErrNotFound if there is no Shorten with given
+	// ID.
+	QR(context.Context, *req.QR) (*res.QRResponse, error)
+	// Variants retrieve the variants of a Shorten that has the ID in given
+	// request along with their hits. Return ErrNotFound if there is no
+	// Shorten with given ID.
+	Variants(context.Context, *req.ShortenVariants) ([]*res.ShortenVariantItem, error)
+	// CheckLinks check the destination of each Shorten that is not checked
+	// recently, then save the result. Do nothing if the checks are disabled.
+	CheckLinks(context.Context)
 	// Delete remove an SNS data from DB using given id as the condition.
+	// Return ErrNotFound if there is no Shorten with given ID or
+	// ErrPreconditionFailed if it was changed since the version in given
+	// request.
 	Delete(context.Context, *req.ShortenDelete) error
 }
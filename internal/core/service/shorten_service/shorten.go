@@ -3,66 +3,150 @@ package shorten_service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	repo "github.com/mdanialr/sns_backend/internal/core/repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/revision_repository"
 	"github.com/mdanialr/sns_backend/internal/core/repository/sns_repository"
 	"github.com/mdanialr/sns_backend/internal/domain"
 	req "github.com/mdanialr/sns_backend/internal/requests"
 	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/actor"
+	"github.com/mdanialr/sns_backend/pkg/domains"
 	h "github.com/mdanialr/sns_backend/pkg/helper"
+	"github.com/mdanialr/sns_backend/pkg/linkcheck"
 	"github.com/mdanialr/sns_backend/pkg/logger"
+	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
+	"github.com/mdanialr/sns_backend/pkg/passthrough"
+	"github.com/mdanialr/sns_backend/pkg/targeting"
+	"github.com/mdanialr/sns_backend/pkg/urlpolicy"
 )
 
+// updateCols the columns that are written by a full update.
+var updateCols = []string{"domain", "url", "description", "shorten", "is_permanent", "active_from", "active_until",
+	"forward_query", "default_params"}
+
 type shService struct {
 	log  logger.Writer
 	repo sns_repository.IRepository
+	rev  revision_repository.IRepository
+	tx   repo.ITransactor
+	dom  *domains.Domains
+	pol  *urlpolicy.Policy
+	lc   *linkcheck.Checker
 }
 
 // New return implementation of core business logic for Shorten service layer.
-func New(l logger.Writer, repo sns_repository.IRepository) IService {
-	return &shService{l, repo}
+// Every change is recorded as a revision using given revision repository in
+// the same transaction that is run by given transactor. Given public domains
+// are used to resolve the public url of each Shorten, while given policy
+// decide which destination is accepted. Given checker may be nil which means
+// the destinations are never checked.
+func New(l logger.Writer, repo sns_repository.IRepository, rev revision_repository.IRepository, tx repo.ITransactor, d *domains.Domains, p *urlpolicy.Policy, lc *linkcheck.Checker) IService {
+	return &shService{l, repo, rev, tx, d, p, lc}
 }
 
 func (s *shService) Index(ctx context.Context, sh *req.Shorten) (*res.ShortenIndexResponse, error) {
-	// set up repo options
-	opts := []repo.IOptions{repo.Paginate(&sh.M), repo.Order(sh.Order + " " + sh.Sort)}
+	// set up repo options, conditions go first so they are counted by the
+	// pagination
+	var opts []repo.IOptions
 	// additionally add search option
 	if sh.Search != "" {
-		q := "url LIKE '%" + sh.Search + "%'"
-		opts = append(opts, repo.Cons(q))
+		opts = append(opts, repo.Where("url LIKE ?", "%"+repo.EscapeLike(sh.Search)+"%"))
+	}
+	// additionally filter by the activation window
+	if sh.Status != "" {
+		opts = append(opts, sns_repository.Status(sh.Status, time.Now()))
+	}
+	// additionally filter by the tags
+	if tags := domain.TagNames(sh.Tagged); len(tags) > 0 {
+		opts = append(opts, sns_repository.Tagged(tags, sh.Match == req.MatchAll))
 	}
+	// additionally filter by the health of the destination
+	switch sh.Health {
+	case req.HealthBroken:
+		opts = append(opts, repo.Cons("health_failures >= "+strconv.Itoa(s.brokenAfter())))
+	case req.HealthHealthy:
+		opts = append(opts, repo.Cons("health_checked_at IS NOT NULL", "health_failures < "+strconv.Itoa(s.brokenAfter())))
+	case req.HealthUnchecked:
+		opts = append(opts, repo.Cons("health_checked_at IS NULL"))
+	}
+	sh.OrderBy(sh.Order, sh.Sort)
+	opts = append(opts, repo.Paginate(&sh.M), repo.Preload("Rules", "Variants", "Tags"))
 
 	// query Shorten data using options above
 	shortens, err := s.repo.FindShorten(ctx, opts...)
+	if errors.Is(err, paginate.ErrInvalidCursor) {
+		return nil, err
+	}
 	if err != nil {
 		errMsg := "failed to retrieve all shorten data"
 		s.log.Err(errMsg+":", err)
 		return nil, errors.New(errMsg)
 	}
 
+	for _, sn := range shortens {
+		sortByPosition(sn)
+	}
+
+	shortens = paginate.Cut(&sh.M, shortens, func(o *domain.SNS) uint { return o.ID })
 	r := &res.ShortenIndexResponse{Pagination: &sh.M}
 	r.Pagination.Paginate()
-	r.FromDomain(shortens)
+	r.FromDomain(shortens, s.dom)
 
 	return r, nil
 }
 
 func (s *shService) Create(ctx context.Context, req *req.Shorten) (*res.ShortenResponse, error) {
-	// make sure given url is not used yet in the db
-	o, _ := s.repo.GetByUrl(ctx, req.Url, repo.Cols("id"))
+	dom, err := s.dom.Normalize(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	// make sure given url is not used yet in the domain
+	o, _ := s.repo.GetByUrl(ctx, dom, req.Url, repo.Cols("id"))
 	if o.ID != 0 {
-		return nil, errors.New("url already been taken")
+		return nil, ErrUrlTaken
 	}
 
 	// prepare new object to be saved to DB
+	from, until := req.Times()
 	sh := &domain.SNS{
-		Url:         req.Url,
-		Description: req.Description,
-		Shorten:     &req.Shorten,
-		IsPermanent: h.Ptr(req.PermanentToBool()),
+		Kind:          domain.KindShorten,
+		Domain:        dom,
+		Url:           req.Url,
+		Description:   req.Description,
+		Shorten:       &req.Shorten,
+		IsPermanent:   h.Ptr(req.PermanentToBool()),
+		ActiveFrom:    from,
+		ActiveUntil:   until,
+		Rules:         shortenRules(req.Rules),
+		Variants:      shortenVariants(req.Variants),
+		ForwardQuery:  req.Forward,
+		DefaultParams: encodeParams(req.Params),
 	}
-	if _, err := s.repo.Create(ctx, sh); err != nil {
+	if err = s.checkDestinations(ctx, sh); err != nil {
+		return nil, err
+	}
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.Create(ctx, sh); err != nil {
+			return err
+		}
+		// tags are saved separately, so the existing ones are reused by their
+		// name
+		if sh.Tags = domain.NewTags(req.Tags); len(sh.Tags) > 0 {
+			if err := s.repo.ReplaceTags(ctx, sh.ID, sh.Tags); err != nil {
+				return fmt.Errorf("tags: %w", err)
+			}
+		}
+		return s.record(ctx, sh.ID, domain.ActionCreate, req.Reason, nil, sh)
+	})
+	if err != nil {
 		errMsg := "failed to create new Shorten"
 		s.log.Err(errMsg+":", err)
 		return nil, errors.New(errMsg)
@@ -70,57 +154,537 @@ func (s *shService) Create(ctx context.Context, req *req.Shorten) (*res.ShortenR
 
 	// adapt data from domain.SNS to required ShortenResponse
 	var r res.ShortenResponse
-	r.FromDomain(sh)
+	r.FromDomain(sh, s.dom)
+
+	return &r, nil
+}
+
+func (s *shService) Get(ctx context.Context, req *req.ShortenGet) (*res.ShortenResponse, error) {
+	sh, err := s.repo.GetByID(ctx, req.ID, repo.Preload("Rules", "Variants", "Tags"))
+	if err != nil || sh.Kind != domain.KindShorten {
+		return nil, ErrNotFound
+	}
+	sortByPosition(sh)
+
+	var r res.ShortenResponse
+	r.FromDomain(sh, s.dom)
 
 	return &r, nil
 }
 
 func (s *shService) Update(ctx context.Context, req *req.ShortenUpdate) (*res.ShortenResponse, error) {
-	// make sure given url is not used yet in the db -
-	sns, _ := s.repo.GetByID(ctx, req.ID, repo.Cols("url"))
-	if sns.Url != req.Url {
-		// - if given id has different url from url in request
-		o, _ := s.repo.GetByUrl(ctx, req.Url, repo.Cols("id"))
-		if o.ID != 0 {
-			return nil, errors.New("url already been taken")
-		}
+	before, err := s.current(ctx, req.ID, req.Version)
+	if err != nil {
+		return nil, err
 	}
 
 	// prepare new object to be updated to DB
+	from, until := req.Times()
 	sh := &domain.SNS{
-		ID:          req.ID,
-		Url:         req.Url,
-		Description: req.Description,
-		Shorten:     req.Shorten,
-		IsPermanent: h.Ptr(req.PermanentToBool()),
+		ID:            req.ID,
+		Domain:        req.Domain,
+		Url:           req.Url,
+		Description:   req.Description,
+		Shorten:       req.Shorten,
+		IsPermanent:   h.Ptr(req.PermanentToBool()),
+		ActiveFrom:    from,
+		ActiveUntil:   until,
+		Rules:         shortenRules(req.Rules),
+		Variants:      shortenVariants(req.Variants),
+		ForwardQuery:  req.Forward,
+		DefaultParams: encodeParams(req.Params),
+		Tags:          domain.NewTags(req.Tags),
+	}
+
+	return s.save(ctx, before, sh, updateCols, domain.ActionUpdate, req.Reason)
+}
+
+func (s *shService) Patch(ctx context.Context, req *req.ShortenPatch) (*res.ShortenResponse, error) {
+	before, err := s.current(ctx, req.ID, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// start from the current values then only change the given fields, while
+	// the rules, variants and tags are left as they are unless given
+	sh := *before
+	sh.Rules, sh.Variants, sh.Tags = nil, nil, nil
+	var cols []string
+	if req.Url != nil {
+		sh.Url, cols = *req.Url, append(cols, "url")
+	}
+	if req.Domain != nil {
+		sh.Domain, cols = *req.Domain, append(cols, "domain")
+	}
+	if req.Description != nil {
+		sh.Description, cols = *req.Description, append(cols, "description")
+	}
+	if req.Shorten != nil {
+		sh.Shorten, cols = req.Shorten, append(cols, "shorten")
+	}
+	if req.Permanent != nil {
+		b, _ := strconv.ParseBool(*req.Permanent)
+		sh.IsPermanent, cols = &b, append(cols, "is_permanent")
+	}
+	from, until := req.Times()
+	if req.ActiveFrom != nil {
+		sh.ActiveFrom, cols = from, append(cols, "active_from")
+	}
+	if req.ActiveUntil != nil {
+		sh.ActiveUntil, cols = until, append(cols, "active_until")
+	}
+	if sh.ActiveFrom != nil && sh.ActiveUntil != nil && !sh.ActiveUntil.After(*sh.ActiveFrom) {
+		return nil, ErrInvalidWindow
+	}
+	if req.Forward != nil {
+		sh.ForwardQuery, cols = *req.Forward, append(cols, "forward_query")
+	}
+	if req.Params != nil {
+		sh.DefaultParams, cols = encodeParams(req.Params), append(cols, "default_params")
+	}
+	if req.Rules != nil {
+		sh.Rules = shortenRules(req.Rules)
+	}
+	if req.Variants != nil {
+		sh.Variants = shortenVariants(req.Variants)
+	}
+	if req.Tags != nil {
+		sh.Tags = domain.NewTags(req.Tags)
+	}
+
+	return s.save(ctx, before, &sh, cols, domain.ActionUpdate, req.Reason)
+}
+
+func (s *shService) Revisions(ctx context.Context, req *req.Revisions) ([]*res.RevisionResponse, error) {
+	sh, err := s.repo.GetByID(ctx, req.ID, repo.Cols("id", "kind"))
+	if err != nil || sh.Kind != domain.KindShorten {
+		return nil, ErrNotFound
+	}
+
+	rvs, err := s.rev.FindBySNS(ctx, req.ID)
+	if err != nil {
+		errMsg := "failed to retrieve the revisions of Shorten with id " + strconv.Itoa(int(req.ID))
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	return res.NewRevisionResponses(rvs), nil
+}
+
+func (s *shService) Rollback(ctx context.Context, req *req.Rollback) (*res.ShortenResponse, error) {
+	before, err := s.current(ctx, req.ID, req.Version)
+	if err != nil {
+		return nil, err
 	}
-	newSh, err := s.repo.Update(ctx, sh)
+	rv, err := s.rev.GetByID(ctx, req.ID, req.RevisionID)
 	if err != nil {
-		errMsg := "failed to update Shorten with id " + strconv.Itoa(int(req.ID))
+		return nil, ErrRevisionNotFound
+	}
+	ss, err := rv.Snapshot()
+	if err != nil {
+		errMsg := "failed to read revision with id " + strconv.Itoa(int(req.RevisionID))
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+	if ss == nil {
+		return nil, ErrNothingToRestore
+	}
+
+	sh := &domain.SNS{ID: req.ID}
+	ss.Apply(sh)
+
+	return s.save(ctx, before, sh, updateCols, domain.ActionRollback, req.Reason)
+}
+
+// current retrieve every value of the Shorten that has given id that may be
+// changed, including its rules, variants and tags. Return ErrNotFound if
+// there is no Shorten with given id or ErrPreconditionFailed if it doesn't
+// have given version, where zero means any version.
+func (s *shService) current(ctx context.Context, id uint, version int) (*domain.SNS, error) {
+	cols := repo.Cols("id", "kind", "domain", "url", "description", "shorten", "is_permanent", "active_from",
+		"active_until", "forward_query", "default_params", "version")
+	sh, err := s.repo.GetByID(ctx, id, cols, repo.Preload("Rules", "Variants", "Tags"))
+	if err != nil || sh.Kind != domain.KindShorten {
+		return nil, ErrNotFound
+	}
+	if version != 0 && version != sh.Version {
+		return nil, ErrPreconditionFailed
+	}
+	sortByPosition(sh)
+
+	return sh, nil
+}
+
+// save write given cols of given sh over given before along with its rules,
+// variants and tags, then record the change as given action, all in one
+// transaction. Any of the rules, variants and tags that is nil in sh is left
+// as it is in before. The hits of each variant in before are kept if the
+// destination is still there. Return ErrPreconditionFailed if the version of
+// before is not the current one anymore.
+func (s *shService) save(ctx context.Context, before, sh *domain.SNS, cols []string, action, reason string) (*res.ShortenResponse, error) {
+	var err error
+	if sh.Domain, err = s.dom.Normalize(sh.Domain); err != nil {
+		return nil, err
+	}
+	// make sure given url is not used yet in the domain if it's changed
+	if before.Url != sh.Url || before.Domain != sh.Domain {
+		o, _ := s.repo.GetByUrl(ctx, sh.Domain, sh.Url, repo.Cols("id"))
+		if o.ID != 0 {
+			return nil, ErrUrlTaken
+		}
+	}
+	if err = s.checkDestinations(ctx, sh); err != nil {
+		return nil, err
+	}
+
+	// take the rules, variants and tags out, so they are not saved along with
+	// it
+	rules, variants, tags := sh.Rules, carryHits(sh.Variants, before.Variants), sh.Tags
+	sh.Rules, sh.Variants, sh.Tags = nil, nil, nil
+	// the row is always touched, so the version moves on even if only the
+	// rules, variants or tags are changed
+	if len(cols) == 0 {
+		cols = []string{"updated_at"}
+	}
+	// name the columns, so moving to the primary domain, removing the
+	// activation window or emptying the description is not skipped
+	sh.Version = before.Version
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.Update(ctx, sh, repo.Cols(cols...)); err != nil {
+			return err
+		}
+		if rules == nil {
+			rules = before.Rules
+		} else if err := s.repo.ReplaceRules(ctx, sh.ID, rules); err != nil {
+			return fmt.Errorf("rules: %w", err)
+		}
+		if variants == nil {
+			variants = before.Variants
+		} else if err := s.repo.ReplaceVariants(ctx, sh.ID, variants); err != nil {
+			return fmt.Errorf("variants: %w", err)
+		}
+		// tags are not part of the revisions, so a rollback keeps them as
+		// they are
+		if tags == nil {
+			tags = before.Tags
+		} else if err := s.repo.ReplaceTags(ctx, sh.ID, tags); err != nil {
+			return fmt.Errorf("tags: %w", err)
+		}
+		sh.Rules, sh.Variants, sh.Tags = rules, variants, tags
+		return s.record(ctx, sh.ID, action, reason, before, sh)
+	})
+	if errors.Is(err, sns_repository.ErrStale) {
+		return nil, ErrPreconditionFailed
+	}
+	if err != nil {
+		errMsg := "failed to update Shorten with id " + strconv.Itoa(int(sh.ID))
 		s.log.Err(errMsg+":", err)
 		return nil, errors.New(errMsg)
 	}
 
 	var r res.ShortenResponse
-	r.FromDomain(newSh)
+	r.FromDomain(sh, s.dom)
 
 	return &r, nil
 }
 
-func (s *shService) Delete(ctx context.Context, req *req.ShortenDelete) error {
-	// check first if given id is exists in DB
-	sh, err := s.repo.GetByID(ctx, req.ID, repo.Cols("id"))
+// record save the change of the Shorten that has given id as a revision along
+// with the actor in given context. It should be called in the same
+// transaction as the change, so there is no change without its revision.
+func (s *shService) record(ctx context.Context, id uint, action, reason string, before, after *domain.SNS) error {
+	rv, err := domain.NewRevision(id, action, actor.From(ctx), reason, before, after)
 	if err != nil {
-		errMsg := "data with id " + strconv.Itoa(int(req.ID)) + " was not found"
+		return err
+	}
+	if _, err = s.rev.Create(ctx, rv); err != nil {
+		return fmt.Errorf("%s revision: %w", action, err)
+	}
+	return nil
+}
+
+func (s *shService) Lookup(ctx context.Context, dom, url string, cl targeting.Client) (*res.ShortenResponse, error) {
+	sh, err := s.repo.GetByUrl(ctx, dom, url, repo.Cols("id", "kind", "domain", "url", "shorten", "is_permanent", "active_from", "active_until", "forward_query", "default_params"), repo.Preload("Rules", "Variants"))
+	if err != nil || sh.Kind != domain.KindShorten {
+		return nil, ErrNotFound
+	}
+	switch sh.Status(time.Now()) {
+	case domain.StatusScheduled:
+		return nil, ErrNotYetAvailable
+	case domain.StatusEnded:
+		return nil, ErrExpired
+	}
+	sortByPosition(sh)
+
+	var r res.ShortenResponse
+	r.FromDomain(sh, s.dom)
+	dest, err := s.passthrough(sh, s.destination(ctx, sh, cl), cl.Query)
+	if err != nil {
+		errMsg := "failed to prepare the destination of Shorten " + sh.Url
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+	r.Shorten = &dest
+
+	return &r, nil
+}
+
+// passthrough add the default parameters of given sh to given destination,
+// then forward given query string of the request according to the setting of
+// the sh.
+func (s *shService) passthrough(sh *domain.SNS, dest string, query url.Values) (string, error) {
+	defaults, _ := url.ParseQuery(sh.DefaultParams)
+	vars := map[string]string{"slug": sh.Url}
+	if base, err := url.Parse(s.dom.Base(sh.Domain)); err == nil {
+		vars["domain"] = base.Hostname()
+	}
+
+	return passthrough.Apply(dest, passthrough.Template(defaults, vars), query, sh.ForwardQuery)
+}
+
+// destination return the destination of given sh for given client. The
+// first rule that match the client win, otherwise pick one of the variants
+// for the client, otherwise use the default one.
+func (s *shService) destination(ctx context.Context, sh *domain.SNS, cl targeting.Client) string {
+	if dest := targeting.Resolve(targetingRules(sh.Rules), cl, ""); dest != "" {
+		return dest
+	}
+
+	vs := make([]targeting.Variant, 0, len(sh.Variants))
+	for _, v := range sh.Variants {
+		vs = append(vs, targeting.Variant{Destination: v.Destination, Weight: v.Weight})
+	}
+	// pick independently for each Shorten, so a visitor doesn't always get
+	// the same position in every Shorten
+	i := targeting.Pick(vs, cl.Visitor+":"+strconv.Itoa(int(sh.ID)))
+	if i < 0 {
+		return h.Def(sh.Shorten)
+	}
+	if err := s.repo.HitVariant(ctx, sh.Variants[i].ID); err != nil {
+		s.log.Err("failed to count the hit of variant with id", sh.Variants[i].ID, ":", err)
+	}
+
+	return sh.Variants[i].Destination
+}
+
+func (s *shService) Variants(ctx context.Context, req *req.ShortenVariants) ([]*res.ShortenVariantItem, error) {
+	sh, err := s.repo.GetByID(ctx, req.ID, repo.Cols("id", "kind"), repo.Preload("Variants"))
+	if err != nil || sh.Kind != domain.KindShorten {
+		return nil, ErrNotFound
+	}
+	sortByPosition(sh)
+
+	return res.ShortenVariantItems(sh), nil
+}
+
+func (s *shService) QR(ctx context.Context, req *req.QR) (*res.QRResponse, error) {
+	sh, err := s.repo.GetByID(ctx, req.ID, repo.Cols("domain", "url", "kind"))
+	if err != nil || sh.Kind != domain.KindShorten {
+		return nil, ErrNotFound
+	}
+
+	r, err := res.NewQRResponse(sh.Url, s.dom.Url(sh.Domain, sh.Url), req.Options())
+	if err != nil {
+		errMsg := "failed to render QR code of Shorten " + sh.Url
+		s.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	return r, nil
+}
+
+func (s *shService) CheckLinks(ctx context.Context) {
+	if s.lc == nil {
+		return
+	}
+
+	// check the ones that are never checked first, then the oldest ones
+	due := time.Now().Add(-s.lc.Interval)
+	shortens, err := s.repo.FindShorten(ctx,
+		repo.Cols("id", "shorten", "health_failures"),
+		repo.Where("(health_checked_at IS NULL OR health_checked_at < ?)", due),
+		repo.Order("health_checked_at ASC NULLS FIRST"),
+		repo.Paginate(&paginate.M{Limit: s.lc.Batch}),
+	)
+	if err != nil {
+		s.log.Err("failed to retrieve shorten to be checked:", err)
+		return
+	}
+
+	targets := make([]linkcheck.Target, 0, len(shortens))
+	failures := make(map[uint]int, len(shortens))
+	for _, sh := range shortens {
+		targets = append(targets, linkcheck.Target{ID: sh.ID, Url: h.Def(sh.Shorten)})
+		failures[sh.ID] = sh.HealthFailures
+	}
+
+	var checked, broken int32
+	s.lc.CheckAll(ctx, targets, func(t linkcheck.Target, r linkcheck.Result) {
+		now := time.Now()
+		sh := &domain.SNS{ID: t.ID, HealthStatus: &r.Status, HealthCheckedAt: &now}
+		if r.Broken() {
+			sh.HealthFailures = failures[t.ID] + 1
+			atomic.AddInt32(&broken, 1)
+		}
+		// the Shorten itself is not changed, so keep its updated_at
+		cols := repo.Cols("health_status", "health_checked_at", "health_failures")
+		if _, err := s.repo.Update(ctx, sh, cols, repo.Omit("updated_at")); err != nil {
+			s.log.Err("failed to save the health of Shorten with id", t.ID, ":", err)
+			return
+		}
+		atomic.AddInt32(&checked, 1)
+	})
+	if checked > 0 {
+		s.log.Inf("checked", checked, "shorten destinations,", broken, "of them are broken")
+	}
+}
+
+// brokenAfter return the number of consecutive failures before the
+// destination of a Shorten is considered broken.
+func (s *shService) brokenAfter() int {
+	if s.lc == nil || s.lc.BrokenAfter < 1 {
+		return 1
+	}
+	return s.lc.BrokenAfter
+}
+
+// checkDestinations make sure the default destination of given sh along with
+// the destination of each of its rules and variants are allowed by the policy.
+func (s *shService) checkDestinations(ctx context.Context, sh *domain.SNS) error {
+	self := urlpolicy.Link{Domain: sh.Domain, Url: sh.Url}
+	dests := []string{h.Def(sh.Shorten)}
+	for _, rl := range sh.Rules {
+		dests = append(dests, rl.Destination)
+	}
+	for _, v := range sh.Variants {
+		dests = append(dests, v.Destination)
+	}
+
+	for _, dest := range dests {
+		if err := s.checkDestination(ctx, self, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDestination make sure given destination of given link is accepted by
+// the policy.
+func (s *shService) checkDestination(ctx context.Context, self urlpolicy.Link, dest string) error {
+	err := s.pol.Check(ctx, self, dest, s.follow)
+	if errors.Is(err, urlpolicy.ErrRejected) {
+		return err
+	}
+	if err != nil {
+		errMsg := "failed to check the destination"
 		s.log.Err(errMsg+":", err)
 		return errors.New(errMsg)
 	}
+	return nil
+}
+
+// follow return the destination of given link if it's a Shorten.
+func (s *shService) follow(ctx context.Context, l urlpolicy.Link) (string, bool) {
+	sh, err := s.repo.GetByUrl(ctx, l.Domain, l.Url, repo.Cols("kind", "shorten"))
+	if err != nil || sh.Kind != domain.KindShorten || sh.Shorten == nil {
+		return "", false
+	}
+	return *sh.Shorten, true
+}
+
+func (s *shService) Delete(ctx context.Context, req *req.ShortenDelete) error {
+	// check first if given id is exists in DB
+	sh, err := s.current(ctx, req.ID, req.Version)
+	if err != nil {
+		return err
+	}
 
-	// then delete it using the id from query
-	if err = s.repo.DeleteByID(ctx, sh.ID); err != nil {
+	// then delete it using the id from query as long as it's not changed
+	// meanwhile
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.DeleteByVersion(ctx, sh.ID, sh.Version); err != nil {
+			return err
+		}
+		return s.record(ctx, sh.ID, domain.ActionDelete, req.Reason, sh, nil)
+	})
+	if errors.Is(err, sns_repository.ErrStale) {
+		return ErrPreconditionFailed
+	}
+	if err != nil {
 		errMsg := "failed to delete SNS data with id " + strconv.Itoa(int(req.ID))
 		s.log.Err(errMsg+":", err)
 		return errors.New(errMsg)
 	}
+
 	return nil
 }
+
+// shortenRules convert given rules from the request to domain.ShortenRule
+// while keeping their order. The result is never nil, so it replaces the
+// existing rules even if there is none.
+func shortenRules(rules []*req.ShortenRule) []*domain.ShortenRule {
+	rls := make([]*domain.ShortenRule, 0, len(rules))
+	for i, rl := range rules {
+		rls = append(rls, &domain.ShortenRule{
+			Position:    i,
+			Platform:    rl.Platform,
+			Language:    strings.ToLower(rl.Language),
+			Query:       encodeParams(rl.Query),
+			Destination: rl.Destination,
+		})
+	}
+	return rls
+}
+
+// encodeParams encode given parameters, empty if there is none.
+func encodeParams(params map[string]string) string {
+	q := make(url.Values, len(params))
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	return q.Encode()
+}
+
+// targetingRules convert given rules to targeting.Rule.
+func targetingRules(rules []*domain.ShortenRule) []targeting.Rule {
+	trs := make([]targeting.Rule, 0, len(rules))
+	for _, rl := range rules {
+		q, _ := url.ParseQuery(rl.Query)
+		trs = append(trs, targeting.Rule{Platform: rl.Platform, Language: rl.Language, Query: q, Destination: rl.Destination})
+	}
+	return trs
+}
+
+// shortenVariants convert given variants from the request to
+// domain.ShortenVariant while keeping their order. The result is never nil,
+// so it replaces the existing variants even if there is none.
+func shortenVariants(variants []*req.ShortenVariant) []*domain.ShortenVariant {
+	vs := make([]*domain.ShortenVariant, 0, len(variants))
+	for i, v := range variants {
+		vs = append(vs, &domain.ShortenVariant{Position: i, Destination: v.Destination, Weight: v.Weight})
+	}
+	return vs
+}
+
+// carryHits carry the id and the hits of given old variants over to the one
+// in given variants that has the same destination, so they are updated in
+// place and keep counting their hits. Return the variants.
+func carryHits(variants, old []*domain.ShortenVariant) []*domain.ShortenVariant {
+	byDest := make(map[string][]*domain.ShortenVariant, len(old))
+	for _, v := range old {
+		byDest[v.Destination] = append(byDest[v.Destination], v)
+	}
+	for _, v := range variants {
+		// each old variant is only carried once for duplicated destination
+		if olds := byDest[v.Destination]; len(olds) > 0 {
+			v.ID, v.Hits = olds[0].ID, olds[0].Hits
+			byDest[v.Destination] = olds[1:]
+		}
+	}
+	return variants
+}
+
+// sortByPosition sort the rules and variants of given sh by their position.
+func sortByPosition(sh *domain.SNS) {
+	sort.SliceStable(sh.Rules, func(i, j int) bool { return sh.Rules[i].Position < sh.Rules[j].Position })
+	sort.SliceStable(sh.Variants, func(i, j int) bool { return sh.Variants[i].Position < sh.Variants[j].Position })
+}
@@ -0,0 +1,41 @@
+package shorten_service
+
+import (
+	"testing"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCarryHits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		variants []*domain.ShortenVariant
+		old      []*domain.ShortenVariant
+		expect   []*domain.ShortenVariant
+	}{
+		{
+			name:     "Given destination that is still there should keep its id and hits",
+			variants: []*domain.ShortenVariant{{Destination: "https://b.com", Weight: 2}, {Destination: "https://a.com", Weight: 1}},
+			old:      []*domain.ShortenVariant{{ID: 1, Destination: "https://a.com", Hits: 10}, {ID: 2, Destination: "https://c.com", Hits: 5}},
+			expect:   []*domain.ShortenVariant{{Destination: "https://b.com", Weight: 2}, {ID: 1, Destination: "https://a.com", Weight: 1, Hits: 10}},
+		},
+		{
+			name:     "Given duplicated destination should only carry each old variant once",
+			variants: []*domain.ShortenVariant{{Destination: "https://a.com"}, {Destination: "https://a.com"}, {Destination: "https://a.com"}},
+			old:      []*domain.ShortenVariant{{ID: 1, Destination: "https://a.com", Hits: 3}, {ID: 2, Destination: "https://a.com", Hits: 4}},
+			expect:   []*domain.ShortenVariant{{ID: 1, Destination: "https://a.com", Hits: 3}, {ID: 2, Destination: "https://a.com", Hits: 4}, {Destination: "https://a.com"}},
+		},
+		{
+			name:     "Given no old variants should leave the variants as new ones",
+			variants: []*domain.ShortenVariant{{Destination: "https://a.com"}},
+			expect:   []*domain.ShortenVariant{{Destination: "https://a.com"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, carryHits(tc.variants, tc.old))
+		})
+	}
+}
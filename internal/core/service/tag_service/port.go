@@ -0,0 +1,33 @@
+package tag_service
+
+import (
+	"context"
+	"errors"
+
+	req "github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+)
+
+// ErrNotFound the requested Tag is not exist.
+var ErrNotFound = errors.New("tag was not found")
+
+// ErrTaken the requested name is already used by other Tag.
+var ErrTaken = errors.New("tag already exists")
+
+// IService an interface that should be used when dealing with tag.
+type IService interface {
+	// Index retrieve all tags ordered by their name along with the number of
+	// links and files that use each of them.
+	Index(context.Context) ([]*res.TagResponse, error)
+	// Create save a new Tag instance based on given request. Return ErrTaken
+	// if the name is already used.
+	Create(context.Context, *req.Tag) (*res.TagResponse, error)
+	// Update rename an existing Tag instance based on ID in given request.
+	// Return ErrNotFound if there is no Tag with given ID, or ErrTaken if the
+	// name is already used by other Tag.
+	Update(context.Context, *req.TagUpdate) (*res.TagResponse, error)
+	// Delete remove a Tag from DB along with its usage by any link and file
+	// using given id as the condition. Return ErrNotFound if there is no Tag
+	// with given ID.
+	Delete(context.Context, *req.TagDelete) error
+}
@@ -0,0 +1,94 @@
+package tag_service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/mdanialr/sns_backend/internal/core/repository/tag_repository"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	req "github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+)
+
+type tagSvc struct {
+	log  logger.Writer
+	repo tag_repository.IRepository
+}
+
+// New return implementation of core business logic for Tag service layer.
+func New(l logger.Writer, repo tag_repository.IRepository) IService {
+	return &tagSvc{l, repo}
+}
+
+func (t *tagSvc) Index(ctx context.Context) ([]*res.TagResponse, error) {
+	tags, err := t.repo.FindWithUsage(ctx)
+	if err != nil {
+		errMsg := "failed to retrieve all tag data"
+		t.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	return res.NewTagResponses(tags), nil
+}
+
+func (t *tagSvc) Create(ctx context.Context, req *req.Tag) (*res.TagResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	// make sure given name is not used yet
+	if o, _ := t.repo.GetByName(ctx, name); o.ID != 0 {
+		return nil, ErrTaken
+	}
+
+	tag := &domain.Tag{Name: name}
+	if _, err := t.repo.Create(ctx, tag); err != nil {
+		errMsg := "failed to create new Tag"
+		t.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	var r res.TagResponse
+	r.FromDomain(tag)
+
+	return &r, nil
+}
+
+func (t *tagSvc) Update(ctx context.Context, req *req.TagUpdate) (*res.TagResponse, error) {
+	tag, err := t.repo.GetByID(ctx, req.ID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	name := strings.TrimSpace(req.Name)
+	// make sure given name is not used yet by other tag
+	if o, _ := t.repo.GetByName(ctx, name); o.ID != 0 && o.ID != tag.ID {
+		return nil, ErrTaken
+	}
+
+	tag.Name = name
+	if _, err = t.repo.Update(ctx, tag); err != nil {
+		errMsg := "failed to update Tag with id " + strconv.Itoa(int(req.ID))
+		t.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	var r res.TagResponse
+	r.FromDomain(tag)
+
+	return &r, nil
+}
+
+func (t *tagSvc) Delete(ctx context.Context, req *req.TagDelete) error {
+	// check first if given id is exists in DB
+	tag, err := t.repo.GetByID(ctx, req.ID)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	if err = t.repo.DeleteByID(ctx, tag.ID); err != nil {
+		errMsg := "failed to delete Tag with id " + strconv.Itoa(int(req.ID))
+		t.log.Err(errMsg+":", err)
+		return errors.New(errMsg)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package tag_service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mdanialr/sns_backend/internal/core/repository/tag_repository/mocks"
+	"github.com/mdanialr/sns_backend/internal/core/service/tag_service"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"github.com/mdanialr/sns_backend/internal/requests"
+	"github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func newLog() logger.Writer {
+	l := logger.NewStdOut()
+	l.Init()
+	return l
+}
+
+func TestTagSvc_Create(t *testing.T) {
+	testCases := []struct {
+		name      string
+		req       *requests.Tag
+		setup     func(*mocks.Mocktag_repositoryIRepository)
+		expect    *responses.TagResponse
+		expectErr error
+	}{
+		{
+			name: "Given name that is already used should return ErrTaken",
+			req:  &requests.Tag{Name: "work"},
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByName(mock.Anything, "work").Return(&domain.Tag{ID: 1, Name: "work"}, nil).Once()
+			},
+			expectErr: tag_service.ErrTaken,
+		},
+		{
+			name: "Given new name surrounded by spaces should save the trimmed name",
+			req:  &requests.Tag{Name: "  work "},
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByName(mock.Anything, "work").Return(&domain.Tag{}, gorm.ErrRecordNotFound).Once()
+				repo.EXPECT().
+					Create(mock.Anything, mock.MatchedBy(func(t *domain.Tag) bool { return t.Name == "work" })).
+					Return(&domain.Tag{ID: 2, Name: "work"}, nil).
+					Once()
+			},
+			expect: &responses.TagResponse{Name: "work"},
+		},
+		{
+			name: "Given repository that fail to save should return error",
+			req:  &requests.Tag{Name: "work"},
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByName(mock.Anything, "work").Return(&domain.Tag{}, gorm.ErrRecordNotFound).Once()
+				repo.EXPECT().Create(mock.Anything, mock.Anything).Return(nil, errors.New("db down")).Once()
+			},
+			expectErr: errors.New("failed to create new Tag"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(mocks.Mocktag_repositoryIRepository)
+			tc.setup(repo)
+
+			got, err := tag_service.New(newLog(), repo).Create(context.Background(), tc.req)
+			if tc.expectErr != nil {
+				assert.EqualError(t, err, tc.expectErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, got)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTagSvc_Update(t *testing.T) {
+	testCases := []struct {
+		name      string
+		req       *requests.TagUpdate
+		setup     func(*mocks.Mocktag_repositoryIRepository)
+		expect    *responses.TagResponse
+		expectErr error
+	}{
+		{
+			name: "Given id that is not exist should return ErrNotFound",
+			req:  &requests.TagUpdate{ID: 9, Name: "home"},
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByID(mock.Anything, uint(9)).Return(&domain.Tag{}, gorm.ErrRecordNotFound).Once()
+			},
+			expectErr: tag_service.ErrNotFound,
+		},
+		{
+			name: "Given name that is used by other tag should return ErrTaken",
+			req:  &requests.TagUpdate{ID: 2, Name: "work"},
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByID(mock.Anything, uint(2)).Return(&domain.Tag{ID: 2, Name: "home"}, nil).Once()
+				repo.EXPECT().GetByName(mock.Anything, "work").Return(&domain.Tag{ID: 1, Name: "work"}, nil).Once()
+			},
+			expectErr: tag_service.ErrTaken,
+		},
+		{
+			name: "Given the same name of the tag itself should save it",
+			req:  &requests.TagUpdate{ID: 2, Name: "home"},
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByID(mock.Anything, uint(2)).Return(&domain.Tag{ID: 2, Name: "home"}, nil).Once()
+				repo.EXPECT().GetByName(mock.Anything, "home").Return(&domain.Tag{ID: 2, Name: "home"}, nil).Once()
+				repo.EXPECT().
+					Update(mock.Anything, mock.MatchedBy(func(t *domain.Tag) bool { return t.ID == 2 && t.Name == "home" })).
+					Return(&domain.Tag{ID: 2, Name: "home"}, nil).
+					Once()
+			},
+			expect: &responses.TagResponse{ID: 2, Name: "home"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(mocks.Mocktag_repositoryIRepository)
+			tc.setup(repo)
+
+			got, err := tag_service.New(newLog(), repo).Update(context.Background(), tc.req)
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, got)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTagSvc_Delete(t *testing.T) {
+	testCases := []struct {
+		name      string
+		setup     func(*mocks.Mocktag_repositoryIRepository)
+		expectErr string
+	}{
+		{
+			name: "Given id that is not exist should return ErrNotFound",
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByID(mock.Anything, uint(3)).Return(&domain.Tag{}, gorm.ErrRecordNotFound).Once()
+			},
+			expectErr: tag_service.ErrNotFound.Error(),
+		},
+		{
+			name: "Given repository that fail to delete should return error",
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByID(mock.Anything, uint(3)).Return(&domain.Tag{ID: 3}, nil).Once()
+				repo.EXPECT().DeleteByID(mock.Anything, uint(3)).Return(errors.New("db down")).Once()
+			},
+			expectErr: "failed to delete Tag with id 3",
+		},
+		{
+			name: "Given id that exists should delete it",
+			setup: func(repo *mocks.Mocktag_repositoryIRepository) {
+				repo.EXPECT().GetByID(mock.Anything, uint(3)).Return(&domain.Tag{ID: 3}, nil).Once()
+				repo.EXPECT().DeleteByID(mock.Anything, uint(3)).Return(nil).Once()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(mocks.Mocktag_repositoryIRepository)
+			tc.setup(repo)
+
+			err := tag_service.New(newLog(), repo).Delete(context.Background(), &requests.TagDelete{ID: 3})
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package upload_service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	req "github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+)
+
+var (
+	// ErrNotFound the upload is not exist or already expired.
+	ErrNotFound = errors.New("upload was not found")
+	// ErrOffsetMismatch the given offset is not the same as the current
+	// offset of the upload.
+	ErrOffsetMismatch = errors.New("upload offset does not match")
+	// ErrTooLarge the upload is larger than allowed by config.
+	ErrTooLarge = errors.New("upload is too large")
+	// ErrExceedLength the given chunk exceed the declared upload length.
+	ErrExceedLength = errors.New("chunk exceed the upload length")
+	// ErrFinalize the upload is complete but failed to be saved as a Send.
+	// The upload is kept, so it may be retried by writing an empty chunk at
+	// the final offset.
+	ErrFinalize = errors.New("failed to finalize upload, retry with an empty chunk")
+)
+
+// IService an interface that should be used when dealing with resumable
+// upload.
+type IService interface {
+	// Create start a new resumable upload based on given request. Return the
+	// newly created Upload back along with error if any.
+	Create(context.Context, *req.Upload) (*res.UploadResponse, error)
+	// Get retrieve an Upload that has given id. Return ErrNotFound if it's
+	// not exist or already expired.
+	Get(ctx context.Context, id string) (*res.UploadResponse, error)
+	// Write append given chunk to the Upload that has given id starting from
+	// given offset. Once the upload is complete, a Send is created and
+	// returned along with the Upload. Return ErrFinalize if the Send can't be
+	// created for now.
+	Write(ctx context.Context, id string, offset int64, chunk io.Reader) (*res.UploadResponse, error)
+	// Delete terminate an Upload that has given id and remove its file.
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,241 @@
+package upload_service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	repo "github.com/mdanialr/sns_backend/internal/core/repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/sns_repository"
+	"github.com/mdanialr/sns_backend/internal/core/repository/upload_repository"
+	"github.com/mdanialr/sns_backend/internal/core/service/send_service"
+	"github.com/mdanialr/sns_backend/internal/domain"
+	req "github.com/mdanialr/sns_backend/internal/requests"
+	res "github.com/mdanialr/sns_backend/internal/responses"
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	h "github.com/mdanialr/sns_backend/pkg/helper"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/mdanialr/sns_backend/pkg/scanner"
+	"github.com/mdanialr/sns_backend/pkg/sniff"
+	"github.com/mdanialr/sns_backend/pkg/storage"
+	"github.com/spf13/viper"
+)
+
+type uploadSvc struct {
+	log     logger.Writer
+	st      storage.IStorage
+	v       *viper.Viper
+	repo    upload_repository.IRepository
+	snsRepo sns_repository.IRepository
+	sendSvc send_service.IService
+	dom     *domains.Domains
+	// locks hold mutex for each upload id, so chunks of the same upload are
+	// never written concurrently.
+	locks sync.Map
+}
+
+// New return implementation of core business logic for resumable Upload
+// service layer. Finished upload is handed to given send service to be saved
+// as a new Send in one of given public domains.
+func New(l logger.Writer, s storage.IStorage, v *viper.Viper, r upload_repository.IRepository, sr sns_repository.IRepository, ss send_service.IService, d *domains.Domains) IService {
+	return &uploadSvc{log: l, st: s, v: v, repo: r, snsRepo: sr, sendSvc: ss, dom: d}
+}
+
+func (u *uploadSvc) Create(ctx context.Context, req *req.Upload) (*res.UploadResponse, error) {
+	// use this chance to clean up abandoned uploads
+	go u.purgeExpired()
+
+	if max := u.v.GetInt64("upload.max_size") * 1024 * 1024; max > 0 && req.Length > max {
+		return nil, ErrTooLarge
+	}
+	dom, err := u.dom.Normalize(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	// make sure given url is not used yet in the domain
+	o, _ := u.snsRepo.GetByUrl(ctx, dom, req.Url, repo.Cols("id"))
+	if o.ID != 0 {
+		return nil, errors.New("url already been taken")
+	}
+
+	// prepare new object to be saved to DB
+	exp := time.Now().Add(time.Duration(u.v.GetInt("upload.expiration")) * time.Minute)
+	up := &domain.Upload{
+		ID:          uuid.NewString(),
+		Length:      req.Length,
+		Metadata:    req.Metadata,
+		Filename:    req.Filename,
+		Domain:      dom,
+		Url:         req.Url,
+		Description: req.Description,
+		IsPermanent: h.Ptr(req.PermanentToBool()),
+		ExpiresAt:   &exp,
+	}
+	// create the empty file that will be appended by each chunk
+	if _, err := u.st.Append(strings.NewReader(""), u.filePath(up.ID)); err != nil {
+		errMsg := "failed to prepare upload file"
+		u.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+	if _, err := u.repo.Create(ctx, up); err != nil {
+		errMsg := "failed to create new Upload"
+		u.log.Err(errMsg+":", err)
+		go u.st.Remove(u.filePath(up.ID))
+		return nil, errors.New(errMsg)
+	}
+
+	var r res.UploadResponse
+	r.FromDomain(up)
+
+	return &r, nil
+}
+
+func (u *uploadSvc) Get(ctx context.Context, id string) (*res.UploadResponse, error) {
+	up, err := u.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var r res.UploadResponse
+	r.FromDomain(up)
+
+	return &r, nil
+}
+
+func (u *uploadSvc) Write(ctx context.Context, id string, offset int64, chunk io.Reader) (*res.UploadResponse, error) {
+	mu, _ := u.locks.LoadOrStore(id, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	up, err := u.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if up.Offset != offset {
+		return nil, ErrOffsetMismatch
+	}
+
+	// never write beyond the declared length, read one more byte to find out
+	// whether the chunk is longer than that
+	n, err := u.st.Append(io.LimitReader(chunk, up.Length-up.Offset+1), u.filePath(up.ID))
+	if n > up.Length-up.Offset {
+		// the extra byte was appended as well, so the upload is corrupted
+		u.remove(ctx, up)
+		return nil, ErrExceedLength
+	}
+	up.Offset += n
+	// keep the progress even if the chunk is only partially written, so the
+	// client may resume from there
+	if uErr := u.repo.UpdateOffset(ctx, up.ID, up.Offset); uErr != nil {
+		errMsg := "failed to update upload offset"
+		u.log.Err(errMsg+":", uErr)
+		return nil, errors.New(errMsg)
+	}
+	if err != nil {
+		errMsg := "failed to write upload chunk"
+		u.log.Err(errMsg+":", err)
+		return nil, errors.New(errMsg)
+	}
+
+	var r res.UploadResponse
+	r.FromDomain(up)
+	if up.Offset < up.Length {
+		return &r, nil
+	}
+
+	// the upload is complete, save it as a new Send
+	sn := &req.Upload{
+		Length:      up.Length,
+		Filename:    up.Filename,
+		Domain:      up.Domain,
+		Url:         up.Url,
+		Description: up.Description,
+		Permanent:   strconv.FormatBool(h.Def(up.IsPermanent)),
+	}
+	// keep the complete upload if it fails, it's removed once terminated by
+	// the client or expired
+	r.Send, err = u.sendSvc.Attach(ctx, sn, up.ID+partSuffix)
+	if isPermanent(err) {
+		return nil, err
+	}
+	if err != nil {
+		u.log.Err("failed to finalize upload", up.ID, ":", err)
+		return nil, ErrFinalize
+	}
+	// the file already belong to the Send, so only delete the record
+	if err = u.repo.DeleteByID(ctx, up.ID); err != nil {
+		u.log.Err("failed to delete finished upload", up.ID, ":", err)
+	}
+	u.locks.Delete(up.ID)
+
+	return &r, nil
+}
+
+func (u *uploadSvc) Delete(ctx context.Context, id string) error {
+	up, err := u.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	u.remove(ctx, up)
+
+	return nil
+}
+
+// get retrieve an Upload that has given id and make sure it's not expired.
+func (u *uploadSvc) get(ctx context.Context, id string) (*domain.Upload, error) {
+	up, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if up.ExpiresAt != nil && up.ExpiresAt.Before(time.Now()) {
+		go u.remove(context.Background(), up)
+		return nil, ErrNotFound
+	}
+
+	return up, nil
+}
+
+// remove delete given upload along with its file.
+func (u *uploadSvc) remove(ctx context.Context, up *domain.Upload) {
+	if err := u.repo.DeleteByID(ctx, up.ID); err != nil {
+		u.log.Err("failed to delete upload", up.ID, ":", err)
+		return
+	}
+	u.st.Remove(u.filePath(up.ID))
+	u.locks.Delete(up.ID)
+}
+
+// purgeExpired remove all uploads that already expired.
+func (u *uploadSvc) purgeExpired() {
+	ctx := context.Background()
+	ups, err := u.repo.FindExpired(ctx, time.Now())
+	if err != nil {
+		u.log.Err("failed to retrieve expired uploads:", err)
+		return
+	}
+	for _, up := range ups {
+		u.remove(ctx, up)
+	}
+}
+
+// isPermanent return true if given error from saving the upload as a Send
+// will never go away by retrying, so there is no point to retry it.
+func isPermanent(err error) bool {
+	return errors.Is(err, sniff.ErrRejected) || errors.Is(err, scanner.ErrRejected) ||
+		errors.Is(err, send_service.ErrUrlTaken) || errors.Is(err, domains.ErrUnknown)
+}
+
+// partSuffix suffix that is appended to the upload id as the filename until
+// the upload is complete.
+const partSuffix = ".part"
+
+// filePath return the file path of given upload id in Storage.
+func (u *uploadSvc) filePath(id string) string {
+	pt := strings.TrimSuffix(u.v.GetString("storage.path"), "/") + "/" // make sure to manually append slice
+	return pt + id + partSuffix
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Blob object for table `blobs`. A Blob is a content-addressed file in Storage
+// that may be shared by several domain.SNS which have the same content.
+type Blob struct {
+	ID uint `gorm:"primaryKey"`
+	// Hash hex encoded SHA-256 of the file content.
+	Hash string `gorm:"uniqueIndex;size:64"`
+	// Name the file name in Storage relative to the storage path.
+	Name string
+	// Size the file size in bytes.
+	Size int64
+	// RefCount the number of domain.SNS that still refer to this Blob.
+	RefCount  uint
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+func (b *Blob) TableName() string {
+	return "blobs"
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// IdempotencyKey object for table `idempotency_keys`. Key is given by the
+// client of a create request along with the Fingerprint of the request, so a
+// retry of the same request is answered using the kept response instead of
+// being run again. StatusCode is zero while the request is still in progress,
+// otherwise the response is kept in ContentType, ETag, Location and Body until
+// ExpiresAt.
+type IdempotencyKey struct {
+	Key         string `gorm:"primaryKey;size:255"`
+	Fingerprint string `gorm:"size:64;not null"`
+	StatusCode  int    `gorm:"not null;default:0"`
+	ContentType string
+	ETag        string `gorm:"column:etag;size:64"`
+	Location    string
+	Body        []byte
+	ExpiresAt   *time.Time `gorm:"index"`
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
+func (i *IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Done return whether the request that use the key is already finished.
+func (i *IdempotencyKey) Done() bool {
+	return i.StatusCode != 0
+}
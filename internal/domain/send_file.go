@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// SendFile object for table `send_files`. Each one is a file that belong to a
+// Send, so a Send may consist of several files.
+type SendFile struct {
+	ID    uint `gorm:"primaryKey"`
+	SNSID uint `gorm:"index"`
+	// Name the original filename that is presented to the client.
+	Name string
+	// Send the name of the file in Storage.
+	Send       string
+	Hash       string `gorm:"size:64"`
+	MimeType   string
+	Size       int64
+	ScanStatus *string `gorm:"size:16"`
+	CreatedAt  *time.Time
+}
+
+func (s *SendFile) TableName() string {
+	return "send_files"
+}
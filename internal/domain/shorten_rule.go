@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// ShortenRule object for table `shorten_rules`. Each one is a targeting rule
+// that belong to a Shorten, the rules are evaluated by Position and the first
+// one that match the client decide the destination. Query is url-encoded
+// parameters that should be in the request, and empty condition match any
+// client.
+type ShortenRule struct {
+	ID          uint `gorm:"primaryKey"`
+	SNSID       uint `gorm:"index"`
+	Position    int
+	Platform    string `gorm:"size:16"`
+	Language    string `gorm:"size:35"`
+	Query       string
+	Destination string
+	CreatedAt   *time.Time
+}
+
+func (s *ShortenRule) TableName() string {
+	return "shorten_rules"
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// ShortenVariant object for table `shorten_variants`. Each one is a weighted
+// destination that belong to a Shorten, the traffic of the Shorten is split
+// between its variants by their Weight. Hits is the number of times the
+// variant is picked.
+type ShortenVariant struct {
+	ID          uint `gorm:"primaryKey"`
+	SNSID       uint `gorm:"index"`
+	Position    int
+	Destination string
+	Weight      int
+	Hits        int64 `gorm:"not null;default:0"`
+	CreatedAt   *time.Time
+}
+
+func (s *ShortenVariant) TableName() string {
+	return "shorten_variants"
+}
@@ -6,18 +6,84 @@ import (
 	"gorm.io/gorm"
 )
 
-// SNS object for table `sns`.
+const (
+	// KindShorten SNS that redirect to the url in Shorten.
+	KindShorten = "shorten"
+	// KindSend SNS that serve the uploaded file in Send.
+	KindSend = "send"
+	// KindPaste SNS that serve the text in Paste.
+	KindPaste = "paste"
+)
+
+const (
+	// StatusScheduled SNS that is not public yet.
+	StatusScheduled = "scheduled"
+	// StatusActive SNS that is public.
+	StatusActive = "active"
+	// StatusEnded SNS that is not public anymore.
+	StatusEnded = "ended"
+)
+
+// SNS object for table `sns`. Kind use enum type `sns_kind` that should be
+// created before migrating this table, and each Kind requires its own payload
+// column to be filled. Url is unique within its Domain, where empty Domain is
+// the primary public domain. The destination of a Shorten is checked
+// periodically, HealthStatus is the status code of the last check, which is
+// zero if there was no response at all, and HealthFailures is the number of
+// consecutive checks that found it broken. An SNS is only served publicly
+// from ActiveFrom until ActiveUntil, either of them may be nil which means the
+// window is open on that side. Rules may send the client of a Shorten to other
+// destination than Shorten, otherwise the client is sent to one of its
+// Variants if there is any. DefaultParams is url-encoded parameters that are
+// added to the destination, while ForwardQuery decide how the query string of
+// the request is forwarded to the destination. Version is moved on by every
+// change that is made through the API, so a client may tell whether the SNS
+// was changed since it's read.
 type SNS struct {
-	ID          uint `gorm:"primaryKey"`
-	Url         string
-	Description string
-	Shorten     *string
-	Send        *string
-	FileSize    *string
-	IsPermanent *bool
-	CreatedAt   *time.Time
-	UpdatedAt   *time.Time
-	DeletedAt   gorm.DeletedAt ` gorm:"index"`
+	ID              uint   `gorm:"primaryKey"`
+	Kind            string `gorm:"type:sns_kind;not null;index;check:chk_sns_kind_payload,(kind <> 'shorten' OR shorten IS NOT NULL) AND (kind <> 'send' OR send IS NOT NULL) AND (kind <> 'paste' OR paste IS NOT NULL)"`
+	Domain          string `gorm:"size:253;not null;default:'';uniqueIndex:idx_sns_domain_url,where:deleted_at IS NULL"`
+	Url             string `gorm:"uniqueIndex:idx_sns_domain_url,where:deleted_at IS NULL"`
+	Description     string
+	Shorten         *string
+	Send            *string
+	Paste           *string
+	Language        *string `gorm:"size:32"`
+	FileSize        *string
+	Hash            *string `gorm:"index;size:64"`
+	MimeType        *string
+	Thumbnail       *string
+	ScanStatus      *string `gorm:"size:16"`
+	ScanDetail      *string
+	IsQuarantined   *bool
+	IsPermanent     *bool
+	ForwardQuery    string     `gorm:"size:16;not null;default:''"`
+	DefaultParams   string     `gorm:"not null;default:''"`
+	ActiveFrom      *time.Time `gorm:"index"`
+	ActiveUntil     *time.Time `gorm:"index"`
+	HealthStatus    *int
+	HealthCheckedAt *time.Time `gorm:"index"`
+	HealthFailures  int        `gorm:"not null;default:0"`
+	Version         int        `gorm:"not null;default:1"`
+	CreatedAt       *time.Time
+	UpdatedAt       *time.Time
+	DeletedAt       gorm.DeletedAt    ` gorm:"index"`
+	Files           []*SendFile       `gorm:"foreignKey:SNSID"`
+	Rules           []*ShortenRule    `gorm:"foreignKey:SNSID"`
+	Variants        []*ShortenVariant `gorm:"foreignKey:SNSID"`
+	Tags            []*Tag            `gorm:"many2many:sns_tags"`
+}
+
+// Status return whether the SNS is scheduled, active or ended at given time
+// according to its activation window.
+func (s *SNS) Status(now time.Time) string {
+	switch {
+	case s.ActiveFrom != nil && now.Before(*s.ActiveFrom):
+		return StatusScheduled
+	case s.ActiveUntil != nil && !now.Before(*s.ActiveUntil):
+		return StatusEnded
+	}
+	return StatusActive
 }
 
 func (s *SNS) TableName() string {
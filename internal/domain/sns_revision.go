@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// ActionCreate revision of a newly created SNS.
+	ActionCreate = "create"
+	// ActionUpdate revision of an updated SNS.
+	ActionUpdate = "update"
+	// ActionDelete revision of a deleted SNS.
+	ActionDelete = "delete"
+	// ActionRollback revision of an SNS that is restored to a past revision.
+	ActionRollback = "rollback"
+)
+
+// SNSRevision object for table `sns_revisions`. Each one record a change of
+// an SNS and it's never updated nor deleted. Before and After are the JSON of
+// SNSSnapshot before and after the change, where Before is nil for
+// ActionCreate and After is nil for ActionDelete. Actor is who made the
+// change.
+type SNSRevision struct {
+	ID        uint    `gorm:"primaryKey"`
+	SNSID     uint    `gorm:"index;not null"`
+	Action    string  `gorm:"size:16;not null"`
+	Actor     string  `gorm:"size:64;not null"`
+	Reason    string  `gorm:"size:255;not null;default:''"`
+	Before    *string `gorm:"type:jsonb"`
+	After     *string `gorm:"type:jsonb"`
+	CreatedAt *time.Time
+}
+
+func (s *SNSRevision) TableName() string {
+	return "sns_revisions"
+}
+
+// SNSSnapshot the values of an SNS that may be changed through the API.
+type SNSSnapshot struct {
+	Domain        string             `json:"domain"`
+	Url           string             `json:"url"`
+	Description   string             `json:"description"`
+	Shorten       *string            `json:"shorten,omitempty"`
+	IsPermanent   *bool              `json:"permanent,omitempty"`
+	ActiveFrom    *time.Time         `json:"active_from,omitempty"`
+	ActiveUntil   *time.Time         `json:"active_until,omitempty"`
+	ForwardQuery  string             `json:"forward_query,omitempty"`
+	DefaultParams string             `json:"default_params,omitempty"`
+	Rules         []*SnapshotRule    `json:"rules,omitempty"`
+	Variants      []*SnapshotVariant `json:"variants,omitempty"`
+}
+
+// SnapshotRule the values of a ShortenRule in SNSSnapshot.
+type SnapshotRule struct {
+	Platform    string `json:"platform,omitempty"`
+	Language    string `json:"language,omitempty"`
+	Query       string `json:"query,omitempty"`
+	Destination string `json:"destination"`
+}
+
+// SnapshotVariant the values of a ShortenVariant in SNSSnapshot.
+type SnapshotVariant struct {
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// NewSnapshot take the snapshot of given SNS, nil if the SNS is nil. The rules
+// and variants are expected to be sorted by their position.
+func NewSnapshot(s *SNS) *SNSSnapshot {
+	if s == nil {
+		return nil
+	}
+	ss := &SNSSnapshot{
+		Domain:        s.Domain,
+		Url:           s.Url,
+		Description:   s.Description,
+		Shorten:       s.Shorten,
+		IsPermanent:   s.IsPermanent,
+		ActiveFrom:    s.ActiveFrom,
+		ActiveUntil:   s.ActiveUntil,
+		ForwardQuery:  s.ForwardQuery,
+		DefaultParams: s.DefaultParams,
+	}
+	for _, rl := range s.Rules {
+		ss.Rules = append(ss.Rules, &SnapshotRule{rl.Platform, rl.Language, rl.Query, rl.Destination})
+	}
+	for _, v := range s.Variants {
+		ss.Variants = append(ss.Variants, &SnapshotVariant{v.Destination, v.Weight})
+	}
+	return ss
+}
+
+// Apply set the values in the snapshot to given SNS, including its rules and
+// variants.
+func (ss *SNSSnapshot) Apply(s *SNS) {
+	s.Domain = ss.Domain
+	s.Url = ss.Url
+	s.Description = ss.Description
+	s.Shorten = ss.Shorten
+	s.IsPermanent = ss.IsPermanent
+	s.ActiveFrom = ss.ActiveFrom
+	s.ActiveUntil = ss.ActiveUntil
+	s.ForwardQuery = ss.ForwardQuery
+	s.DefaultParams = ss.DefaultParams
+	// never nil, so the rules and variants are replaced even if there is none
+	s.Rules = make([]*ShortenRule, 0, len(ss.Rules))
+	s.Variants = make([]*ShortenVariant, 0, len(ss.Variants))
+	for i, rl := range ss.Rules {
+		s.Rules = append(s.Rules, &ShortenRule{
+			Position:    i,
+			Platform:    rl.Platform,
+			Language:    rl.Language,
+			Query:       rl.Query,
+			Destination: rl.Destination,
+		})
+	}
+	for i, v := range ss.Variants {
+		s.Variants = append(s.Variants, &ShortenVariant{Position: i, Destination: v.Destination, Weight: v.Weight})
+	}
+}
+
+// NewRevision return SNSRevision of given action on the SNS that has given id
+// using the SNS before and after the change, either of them may be nil.
+func NewRevision(id uint, action, actor, reason string, before, after *SNS) (*SNSRevision, error) {
+	rv := &SNSRevision{SNSID: id, Action: action, Actor: actor, Reason: reason}
+	var err error
+	if rv.Before, err = marshalSnapshot(before); err != nil {
+		return nil, err
+	}
+	if rv.After, err = marshalSnapshot(after); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Snapshot return the snapshot in After, nil if there is none.
+func (s *SNSRevision) Snapshot() (*SNSSnapshot, error) {
+	if s.After == nil {
+		return nil, nil
+	}
+	var ss SNSSnapshot
+	if err := json.Unmarshal([]byte(*s.After), &ss); err != nil {
+		return nil, err
+	}
+	return &ss, nil
+}
+
+// marshalSnapshot marshal the snapshot of given SNS, nil if it's nil.
+func marshalSnapshot(s *SNS) (*string, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(NewSnapshot(s))
+	if err != nil {
+		return nil, err
+	}
+	str := string(b)
+	return &str, nil
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Tag object for table `tags`. A Tag group SNS of any kind, while each SNS may
+// have several tags through table `sns_tags`. Usage is the number of SNS that
+// use the Tag, which is only filled when it's counted.
+type Tag struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"size:64;not null;uniqueIndex"`
+	Usage     int64  `gorm:"->;-:migration"`
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+func (t *Tag) TableName() string {
+	return "tags"
+}
+
+// TagNames trim each of given names, then drop the empty and the repeated
+// ones while keeping their order.
+func TagNames(names []string) []string {
+	out := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	return out
+}
+
+// NewTags return a Tag for each of given names after passing them to
+// TagNames. The result is never nil, so it can tell apart no tags from tags
+// that are not loaded.
+func NewTags(names []string) []*Tag {
+	names = TagNames(names)
+	tags := make([]*Tag, 0, len(names))
+	for _, n := range names {
+		tags = append(tags, &Tag{Name: n})
+	}
+	return tags
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Upload object for table `uploads`. An Upload is an unfinished resumable
+// upload whose chunks are appended to Storage until Offset reach Length.
+type Upload struct {
+	ID string `gorm:"primaryKey;size:36"`
+	// Length the total size of the file in bytes.
+	Length int64
+	// Offset the number of bytes that already been received.
+	Offset int64
+	// Metadata the raw Upload-Metadata header that was given on creation.
+	Metadata    string
+	Filename    string
+	Domain      string
+	Url         string
+	Description string
+	IsPermanent *bool
+	ExpiresAt   *time.Time `gorm:"index"`
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
+func (u *Upload) TableName() string {
+	return "uploads"
+}
@@ -0,0 +1,120 @@
+package requests
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
+)
+
+// Paste standard request object that may be used to parse request in
+// /paste & /paste/create endpoints.
+type Paste struct {
+	Url string `json:"url" validate:"required"`
+	// Domain one of the public domains that serve Url. Default to the primary
+	// domain.
+	Domain      string `json:"domain" validate:"omitempty,max=253"`
+	Description string `json:"description" validate:"required"`
+	Paste       string `json:"paste" validate:"required"`
+	// Language optional hint of the language of the text, e.g. go or json.
+	Language  string `json:"language" validate:"omitempty,max=32"`
+	Permanent string `json:"permanent" validate:"required,boolean"`
+
+	paginate.M `json:"-"`
+	// Order the column to query Order, only the ones in the validation are
+	// accepted. Default to id.
+	Order string `json:"-" query:"order" validate:"omitempty,oneof=id url domain description created_at updated_at"`
+	// Sort to query Order. Should be filled with either asc or desc. Default
+	// to asc.
+	Sort string `json:"-" query:"sort"`
+	// Search do search for url from given string.
+	Search string `json:"-" query:"search"`
+}
+
+// PermanentToBool convert Permanent field to bool.
+func (p *Paste) PermanentToBool() bool {
+	b, _ := strconv.ParseBool(p.Permanent)
+	return b
+}
+
+// SetQuery do setup Order and Sort.
+func (p *Paste) SetQuery() {
+	if p.Order == "" {
+		p.Order = "id" // set default to id
+	}
+	// sanitize Sort
+	p.Sort = p.sanitizeQuerySort()
+	if p.Sort == "" {
+		p.Sort = "asc" // set default to asc
+	}
+	// make sure the Sort is upper case
+	p.Sort = strings.ToUpper(p.Sort)
+}
+
+// ValidateQuery validation rules for Paste that should be parsed from request
+// query to list them.
+func (p *Paste) ValidateQuery() validator.ValidationErrors {
+	if err := validate.StructPartial(p, "Order"); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// sanitizeQuerySort make sure Sort has the expected value.
+func (p *Paste) sanitizeQuerySort() string {
+	switch strings.ToLower(p.Sort) {
+	case "asc", "desc":
+		return p.Sort
+	}
+	return ""
+}
+
+// Validate validation rules for Paste that should be parsed from request
+// body.
+func (p *Paste) Validate() validator.ValidationErrors {
+	if err := validate.Struct(p); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// PasteUpdate standard request object that may be used to parse request in
+// /paste/update endpoint.
+type PasteUpdate struct {
+	ID          uint    `json:"id" validate:"required,numeric"`
+	Url         string  `json:"url" validate:"required"`
+	Domain      string  `json:"domain" validate:"omitempty,max=253"`
+	Description string  `json:"description" validate:"required"`
+	Paste       *string `json:"paste" validate:"required"`
+	Language    *string `json:"language" validate:"omitempty,max=32"`
+	Permanent   string  `json:"permanent" validate:"required,boolean"`
+}
+
+// Validate validation rules for PasteUpdate.
+func (p *PasteUpdate) Validate() validator.ValidationErrors {
+	if err := validate.Struct(p); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// PermanentToBool convert Permanent field to bool.
+func (p *PasteUpdate) PermanentToBool() bool {
+	b, _ := strconv.ParseBool(p.Permanent)
+	return b
+}
+
+// PasteDelete standard request object that may be used to parse request in
+// /paste/delete endpoint.
+type PasteDelete struct {
+	ID uint `json:"id" validate:"required,numeric"`
+}
+
+// Validate validation rules for PasteDelete.
+func (p *PasteDelete) Validate() validator.ValidationErrors {
+	if err := validate.Struct(p); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package requests
+
+// Precondition the version of a link that the client expects to change, which
+// is taken from the If-Match header rather than the body. Zero means the
+// change doesn't depend on the version.
+type Precondition struct {
+	Version int `json:"-" form:"-" params:"-"`
+}
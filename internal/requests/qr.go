@@ -0,0 +1,51 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mdanialr/sns_backend/pkg/qrcode"
+)
+
+// QR standard request object that may be used to parse request in
+// /shorten/:id/qr & /send/:id/qr endpoints.
+type QR struct {
+	ID     uint   `params:"id" validate:"required"`
+	Format string `query:"format" validate:"oneof=png svg"`
+	Size   int    `query:"size" validate:"min=64,max=2048"`
+	// Level the error correction level, either L, M, Q or H.
+	Level string `query:"level" validate:"oneof=L M Q H"`
+	// Quiet the number of blank modules around the code.
+	Quiet *int `query:"quiet" validate:"omitempty,min=0,max=16"`
+}
+
+// SetDefault fill the optional fields that are not set.
+func (q *QR) SetDefault() {
+	if q.Format == "" {
+		q.Format = qrcode.FormatPNG
+	}
+	if q.Size == 0 {
+		q.Size = 256
+	}
+	if q.Level == "" {
+		q.Level = "M"
+	}
+	q.Level = strings.ToUpper(q.Level)
+	if q.Quiet == nil {
+		q.Quiet = new(int)
+		*q.Quiet = 4 // the minimum quiet zone from the spec
+	}
+}
+
+// Options return the options to render the QR code.
+func (q *QR) Options() qrcode.Options {
+	return qrcode.Options{Format: q.Format, Size: q.Size, Level: q.Level, Quiet: *q.Quiet}
+}
+
+// Validate validation rules for QR.
+func (q *QR) Validate() validator.ValidationErrors {
+	if err := validate.Struct(q); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package requests
+
+import "github.com/go-playground/validator/v10"
+
+// Audit the reason of a change that is recorded in the revision of a link.
+type Audit struct {
+	Reason string `json:"reason" form:"reason" validate:"omitempty,max=255"`
+}
+
+// Revisions standard request object that may be used to parse request in
+// /shorten/:id/revisions & /send/:id/revisions endpoints.
+type Revisions struct {
+	ID uint `params:"id" validate:"required"`
+}
+
+// Validate validation rules for Revisions.
+func (r *Revisions) Validate() validator.ValidationErrors {
+	if err := validate.Struct(r); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// Rollback standard request object that may be used to parse request in
+// /shorten/:id/rollback & /send/:id/rollback endpoints.
+type Rollback struct {
+	ID         uint `params:"id" validate:"required"`
+	RevisionID uint `json:"revision_id" validate:"required"`
+	Audit
+	Precondition
+}
+
+// Validate validation rules for Rollback.
+func (r *Rollback) Validate() validator.ValidationErrors {
+	if err := validate.Struct(r); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
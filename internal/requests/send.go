@@ -12,19 +12,36 @@ import (
 // Send standard request object that may be used to parse request in
 // /send.
 type Send struct {
-	Url         string                `form:"url" validate:"required"`
-	Description string                `form:"description" validate:"required"`
-	Send        *multipart.FileHeader `form:"send" validate:"required"`
-	Permanent   string                `form:"permanent" validate:"required,boolean"`
+	Url string `form:"url" validate:"required"`
+	// Domain one of the public domains that serve Url. Default to the primary
+	// domain.
+	Domain      string                  `form:"domain" validate:"omitempty,max=253"`
+	Description string                  `form:"description" validate:"required"`
+	Send        []*multipart.FileHeader `form:"send" validate:"required,min=1,dive,required"`
+	Permanent   string                  `form:"permanent" validate:"required,boolean"`
+	// Tags the names of the tags of the Send, the ones that don't exist yet
+	// are created. The field may be repeated for each tag.
+	Tags []string `form:"tags" query:"-" validate:"omitempty,max=20,dive,required,max=64"`
+	Window
+	Audit
 
 	paginate.M
-	// Order the field name to query Order. Default to id.
-	Order string `json:"-" query:"order"`
+	// Order the column to query Order, only the ones in the validation are
+	// accepted. Default to id.
+	Order string `json:"-" query:"order" validate:"omitempty,oneof=id url domain description created_at updated_at active_from active_until"`
 	// Sort to query Order. Should be filled with either asc or desc. Default
 	// to asc.
 	Sort string `json:"-" query:"sort"`
 	// Search do search for url from given string.
 	Search string `json:"-" query:"search"`
+	// Status filter by the activation window, either domain.StatusScheduled,
+	// domain.StatusActive or domain.StatusEnded.
+	Status string `json:"-" query:"status" validate:"omitempty,oneof=scheduled active ended"`
+	// Tagged filter by the names of the tags, separated by comma.
+	Tagged []string `json:"-" query:"tags"`
+	// Match whether the Send should have every tag in Tagged, either
+	// MatchAll or MatchAny. Default to MatchAny.
+	Match string `json:"-" query:"match"`
 }
 
 // PermanentToBool convert Permanent field to bool.
@@ -33,6 +50,15 @@ func (s *Send) PermanentToBool() bool {
 	return b
 }
 
+// Size return the total size of all files in Send.
+func (s *Send) Size() int64 {
+	var size int64
+	for _, f := range s.Send {
+		size += f.Size
+	}
+	return size
+}
+
 // SetQuery do setup Order and Sort.
 func (s *Send) SetQuery() {
 	if s.Order == "" {
@@ -47,6 +73,15 @@ func (s *Send) SetQuery() {
 	s.Sort = strings.ToUpper(s.Sort)
 }
 
+// ValidateQuery validation rules for Send that should be parsed from request
+// query to list them.
+func (s *Send) ValidateQuery() validator.ValidationErrors {
+	if err := validate.StructPartial(s, "Order", "Status"); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
 // sanitizeQuerySort make sure Sort has the expected value.
 func (s *Send) sanitizeQuerySort() string {
 	switch strings.ToLower(s.Sort) {
@@ -66,13 +101,19 @@ func (s *Send) Validate() validator.ValidationErrors {
 }
 
 // SendUpdate standard request object that may be used to parse request in
-// /send/update endpoint.
+// /send/:id & /send/update endpoints.
 type SendUpdate struct {
-	ID          uint   `form:"id" validate:"required,numeric"`
-	Url         string `form:"url" validate:"required"`
-	Description string `form:"description" validate:"required"`
-	Send        *multipart.FileHeader
-	Permanent   string `form:"permanent" validate:"required,boolean"`
+	ID          uint                  `form:"id" params:"id" validate:"required,numeric"`
+	Url         string                `form:"url" validate:"required"`
+	Domain      string                `form:"domain" validate:"omitempty,max=253"`
+	Description string                `form:"description" validate:"required"`
+	Send        *multipart.FileHeader `form:"send"`
+	Permanent   string                `form:"permanent" validate:"required,boolean"`
+	// Tags replace the existing tags, empty means remove all of them.
+	Tags []string `form:"tags" validate:"omitempty,max=20,dive,required,max=64"`
+	Window
+	Audit
+	Precondition
 }
 
 // Validate validation rules for SendUpdate.
@@ -89,10 +130,51 @@ func (s *SendUpdate) PermanentToBool() bool {
 	return b
 }
 
+// SendPatch standard request object that may be used to parse request in
+// PATCH /send/:id endpoint. Only the fields that are given are changed. Tags
+// replace the existing ones as a whole, a single empty tag means remove all of
+// them.
+type SendPatch struct {
+	ID          uint     `json:"-" form:"-" params:"id" validate:"required"`
+	Url         *string  `json:"url" form:"url" validate:"omitempty,min=1"`
+	Domain      *string  `json:"domain" form:"domain" validate:"omitempty,max=253"`
+	Description *string  `json:"description" form:"description"`
+	Permanent   *string  `json:"permanent" form:"permanent" validate:"omitempty,boolean"`
+	Tags        []string `json:"tags" form:"tags" validate:"omitempty,max=20,dive,max=64"`
+	WindowPatch
+	Audit
+	Precondition
+}
+
+// Validate validation rules for SendPatch, only the given fields are
+// validated.
+func (s *SendPatch) Validate() validator.ValidationErrors {
+	if err := validate.Struct(s); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// SendGet standard request object that may be used to parse request in
+// /send/:id endpoint.
+type SendGet struct {
+	ID uint `params:"id" validate:"required"`
+}
+
+// Validate validation rules for SendGet.
+func (s *SendGet) Validate() validator.ValidationErrors {
+	if err := validate.Struct(s); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
 // SendDelete standard request object that may be used to parse request in
-// /send/delete endpoint.
+// /send/:id & /send/delete endpoints.
 type SendDelete struct {
-	ID uint `json:"id" validate:"required,numeric"`
+	ID uint `json:"id" params:"id" validate:"required,numeric"`
+	Audit
+	Precondition
 }
 
 // Validate validation rules for SendDelete.
@@ -11,21 +11,62 @@ import (
 // Shorten standard request object that may be used to parse request in
 // /shorten & /shorten/create endpoints.
 type Shorten struct {
-	Url         string `json:"url" validate:"required"`
+	Url string `json:"url" validate:"required"`
+	// Domain one of the public domains that serve Url. Default to the primary
+	// domain.
+	Domain      string `json:"domain" validate:"omitempty,max=253"`
 	Description string `json:"description" validate:"required"`
 	Shorten     string `json:"shorten" validate:"required,url"`
 	Permanent   string `json:"permanent" validate:"required,boolean"`
+	// Rules send the client that match one of them to other destination than
+	// Shorten, the first one that match win.
+	Rules []*ShortenRule `json:"rules" validate:"omitempty,max=20,dive"`
+	// Variants split the traffic between several destinations instead of
+	// only Shorten.
+	Variants []*ShortenVariant `json:"variants" validate:"omitempty,max=10,dive"`
+	// Forward how the query string of the request is forwarded to the
+	// destination, either merge or override. Empty means never forward it.
+	Forward string `json:"forward" validate:"omitempty,oneof=merge override"`
+	// Params the parameters that are added to the destination if it doesn't
+	// have them yet. The value may use {slug} and {domain} placeholders.
+	Params map[string]string `json:"params" validate:"omitempty,max=20"`
+	// Tags the names of the tags of the Shorten, the ones that don't exist
+	// yet are created.
+	Tags []string `json:"tags" query:"-" validate:"omitempty,max=20,dive,required,max=64"`
+	Window
+	Audit
 
-	paginate.M
-	// Order the field name to query Order. Default to id.
-	Order string `json:"-" query:"order"`
+	paginate.M `json:"-"`
+	// Order the column to query Order, only the ones in the validation are
+	// accepted. Default to id.
+	Order string `json:"-" query:"order" validate:"omitempty,oneof=id url domain description created_at updated_at active_from active_until health_checked_at"`
 	// Sort to query Order. Should be filled with either asc or desc. Default
 	// to asc.
 	Sort string `json:"-" query:"sort"`
 	// Search do search for url from given string.
 	Search string `json:"-" query:"search"`
+	// Health filter by the health of the destination, either HealthBroken,
+	// HealthHealthy or HealthUnchecked.
+	Health string `json:"-" query:"health"`
+	// Status filter by the activation window, either domain.StatusScheduled,
+	// domain.StatusActive or domain.StatusEnded.
+	Status string `json:"-" query:"status" validate:"omitempty,oneof=scheduled active ended"`
+	// Tagged filter by the names of the tags, separated by comma.
+	Tagged []string `json:"-" query:"tags"`
+	// Match whether the Shorten should have every tag in Tagged, either
+	// MatchAll or MatchAny. Default to MatchAny.
+	Match string `json:"-" query:"match"`
 }
 
+const (
+	// HealthBroken destination that keep failing on the last checks.
+	HealthBroken = "broken"
+	// HealthHealthy destination that is alive on the last checks.
+	HealthHealthy = "healthy"
+	// HealthUnchecked destination that is never checked yet.
+	HealthUnchecked = "unchecked"
+)
+
 // PermanentToBool convert Permanent field to bool.
 func (s *Shorten) PermanentToBool() bool {
 	b, _ := strconv.ParseBool(s.Permanent)
@@ -46,6 +87,15 @@ func (s *Shorten) SetQuery() {
 	s.Sort = strings.ToUpper(s.Sort)
 }
 
+// ValidateQuery validation rules for Shorten that should be parsed from request
+// query to list them.
+func (s *Shorten) ValidateQuery() validator.ValidationErrors {
+	if err := validate.StructPartial(s, "Order", "Status"); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
 // sanitizeQuerySort make sure Sort has the expected value.
 func (s *Shorten) sanitizeQuerySort() string {
 	switch strings.ToLower(s.Sort) {
@@ -65,13 +115,27 @@ func (s *Shorten) Validate() validator.ValidationErrors {
 }
 
 // ShortenUpdate standard request object that may be used to parse request in
-// /shorten/update endpoint.
+// /shorten/:id & /shorten/update endpoints.
 type ShortenUpdate struct {
-	ID          uint    `json:"id" validate:"required,numeric"`
+	ID          uint    `json:"id" params:"id" validate:"required,numeric"`
 	Url         string  `json:"url" validate:"required"`
+	Domain      string  `json:"domain" validate:"omitempty,max=253"`
 	Description string  `json:"description" validate:"required"`
 	Shorten     *string `json:"shorten" validate:"required,url"`
 	Permanent   string  `json:"permanent" validate:"required,boolean"`
+	// Rules replace the existing rules, empty means remove all of them.
+	Rules []*ShortenRule `json:"rules" validate:"omitempty,max=20,dive"`
+	// Variants replace the existing variants, empty means remove all of them.
+	// The hits of a variant are kept as long as its destination is not
+	// changed.
+	Variants []*ShortenVariant `json:"variants" validate:"omitempty,max=10,dive"`
+	Forward  string            `json:"forward" validate:"omitempty,oneof=merge override"`
+	Params   map[string]string `json:"params" validate:"omitempty,max=20"`
+	// Tags replace the existing tags, empty means remove all of them.
+	Tags []string `json:"tags" validate:"omitempty,max=20,dive,required,max=64"`
+	Window
+	Audit
+	Precondition
 }
 
 // Validate validation rules for ShortenUpdate.
@@ -88,10 +152,88 @@ func (s *ShortenUpdate) PermanentToBool() bool {
 	return b
 }
 
+// ShortenPatch standard request object that may be used to parse request in
+// PATCH /shorten/:id endpoint. Only the fields that are given are changed,
+// where null is the same as not given. Rules, Variants, Params and Tags
+// replace the existing ones as a whole, empty means remove all of them.
+type ShortenPatch struct {
+	ID          uint    `json:"-" params:"id" validate:"required"`
+	Url         *string `json:"url" validate:"omitempty,min=1"`
+	Domain      *string `json:"domain" validate:"omitempty,max=253"`
+	Description *string `json:"description"`
+	Shorten     *string `json:"shorten" validate:"omitempty,url"`
+	Permanent   *string `json:"permanent" validate:"omitempty,boolean"`
+	// Forward empty means never forward the query string.
+	Forward  *string           `json:"forward" validate:"omitempty,oneof='' merge override"`
+	Rules    []*ShortenRule    `json:"rules" validate:"omitempty,max=20,dive"`
+	Variants []*ShortenVariant `json:"variants" validate:"omitempty,max=10,dive"`
+	Params   map[string]string `json:"params" validate:"omitempty,max=20"`
+	Tags     []string          `json:"tags" validate:"omitempty,max=20,dive,required,max=64"`
+	WindowPatch
+	Audit
+	Precondition
+}
+
+// Validate validation rules for ShortenPatch, only the given fields are
+// validated.
+func (s *ShortenPatch) Validate() validator.ValidationErrors {
+	if err := validate.Struct(s); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// ShortenRule a targeting rule of a Shorten in Shorten & ShortenUpdate. The
+// client should match every condition that is set, where Query is the
+// parameters that should be in the request along with their value.
+type ShortenRule struct {
+	Platform    string            `json:"platform" validate:"omitempty,oneof=ios android desktop"`
+	Language    string            `json:"language" validate:"omitempty,max=35"`
+	Query       map[string]string `json:"query" validate:"omitempty,max=10"`
+	Destination string            `json:"destination" validate:"required,url"`
+}
+
+// ShortenVariant a weighted destination of a Shorten in Shorten &
+// ShortenUpdate.
+type ShortenVariant struct {
+	Destination string `json:"destination" validate:"required,url"`
+	Weight      int    `json:"weight" validate:"required,min=1,max=1000"`
+}
+
+// ShortenVariants standard request object that may be used to parse request
+// in /shorten/:id/variants endpoint.
+type ShortenVariants struct {
+	ID uint `params:"id" validate:"required"`
+}
+
+// Validate validation rules for ShortenVariants.
+func (s *ShortenVariants) Validate() validator.ValidationErrors {
+	if err := validate.Struct(s); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// ShortenGet standard request object that may be used to parse request in
+// /shorten/:id endpoint.
+type ShortenGet struct {
+	ID uint `params:"id" validate:"required"`
+}
+
+// Validate validation rules for ShortenGet.
+func (s *ShortenGet) Validate() validator.ValidationErrors {
+	if err := validate.Struct(s); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
 // ShortenDelete standard request object that may be used to parse request in
-// /shorten/delete endpoint.
+// /shorten/:id & /shorten/delete endpoints.
 type ShortenDelete struct {
-	ID uint `json:"id" validate:"required,numeric"`
+	ID uint `json:"id" params:"id" validate:"required,numeric"`
+	Audit
+	Precondition
 }
 
 // Validate validation rules for ShortenDelete.
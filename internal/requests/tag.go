@@ -0,0 +1,53 @@
+package requests
+
+import "github.com/go-playground/validator/v10"
+
+const (
+	// MatchAny filter the links that has any of the requested tags.
+	MatchAny = "any"
+	// MatchAll filter the links that has every requested tag.
+	MatchAll = "all"
+)
+
+// Tag standard request object that may be used to parse request in
+// /tags & /tags/create endpoints.
+type Tag struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+// Validate validation rules for Tag.
+func (t *Tag) Validate() validator.ValidationErrors {
+	if err := validate.Struct(t); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// TagUpdate standard request object that may be used to parse request in
+// /tags/:id & /tags/update endpoints.
+type TagUpdate struct {
+	ID   uint   `json:"id" params:"id" validate:"required,numeric"`
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+// Validate validation rules for TagUpdate.
+func (t *TagUpdate) Validate() validator.ValidationErrors {
+	if err := validate.Struct(t); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
+
+// TagDelete standard request object that may be used to parse request in
+// /tags/:id & /tags/delete endpoints.
+type TagDelete struct {
+	ID uint `json:"id" params:"id" validate:"required,numeric"`
+}
+
+// Validate validation rules for TagDelete.
+func (t *TagDelete) Validate() validator.ValidationErrors {
+	if err := validate.Struct(t); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package requests
+
+import (
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Upload standard request object that may be used to parse request in
+// /uploads creation endpoint. Other than Length and Metadata, all fields are
+// decoded from the Metadata by calling ParseMetadata.
+type Upload struct {
+	Length   int64  `reqHeader:"Upload-Length" validate:"required,min=1"`
+	Metadata string `reqHeader:"Upload-Metadata"`
+
+	Filename    string `validate:"required"`
+	Url         string `validate:"required"`
+	Domain      string `validate:"omitempty,max=253"`
+	Description string `validate:"required"`
+	Permanent   string `validate:"required,boolean"`
+}
+
+// ParseMetadata decode Metadata that should be comma separated key value
+// pairs where each value is base64 encoded, then fill the respective fields.
+// Unknown keys are ignored, while a filename that is not a plain file name is
+// rejected.
+func (u *Upload) ParseMetadata() error {
+	for _, pair := range strings.Split(u.Metadata, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		dec, err := base64.StdEncoding.DecodeString(val)
+		if err != nil {
+			return errors.New("metadata " + key + " is not base64 encoded")
+		}
+		switch key {
+		case "filename":
+			if u.Filename, err = cleanFilename(string(dec)); err != nil {
+				return err
+			}
+		case "url":
+			u.Url = string(dec)
+		case "domain":
+			u.Domain = string(dec)
+		case "description":
+			u.Description = string(dec)
+		case "permanent":
+			u.Permanent = string(dec)
+		}
+	}
+	return nil
+}
+
+// cleanFilename return given name as a plain file name. Unlike the name of a
+// multipart file, it's given as is by the client, so reject the one that has
+// a path separator, `..`, a quote or a control character such as CR or LF.
+func cleanFilename(name string) (string, error) {
+	if strings.ContainsAny(name, `/\"'`) || strings.Contains(name, "..") ||
+		strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", errors.New("metadata filename should be a plain file name")
+	}
+	if name == "" {
+		return "", nil
+	}
+	return filepath.Base(name), nil
+}
+
+// PermanentToBool convert Permanent field to bool.
+func (u *Upload) PermanentToBool() bool {
+	b, _ := strconv.ParseBool(u.Permanent)
+	return b
+}
+
+// Validate validation rules for Upload.
+func (u *Upload) Validate() validator.ValidationErrors {
+	if err := validate.Struct(u); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+	return nil
+}
@@ -2,4 +2,12 @@ package requests
 
 import "github.com/go-playground/validator/v10"
 
-var validate = validator.New()
+var validate = newValidator()
+
+// newValidator return validator along with the custom validations.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("after", isAfter)
+	v.RegisterValidation("opt_datetime", isOptDatetime)
+	return v
+}
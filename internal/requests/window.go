@@ -0,0 +1,72 @@
+package requests
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	h "github.com/mdanialr/sns_backend/pkg/helper"
+)
+
+// Window the optional activation window of a link, the link is only served
+// publicly within this window. Both of them are in RFC3339 format, and empty
+// means the window is open on that side.
+type Window struct {
+	ActiveFrom  string `json:"active_from" form:"active_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	ActiveUntil string `json:"active_until" form:"active_until" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00,after=ActiveFrom"`
+}
+
+// Times convert ActiveFrom and ActiveUntil to time, nil if it's empty.
+func (w *Window) Times() (from, until *time.Time) {
+	return parseTime(w.ActiveFrom), parseTime(w.ActiveUntil)
+}
+
+// WindowPatch the activation window of a link in a partial update. Nil means
+// the side is left as it is, while empty means the window is open on that
+// side.
+type WindowPatch struct {
+	ActiveFrom  *string `json:"active_from" form:"active_from" validate:"omitempty,opt_datetime"`
+	ActiveUntil *string `json:"active_until" form:"active_until" validate:"omitempty,opt_datetime,after=ActiveFrom"`
+}
+
+// Times convert ActiveFrom and ActiveUntil to time, nil if it's nil or empty.
+func (w *WindowPatch) Times() (from, until *time.Time) {
+	return parseTime(h.Def(w.ActiveFrom)), parseTime(h.Def(w.ActiveUntil))
+}
+
+// parseTime parse given RFC3339 string, return nil if it's empty or invalid.
+func parseTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// isAfter validate that the RFC3339 time in the field is after the one in the
+// field that is named in the param. Always valid if either of them is empty
+// or nil.
+func isAfter(fl validator.FieldLevel) bool {
+	other := fl.Parent().FieldByName(fl.Param())
+	if !other.IsValid() {
+		return false
+	}
+	if other.Kind() == reflect.Ptr {
+		if other.IsNil() {
+			return true
+		}
+		other = other.Elem()
+	}
+	t, o := parseTime(fl.Field().String()), parseTime(other.String())
+	if t == nil || o == nil {
+		return true
+	}
+	return t.After(*o)
+}
+
+// isOptDatetime validate that the field is either empty or a time in RFC3339
+// format.
+func isOptDatetime(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	return s == "" || parseTime(s) != nil
+}
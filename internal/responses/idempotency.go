@@ -0,0 +1,11 @@
+package responses
+
+// IdempotentResponse the response of a finished request that is kept to be
+// replayed to its retries that use the same idempotency key.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Location    string
+	Body        []byte
+}
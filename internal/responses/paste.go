@@ -0,0 +1,59 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
+)
+
+// PasteResponse adapted response for Paste from domain.SNS.
+type PasteResponse struct {
+	ID          uint    `json:"id,omitempty"`
+	Url         string  `json:"url,omitempty"`
+	Domain      string  `json:"domain,omitempty"`
+	PublicUrl   string  `json:"public_url,omitempty"`
+	Description string  `json:"description"`
+	Paste       *string `json:"paste,omitempty"`
+	Language    *string `json:"language,omitempty"`
+	// RawUrl the url of the public endpoint that serve the plain text.
+	RawUrl      string     `json:"raw_url,omitempty"`
+	IsPermanent *bool      `json:"permanent,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// FromDomain adapt given domain.SNS to PasteResponse. The public urls are
+// resolved using given public domains.
+func (p *PasteResponse) FromDomain(sns *domain.SNS, d *domains.Domains) {
+	if sns != nil {
+		p.ID = sns.ID
+		p.Url = sns.Url
+		p.Domain = sns.Domain
+		p.PublicUrl = d.Url(sns.Domain, sns.Url)
+		p.Description = sns.Description
+		p.Paste = sns.Paste
+		p.Language = sns.Language
+		p.RawUrl = d.Url(sns.Domain, sns.Url+"/raw")
+		p.IsPermanent = sns.IsPermanent
+		p.CreatedAt = sns.CreatedAt
+		p.UpdatedAt = sns.UpdatedAt
+	}
+}
+
+// PasteIndexResponse holds necessary data that should be used by handler to
+// give response.
+type PasteIndexResponse struct {
+	Data       []*PasteResponse
+	Pagination *paginate.M
+}
+
+// FromDomain setup Data from given sns data from domain/DB.
+func (p *PasteIndexResponse) FromDomain(sns []*domain.SNS, d *domains.Domains) {
+	for _, sn := range sns {
+		var r PasteResponse
+		r.FromDomain(sn, d)
+		p.Data = append(p.Data, &r)
+	}
+}
@@ -0,0 +1,35 @@
+package responses
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/mdanialr/sns_backend/pkg/qrcode"
+)
+
+// QRResponse the rendered QR code of a record.
+type QRResponse struct {
+	MimeType string
+	Content  []byte
+	// ETag identify the content using the url of the record, the hash of the
+	// encoded public url and the options that are used to render it.
+	ETag string
+}
+
+// NewQRResponse render the QR code of given public url that belong to the
+// record with given url (slug) using given options.
+func NewQRResponse(url, publicUrl string, opt qrcode.Options) (*QRResponse, error) {
+	content, err := qrcode.Render(publicUrl, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// the public url may change without the url, e.g. when the domain is moved
+	sum := sha256.Sum256([]byte(publicUrl))
+
+	return &QRResponse{
+		MimeType: qrcode.MimeType(opt.Format),
+		Content:  content,
+		ETag:     fmt.Sprintf("%s-%x-%s-%d-%s-%d", url, sum[:8], opt.Format, opt.Size, opt.Level, opt.Quiet),
+	}, nil
+}
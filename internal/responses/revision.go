@@ -0,0 +1,42 @@
+package responses
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// RevisionResponse adapted response for a revision of a link from
+// domain.SNSRevision.
+type RevisionResponse struct {
+	ID        uint            `json:"id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Reason    string          `json:"reason,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+}
+
+// NewRevisionResponses adapt given revisions to RevisionResponse.
+func NewRevisionResponses(rvs []*domain.SNSRevision) []*RevisionResponse {
+	items := make([]*RevisionResponse, 0, len(rvs))
+	for _, rv := range rvs {
+		r := &RevisionResponse{
+			ID:        rv.ID,
+			Action:    rv.Action,
+			Actor:     rv.Actor,
+			Reason:    rv.Reason,
+			CreatedAt: rv.CreatedAt,
+		}
+		if rv.Before != nil {
+			r.Before = json.RawMessage(*rv.Before)
+		}
+		if rv.After != nil {
+			r.After = json.RawMessage(*rv.After)
+		}
+		items = append(items, r)
+	}
+	return items
+}
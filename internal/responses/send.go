@@ -1,33 +1,96 @@
 package responses
 
 import (
+	"io"
+	"net/url"
 	"time"
 
 	"github.com/mdanialr/sns_backend/internal/domain"
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	h "github.com/mdanialr/sns_backend/pkg/helper"
 	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
 )
 
-// SendResponse adapted response for Send from domain.SNS.
+// SendResponse adapted response for Send from domain.SNS. Status is either
+// domain.StatusScheduled, domain.StatusActive or domain.StatusEnded according
+// to the activation window. Version is the one that should be given in
+// If-Match header to change it.
 type SendResponse struct {
-	ID          uint       `json:"id,omitempty"`
-	Url         string     `json:"url,omitempty"`
-	Description string     `json:"description"`
-	Send        *string    `json:"send,omitempty"`
-	FileSize    *string    `json:"file_size,omitempty"`
-	IsPermanent *bool      `json:"permanent,omitempty"`
-	CreatedAt   *time.Time `json:"created_at,omitempty"`
-	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
-}
-
-// FromDomain adapt given domain.SNS to SendResponse.
-func (s *SendResponse) FromDomain(sns *domain.SNS) {
+	ID          uint            `json:"id,omitempty"`
+	Url         string          `json:"url,omitempty"`
+	Domain      string          `json:"domain,omitempty"`
+	PublicUrl   string          `json:"public_url,omitempty"`
+	Description string          `json:"description"`
+	Send        *string         `json:"send,omitempty"`
+	FileSize    *string         `json:"file_size,omitempty"`
+	Hash        *string         `json:"hash,omitempty"`
+	MimeType    *string         `json:"mime_type,omitempty"`
+	Thumbnail   *string         `json:"thumbnail_url,omitempty"`
+	ScanStatus  *string         `json:"scan_status,omitempty"`
+	ScanDetail  *string         `json:"scan_detail,omitempty"`
+	Quarantined *bool           `json:"quarantined,omitempty"`
+	IsPermanent *bool           `json:"permanent,omitempty"`
+	ActiveFrom  *time.Time      `json:"active_from,omitempty"`
+	ActiveUntil *time.Time      `json:"active_until,omitempty"`
+	Status      string          `json:"status,omitempty"`
+	Files       []*SendFileItem `json:"files,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Version     int             `json:"version,omitempty"`
+	CreatedAt   *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
+}
+
+// SendFileItem adapted response for each file in a Send from domain.SendFile.
+type SendFileItem struct {
+	Name       string  `json:"name"`
+	FileSize   string  `json:"file_size"`
+	Hash       string  `json:"hash,omitempty"`
+	MimeType   string  `json:"mime_type,omitempty"`
+	ScanStatus *string `json:"scan_status,omitempty"`
+	// Url the url of the public endpoint to download this file.
+	Url string `json:"url"`
+}
+
+// sendFileItems adapt the files of given sns to SendFileItem.
+func sendFileItems(sns *domain.SNS, d *domains.Domains) []*SendFileItem {
+	var items []*SendFileItem
+	for _, f := range sns.Files {
+		items = append(items, &SendFileItem{
+			Name:       f.Name,
+			FileSize:   h.BytesToHumanize(f.Size),
+			Hash:       f.Hash,
+			MimeType:   f.MimeType,
+			ScanStatus: f.ScanStatus,
+			Url:        d.Url(sns.Domain, sns.Url+"/files/"+url.PathEscape(f.Name)),
+		})
+	}
+	return items
+}
+
+// FromDomain adapt given domain.SNS to SendResponse. The public urls are
+// resolved using given public domains.
+func (s *SendResponse) FromDomain(sns *domain.SNS, d *domains.Domains) {
 	if sns != nil {
 		s.ID = sns.ID
 		s.Url = sns.Url
+		s.Domain = sns.Domain
+		s.PublicUrl = d.Url(sns.Domain, sns.Url)
 		s.Description = sns.Description
 		s.Send = sns.Send
 		s.FileSize = sns.FileSize
+		s.Hash = sns.Hash
+		s.MimeType = sns.MimeType
+		s.Thumbnail = thumbnailUrl(sns, d)
+		s.ScanStatus = sns.ScanStatus
+		s.ScanDetail = sns.ScanDetail
+		s.Quarantined = sns.IsQuarantined
 		s.IsPermanent = sns.IsPermanent
+		s.ActiveFrom = sns.ActiveFrom
+		s.ActiveUntil = sns.ActiveUntil
+		s.Status = sns.Status(time.Now())
+		s.Files = sendFileItems(sns, d)
+		s.Tags = tagNames(sns)
+		s.Version = sns.Version
 		s.CreatedAt = sns.CreatedAt
 		s.UpdatedAt = sns.UpdatedAt
 	}
@@ -41,18 +104,43 @@ type SendIndexResponse struct {
 }
 
 // FromDomain setup Data from given sns data from domain/DB.
-func (s *SendIndexResponse) FromDomain(sns []*domain.SNS) {
+func (s *SendIndexResponse) FromDomain(sns []*domain.SNS, d *domains.Domains) {
 	for _, sn := range sns {
-		d := &SendResponse{
-			ID:          sn.ID,
-			Url:         sn.Url,
-			Description: sn.Description,
-			Send:        sn.Send,
-			FileSize:    sn.FileSize,
-			IsPermanent: sn.IsPermanent,
-			CreatedAt:   sn.CreatedAt,
-			UpdatedAt:   sn.UpdatedAt,
-		}
-		s.Data = append(s.Data, d)
+		var r SendResponse
+		r.FromDomain(sn, d)
+		s.Data = append(s.Data, &r)
 	}
 }
+
+// thumbnailUrl return the url of the public thumbnail endpoint of given sns
+// if the thumbnail is already generated.
+func thumbnailUrl(sns *domain.SNS, d *domains.Domains) *string {
+	if sns.Thumbnail == nil {
+		return nil
+	}
+	u := d.Url(sns.Domain, sns.Url+"/thumbnail")
+	return &u
+}
+
+// SendFileResponse the file of a Send that is ready to be served.
+type SendFileResponse struct {
+	// Name the filename that should be presented to the client.
+	Name      string
+	Hash      *string
+	MimeType  *string
+	Size      int64
+	UpdatedAt *time.Time
+	// File the file content that should be closed after being served.
+	File io.ReadSeekCloser
+}
+
+// SendArchiveResponse every file of a Send that is ready to be served as a
+// single zip archive.
+type SendArchiveResponse struct {
+	// Name the filename of the archive that should be presented to the client.
+	Name      string
+	UpdatedAt *time.Time
+	// Write stream the archive to given writer. Each file is read only when
+	// it's its turn, so the whole archive is never held in memory.
+	Write func(io.Writer) error
+}
@@ -1,31 +1,131 @@
 package responses
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/mdanialr/sns_backend/internal/domain"
+	"github.com/mdanialr/sns_backend/pkg/domains"
 	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
 )
 
-// ShortenResponse adapted response for Shorten from domain.SNS.
+// ShortenResponse adapted response for Shorten from domain.SNS. HealthStatus
+// is the status code of the destination on the last check, while
+// HealthFailures is the number of consecutive checks that found it broken.
+// Status is either domain.StatusScheduled, domain.StatusActive or
+// domain.StatusEnded according to the activation window. Version is the one
+// that should be given in If-Match header to change it.
 type ShortenResponse struct {
-	ID          uint       `json:"id,omitempty"`
-	Url         string     `json:"url,omitempty"`
-	Description string     `json:"description"`
-	Shorten     *string    `json:"shorten,omitempty"`
-	IsPermanent *bool      `json:"permanent,omitempty"`
-	CreatedAt   *time.Time `json:"created_at,omitempty"`
-	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	ID              uint                  `json:"id,omitempty"`
+	Url             string                `json:"url,omitempty"`
+	Domain          string                `json:"domain,omitempty"`
+	PublicUrl       string                `json:"public_url,omitempty"`
+	Description     string                `json:"description"`
+	Shorten         *string               `json:"shorten,omitempty"`
+	IsPermanent     *bool                 `json:"permanent,omitempty"`
+	Rules           []*ShortenRuleItem    `json:"rules,omitempty"`
+	Variants        []*ShortenVariantItem `json:"variants,omitempty"`
+	Forward         string                `json:"forward,omitempty"`
+	Params          map[string]string     `json:"params,omitempty"`
+	Tags            []string              `json:"tags,omitempty"`
+	ActiveFrom      *time.Time            `json:"active_from,omitempty"`
+	ActiveUntil     *time.Time            `json:"active_until,omitempty"`
+	Status          string                `json:"status,omitempty"`
+	HealthStatus    *int                  `json:"health_status,omitempty"`
+	HealthCheckedAt *time.Time            `json:"health_checked_at,omitempty"`
+	HealthFailures  int                   `json:"health_failures"`
+	Version         int                   `json:"version,omitempty"`
+	CreatedAt       *time.Time            `json:"created_at,omitempty"`
+	UpdatedAt       *time.Time            `json:"updated_at,omitempty"`
 }
 
-// FromDomain adapt given domain.SNS to ShortenResponse.
-func (s *ShortenResponse) FromDomain(sns *domain.SNS) {
+// ShortenRuleItem adapted response for each targeting rule of a Shorten from
+// domain.ShortenRule.
+type ShortenRuleItem struct {
+	Platform    string            `json:"platform,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Query       map[string]string `json:"query,omitempty"`
+	Destination string            `json:"destination"`
+}
+
+// shortenRuleItems adapt the rules of given sns to ShortenRuleItem.
+func shortenRuleItems(sns *domain.SNS) []*ShortenRuleItem {
+	var items []*ShortenRuleItem
+	for _, rl := range sns.Rules {
+		items = append(items, &ShortenRuleItem{
+			Platform:    rl.Platform,
+			Language:    rl.Language,
+			Query:       queryMap(rl.Query),
+			Destination: rl.Destination,
+		})
+	}
+	return items
+}
+
+// queryMap convert given url-encoded parameters to map, nil if it's empty.
+func queryMap(raw string) map[string]string {
+	q, _ := url.ParseQuery(raw)
+	if len(q) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(q))
+	for k := range q {
+		m[k] = q.Get(k)
+	}
+	return m
+}
+
+// ShortenVariantItem adapted response for each weighted destination of a
+// Shorten from domain.ShortenVariant. Share is the ratio of its hits to the
+// hits of every variant of the Shorten.
+type ShortenVariantItem struct {
+	ID          uint    `json:"id"`
+	Destination string  `json:"destination"`
+	Weight      int     `json:"weight"`
+	Hits        int64   `json:"hits"`
+	Share       float64 `json:"share"`
+}
+
+// ShortenVariantItems adapt the variants of given sns to ShortenVariantItem.
+func ShortenVariantItems(sns *domain.SNS) []*ShortenVariantItem {
+	var total int64
+	for _, v := range sns.Variants {
+		total += v.Hits
+	}
+	var items []*ShortenVariantItem
+	for _, v := range sns.Variants {
+		it := &ShortenVariantItem{ID: v.ID, Destination: v.Destination, Weight: v.Weight, Hits: v.Hits}
+		if total > 0 {
+			it.Share = float64(v.Hits) / float64(total)
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// FromDomain adapt given domain.SNS to ShortenResponse. The public url is
+// resolved using given public domains.
+func (s *ShortenResponse) FromDomain(sns *domain.SNS, d *domains.Domains) {
 	if sns != nil {
 		s.ID = sns.ID
 		s.Url = sns.Url
+		s.Domain = sns.Domain
+		s.PublicUrl = d.Url(sns.Domain, sns.Url)
 		s.Description = sns.Description
 		s.Shorten = sns.Shorten
 		s.IsPermanent = sns.IsPermanent
+		s.Rules = shortenRuleItems(sns)
+		s.Variants = ShortenVariantItems(sns)
+		s.Forward = sns.ForwardQuery
+		s.Params = queryMap(sns.DefaultParams)
+		s.Tags = tagNames(sns)
+		s.ActiveFrom = sns.ActiveFrom
+		s.ActiveUntil = sns.ActiveUntil
+		s.Status = sns.Status(time.Now())
+		s.HealthStatus = sns.HealthStatus
+		s.HealthCheckedAt = sns.HealthCheckedAt
+		s.HealthFailures = sns.HealthFailures
+		s.Version = sns.Version
 		s.CreatedAt = sns.CreatedAt
 		s.UpdatedAt = sns.UpdatedAt
 	}
@@ -39,17 +139,10 @@ type ShortenIndexResponse struct {
 }
 
 // FromDomain setup Data from given sns data from domain/DB.
-func (s *ShortenIndexResponse) FromDomain(sns []*domain.SNS) {
+func (s *ShortenIndexResponse) FromDomain(sns []*domain.SNS, d *domains.Domains) {
 	for _, sn := range sns {
-		d := &ShortenResponse{
-			ID:          sn.ID,
-			Url:         sn.Url,
-			Description: sn.Description,
-			Shorten:     sn.Shorten,
-			IsPermanent: sn.IsPermanent,
-			CreatedAt:   sn.CreatedAt,
-			UpdatedAt:   sn.UpdatedAt,
-		}
-		s.Data = append(s.Data, d)
+		var r ShortenResponse
+		r.FromDomain(sn, d)
+		s.Data = append(s.Data, &r)
 	}
 }
@@ -0,0 +1,48 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// TagResponse adapted response for Tag from domain.Tag. Usage is the number
+// of links and files that use the Tag.
+type TagResponse struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Usage     int64      `json:"usage"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// FromDomain adapt given domain.Tag to TagResponse.
+func (t *TagResponse) FromDomain(tag *domain.Tag) {
+	if tag != nil {
+		t.ID = tag.ID
+		t.Name = tag.Name
+		t.Usage = tag.Usage
+		t.CreatedAt = tag.CreatedAt
+		t.UpdatedAt = tag.UpdatedAt
+	}
+}
+
+// NewTagResponses adapt given tags to TagResponse.
+func NewTagResponses(tags []*domain.Tag) []*TagResponse {
+	items := make([]*TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		var r TagResponse
+		r.FromDomain(tag)
+		items = append(items, &r)
+	}
+	return items
+}
+
+// tagNames return the name of each tag of given sns, nil if it has none.
+func tagNames(sns *domain.SNS) []string {
+	var names []string
+	for _, t := range sns.Tags {
+		names = append(names, t.Name)
+	}
+	return names
+}
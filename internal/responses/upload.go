@@ -0,0 +1,29 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/mdanialr/sns_backend/internal/domain"
+)
+
+// UploadResponse adapted response for resumable Upload from domain.Upload.
+type UploadResponse struct {
+	ID        string
+	Length    int64
+	Offset    int64
+	Metadata  string
+	ExpiresAt *time.Time
+	// Send the created Send once the upload is complete.
+	Send *SendResponse
+}
+
+// FromDomain adapt given domain.Upload to UploadResponse.
+func (u *UploadResponse) FromDomain(up *domain.Upload) {
+	if up != nil {
+		u.ID = up.ID
+		u.Length = up.Length
+		u.Offset = up.Offset
+		u.Metadata = up.Metadata
+		u.ExpiresAt = up.ExpiresAt
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/mdanialr/sns_backend/pkg/migration"
 	"github.com/mdanialr/sns_backend/pkg/otp"
+	"github.com/mdanialr/sns_backend/pkg/rewrap"
 	"github.com/mdanialr/sns_backend/pkg/twofa"
 	"github.com/mdanialr/sns_backend/server"
 )
@@ -16,6 +17,7 @@ import (
 var (
 	isGenerateSecret  bool
 	isMigrate, isSeed bool
+	isRewrap          bool
 	generateQR        string
 	verify            string
 )
@@ -24,6 +26,7 @@ func init() {
 	flag.BoolVar(&isGenerateSecret, "gen", false, "Generate secret that can be placed in app config")
 	flag.BoolVar(&isMigrate, "migrate", false, "Run migrations")
 	flag.BoolVar(&isSeed, "seed", false, "Run available seeders. This can only be used with -migrate")
+	flag.BoolVar(&isRewrap, "rewrap", false, "Rewrap data key of all encrypted files using the current master key")
 	flag.StringVar(&generateQR, "qr", "", "Generate QR code to given readable directory or full path")
 	flag.StringVar(&verify, "verify", "", "Verify the given code")
 	flag.Parse()
@@ -51,6 +54,10 @@ func main() {
 		os.WriteFile(strings.TrimSuffix(generateQR, "/")+"/qr.png", qr, 0660)
 		return
 	}
+	if isRewrap {
+		rewrap.Run()
+		return
+	}
 	if isMigrate {
 		migration.Run(isSeed)
 		return
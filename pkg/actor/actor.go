@@ -0,0 +1,27 @@
+package actor
+
+import "context"
+
+// Unknown the actor of a context that doesn't carry any.
+const Unknown = "unknown"
+
+type key struct{}
+
+// Key the key that the actor is stored with in a context. Fiber keeps its
+// Locals in the context of the request, so storing the actor in Locals using
+// this key make it available to anything that receive the context.
+var Key = key{}
+
+// With return a copy of given context that carry given actor.
+func With(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, Key, actor)
+}
+
+// From return the actor that is carried by given context, Unknown if there is
+// none.
+func From(ctx context.Context) string {
+	if a, ok := ctx.Value(Key).(string); ok && a != "" {
+		return a
+	}
+	return Unknown
+}
@@ -0,0 +1,14 @@
+package actor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	assert.Equal(t, "jwt:otp", From(With(context.Background(), "jwt:otp")))
+	assert.Equal(t, Unknown, From(With(context.Background(), "")))
+	assert.Equal(t, Unknown, From(context.Background()))
+}
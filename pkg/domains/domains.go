@@ -0,0 +1,170 @@
+package domains
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrUnknown the domain is not one of the public domains in config.
+var ErrUnknown = errors.New("domain is not one of the public domains")
+
+// Domains the public domains that serve the public endpoints. The primary
+// domain is referred to as an empty string, so the links that use it follow
+// the config whenever the primary domain is moved somewhere else. Every other
+// domain is a vanity domain that is referred to by its host name.
+type Domains struct {
+	// base the scheme, host and path of the primary domain.
+	base string
+	// path the path of the primary domain that every link is prefixed with.
+	path    string
+	scheme  string
+	primary string
+	vanity  map[string]struct{}
+	// loose whether any unknown host is treated as the primary domain, which
+	// is the case when the primary domain is not configured.
+	loose bool
+}
+
+// New return Domains that use given public url as the primary domain along
+// with the optional vanity domains. Vanity domains use the same scheme as the
+// primary domain.
+func New(publicUrl string, vanity ...string) (*Domains, error) {
+	u, err := url.Parse(strings.TrimSuffix(publicUrl, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("public url should be an absolute url such as https://sns.example.com")
+	}
+
+	d := &Domains{
+		base:    u.Scheme + "://" + u.Host + u.Path,
+		path:    u.Path,
+		scheme:  u.Scheme,
+		primary: hostname(u.Host),
+		vanity:  make(map[string]struct{}, len(vanity)),
+	}
+	for _, v := range vanity {
+		if v = hostname(v); v != "" && v != d.primary {
+			d.vanity[v] = struct{}{}
+		}
+	}
+
+	return d, nil
+}
+
+// NewWithConfig return Domains that use `server.public_url` as the primary
+// domain and `server.domains` as the vanity domains. Fallback to the address
+// that the server listen to if the public url is not set, in which case any
+// host that is not a vanity domain is treated as the primary domain.
+func NewWithConfig(v *viper.Viper) (*Domains, error) {
+	if pub := v.GetString("server.public_url"); pub != "" {
+		return New(pub, v.GetStringSlice("server.domains")...)
+	}
+
+	host := v.GetString("server.host")
+	if host == "" || host == "0.0.0.0" {
+		host = "localhost"
+	}
+	d, err := New("http://"+host+":"+v.GetString("server.port"), v.GetStringSlice("server.domains")...)
+	if err != nil {
+		return nil, err
+	}
+	d.loose = true
+
+	return d, nil
+}
+
+// Lookup return the domain that should be used to find a link that is
+// requested through given host, which may contain a port. Return false if the
+// host is not one of the public domains.
+func (d *Domains) Lookup(host string) (string, bool) {
+	dom, err := d.Normalize(host)
+	if err != nil {
+		return "", d.loose
+	}
+	return dom, true
+}
+
+// Normalize return the domain that should be stored for a link that use given
+// domain. Empty domain means the primary domain. Return ErrUnknown if given
+// domain is not one of the public domains.
+func (d *Domains) Normalize(dom string) (string, error) {
+	dom = hostname(dom)
+	if dom == "" || dom == d.primary {
+		return "", nil
+	}
+	if _, ok := d.vanity[dom]; ok {
+		return dom, nil
+	}
+	return "", ErrUnknown
+}
+
+// Base return the absolute base url of given domain without trailing slash.
+func (d *Domains) Base(dom string) string {
+	if dom == "" {
+		return d.base
+	}
+	return d.scheme + "://" + dom
+}
+
+// Url return the absolute url of given path in given domain.
+func (d *Domains) Url(dom, path string) string {
+	return d.Base(dom) + "/" + strings.TrimPrefix(path, "/")
+}
+
+// Parse return the domain and url of the link that given absolute url point
+// to. Return false if the url is not in one of the public domains. Unlike
+// Lookup, unknown host is never treated as the primary domain.
+func (d *Domains) Parse(rawUrl string) (string, string, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+	dom, err := d.Normalize(u.Host)
+	if err != nil {
+		return "", "", false
+	}
+
+	pt := u.Path
+	if dom == "" {
+		if pt, err = cutPrefix(pt, d.path); err != nil {
+			return "", "", false
+		}
+	}
+	// the link is the first segment, the rest are its sub resources
+	slug, _, _ := strings.Cut(strings.TrimPrefix(pt, "/"), "/")
+	if slug == "" {
+		return "", "", false
+	}
+	slug, err = url.PathUnescape(slug)
+	if err != nil {
+		return "", "", false
+	}
+
+	return dom, slug, true
+}
+
+// cutPrefix return given path without given prefix which is a whole segment.
+func cutPrefix(pt, prefix string) (string, error) {
+	if prefix == "" {
+		return pt, nil
+	}
+	rest, ok := strings.CutPrefix(pt, prefix)
+	if !ok || rest != "" && rest[0] != '/' {
+		return "", errors.New("path is not under " + prefix)
+	}
+	return rest, nil
+}
+
+// hostname return the lower-cased host name of given host without the port.
+func hostname(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
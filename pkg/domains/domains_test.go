@@ -0,0 +1,177 @@
+package domains
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomains_Lookup(t *testing.T) {
+	d, err := New("https://sns.example.com/", "go.example.com", "Links.Example.com")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		host     string
+		expect   string
+		expectOk bool
+	}{
+		{
+			name:     "Given the primary host should return empty domain",
+			host:     "sns.example.com",
+			expectOk: true,
+		},
+		{
+			name:     "Given the primary host with port should return empty domain",
+			host:     "sns.example.com:443",
+			expectOk: true,
+		},
+		{
+			name:     "Given vanity host should return the host",
+			host:     "go.example.com",
+			expect:   "go.example.com",
+			expectOk: true,
+		},
+		{
+			name:     "Given upper case vanity host should return the lower case host",
+			host:     "LINKS.example.com",
+			expect:   "links.example.com",
+			expectOk: true,
+		},
+		{
+			name: "Given unknown host should not be found",
+			host: "evil.example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dom, ok := d.Lookup(tc.host)
+			assert.Equal(t, tc.expectOk, ok)
+			assert.Equal(t, tc.expect, dom)
+		})
+	}
+}
+
+func TestDomains_Normalize(t *testing.T) {
+	d, err := New("https://sns.example.com", "go.example.com")
+	require.NoError(t, err)
+
+	dom, err := d.Normalize("")
+	assert.NoError(t, err)
+	assert.Empty(t, dom)
+
+	dom, err = d.Normalize("SNS.example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, dom)
+
+	dom, err = d.Normalize("go.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "go.example.com", dom)
+
+	_, err = d.Normalize("evil.example.com")
+	assert.ErrorIs(t, err, ErrUnknown)
+}
+
+func TestDomains_Url(t *testing.T) {
+	d, err := New("https://sns.example.com:8443/s/", "go.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://sns.example.com:8443/s/yt", d.Url("", "yt"))
+	assert.Equal(t, "https://sns.example.com:8443/s/yt/raw", d.Url("", "/yt/raw"))
+	assert.Equal(t, "https://go.example.com/yt", d.Url("go.example.com", "yt"))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("sns.example.com")
+	assert.Error(t, err)
+}
+
+func TestNewWithConfig(t *testing.T) {
+	t.Run("Given public url should only accept the configured hosts", func(t *testing.T) {
+		v := viper.New()
+		v.Set("server.public_url", "https://sns.example.com")
+		v.Set("server.domains", []string{"go.example.com"})
+		d, err := NewWithConfig(v)
+		require.NoError(t, err)
+
+		_, ok := d.Lookup("localhost:6868")
+		assert.False(t, ok)
+		dom, ok := d.Lookup("go.example.com")
+		assert.True(t, ok)
+		assert.Equal(t, "go.example.com", dom)
+	})
+
+	t.Run("Given no public url should fallback to the server address and treat unknown host as the primary domain", func(t *testing.T) {
+		v := viper.New()
+		v.Set("server.host", "0.0.0.0")
+		v.Set("server.port", 6868)
+		v.Set("server.domains", []string{"go.example.com"})
+		d, err := NewWithConfig(v)
+		require.NoError(t, err)
+
+		assert.Equal(t, "http://localhost:6868/yt", d.Url("", "yt"))
+		dom, ok := d.Lookup("127.0.0.1:6868")
+		assert.True(t, ok)
+		assert.Empty(t, dom)
+		dom, ok = d.Lookup("go.example.com")
+		assert.True(t, ok)
+		assert.Equal(t, "go.example.com", dom)
+	})
+}
+
+func TestDomains_Parse(t *testing.T) {
+	d, err := New("https://sns.example.com/s", "go.example.com")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		url          string
+		expectDomain string
+		expectUrl    string
+		expectOk     bool
+	}{
+		{
+			name:      "Given link in the primary domain should return empty domain and the url",
+			url:       "https://sns.example.com/s/yt",
+			expectUrl: "yt",
+			expectOk:  true,
+		},
+		{
+			name:      "Given sub resource of a link should return the url of the link",
+			url:       "https://SNS.example.com/s/my%20notes/raw?x=1",
+			expectUrl: "my notes",
+			expectOk:  true,
+		},
+		{
+			name:         "Given link in a vanity domain should return the domain and the url",
+			url:          "http://go.example.com/yt",
+			expectDomain: "go.example.com",
+			expectUrl:    "yt",
+			expectOk:     true,
+		},
+		{
+			name: "Given path outside the base path of the primary domain should not be a link",
+			url:  "https://sns.example.com/sx/yt",
+		},
+		{
+			name: "Given the root of the primary domain should not be a link",
+			url:  "https://sns.example.com/s/",
+		},
+		{
+			name: "Given url in other domain should not be a link",
+			url:  "https://www.youtube.com/yt",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dom, url, ok := d.Parse(tc.url)
+			assert.Equal(t, tc.expectOk, ok)
+			assert.Equal(t, tc.expectDomain, dom)
+			assert.Equal(t, tc.expectUrl, url)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package etag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Any the If-Match value that match whatever the current version is.
+const Any = "*"
+
+// Format return the strong ETag of given version of a record.
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Parse return the version in given If-Match value that is made by Format.
+// Zero means there is no precondition, that is the value is either empty or
+// Any. Return false if the value is not made by Format, including a weak ETag
+// or a list of them since only a single version is compared.
+func Parse(ifMatch string) (int, bool) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == Any {
+		return 0, true
+	}
+	if len(ifMatch) < 3 || ifMatch[0] != '"' || ifMatch[len(ifMatch)-1] != '"' {
+		return 0, false
+	}
+	v, err := strconv.Atoi(ifMatch[1 : len(ifMatch)-1])
+	if err != nil || v < 1 {
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,36 @@
+package etag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, `"3"`, Format(3))
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ifMatch string
+		expect  int
+		ok      bool
+	}{
+		{name: "Given empty value should return zero", ok: true},
+		{name: "Given wildcard should return zero", ifMatch: "*", ok: true},
+		{name: "Given formatted version should return the version", ifMatch: ` "12" `, expect: 12, ok: true},
+		{name: "Given weak etag should return false", ifMatch: `W/"12"`},
+		{name: "Given a list of etags should return false", ifMatch: `"1", "2"`},
+		{name: "Given unquoted version should return false", ifMatch: "12"},
+		{name: "Given zero version should return false", ifMatch: `"0"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, ok := Parse(tc.ifMatch)
+			assert.Equal(t, tc.expect, v)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
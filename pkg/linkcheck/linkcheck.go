@@ -0,0 +1,262 @@
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdanialr/sns_backend/pkg/urlpolicy"
+	"github.com/spf13/viper"
+)
+
+const (
+	// userAgent identify the requests that are sent by the Checker.
+	userAgent = "sns_backend-linkcheck/1.0"
+	// maxRedirects the maximum number of redirects that are followed.
+	maxRedirects = 10
+)
+
+// Target a destination that should be checked.
+type Target struct {
+	ID  uint
+	Url string
+}
+
+// Result the outcome of checking a Target. Status is zero if no response is
+// received at all, in which case Err tells why.
+type Result struct {
+	Status int
+	Err    error
+}
+
+// Broken whether the destination is considered dead.
+func (r Result) Broken() bool {
+	return r.Err != nil || r.Status >= http.StatusBadRequest
+}
+
+// Checker check whether destinations are still alive by sending HEAD request,
+// then fallback to GET request for servers that don't handle HEAD properly.
+// Requests are sent concurrently by a bounded number of workers, and requests
+// to the same host are spaced out.
+type Checker struct {
+	client  *http.Client
+	workers int
+	perHost time.Duration
+	// Interval how often the checks should be run.
+	Interval time.Duration
+	// Batch the maximum number of targets that should be checked in a run.
+	Batch int
+	// BrokenAfter the number of consecutive failures before a destination is
+	// considered broken.
+	BrokenAfter int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New return Checker that use given client to send the requests using given
+// number of workers, and wait at least given gap between requests to the
+// same host.
+func New(client *http.Client, workers int, perHost time.Duration) *Checker {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Checker{client: client, workers: workers, perHost: perHost, BrokenAfter: 1}
+}
+
+// NewWithConfig return Checker using the config in `linkcheck`. Return nil if
+// `linkcheck.interval` is not set which means the checks are disabled. Given
+// policy guard every connection and redirect, so a destination can't make the
+// server send requests into the private network.
+func NewWithConfig(v *viper.Viper, p *urlpolicy.Policy) *Checker {
+	if v.GetInt("linkcheck.interval") <= 0 {
+		return nil
+	}
+	v.SetDefault("linkcheck.workers", 4)
+	v.SetDefault("linkcheck.per_host", 1000)
+	v.SetDefault("linkcheck.timeout", 10)
+	v.SetDefault("linkcheck.batch", 500)
+	v.SetDefault("linkcheck.broken_after", 2)
+
+	timeout := time.Duration(v.GetInt("linkcheck.timeout")) * time.Second
+	c := New(NewClient(p, timeout), v.GetInt("linkcheck.workers"), time.Duration(v.GetInt("linkcheck.per_host"))*time.Millisecond)
+	c.Interval = time.Duration(v.GetInt("linkcheck.interval")) * time.Minute
+	c.Batch = v.GetInt("linkcheck.batch")
+	c.BrokenAfter = v.GetInt("linkcheck.broken_after")
+
+	return c
+}
+
+// NewClient return http client that only connect to the addresses and follow
+// the redirects that are accepted by given policy, giving up after given
+// timeout. Proxy from the environment is not used because the policy would
+// only see the address of the proxy.
+func NewClient(p *urlpolicy.Policy, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: p.Control}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: tr,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return p.CheckHop(req.Context(), req.URL.String())
+		},
+	}
+}
+
+// Check send request to given url and return the status code of the response.
+func (c *Checker) Check(ctx context.Context, u string) Result {
+	st, err := c.send(ctx, http.MethodHead, u)
+	// some servers reject or mishandle HEAD, so ask them again with GET
+	if err != nil || st >= http.StatusBadRequest {
+		if ctx.Err() != nil {
+			return Result{Status: st, Err: err}
+		}
+		st, err = c.send(ctx, http.MethodGet, u)
+	}
+	return Result{Status: st, Err: err}
+}
+
+// CheckAll check every given target then call given fn with the result of
+// each of them. fn may be called concurrently. Return once every target is
+// checked or given context is done.
+func (c *Checker) CheckAll(ctx context.Context, targets []Target, fn func(Target, Result)) {
+	lim := &hostLimiter{gap: c.perHost, next: make(map[string]time.Time)}
+	jobs := make(chan Target)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				if err := lim.wait(ctx, host(t.Url)); err != nil {
+					continue
+				}
+				fn(t, c.Check(ctx, t.Url))
+			}
+		}()
+	}
+
+	for _, t := range targets {
+		select {
+		case jobs <- t:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Start call given fn right away, then every Interval until Close is called.
+// Do nothing if the Checker is nil.
+func (c *Checker) Start(fn func(context.Context)) {
+	if c == nil {
+		return
+	}
+	c.stop, c.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		defer close(c.done)
+		defer cancel()
+		tick := time.NewTicker(c.Interval)
+		defer tick.Stop()
+
+		for {
+			fn(ctx)
+			select {
+			case <-tick.C:
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		// abort the running checks as soon as it's closed
+		<-c.stop
+		cancel()
+	}()
+}
+
+// Close stop the periodic checks and wait for the running one to abort. Do
+// nothing if the Checker is nil or not started.
+func (c *Checker) Close() {
+	if c == nil || c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+}
+
+// send given method request to given url without reading the response body.
+func (c *Checker) send(ctx context.Context, method, u string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	return res.StatusCode, nil
+}
+
+// hostLimiter space out requests to the same host by reserving the next time
+// that a request to the host is allowed.
+type hostLimiter struct {
+	gap  time.Duration
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait block until a request to given host is allowed or given context is
+// done.
+func (l *hostLimiter) wait(ctx context.Context, h string) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[h]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[h] = at.Add(l.gap)
+	l.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// host return the lower-cased host of given url along with the port if any.
+func host(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return strings.ToLower(pu.Host)
+}
@@ -0,0 +1,217 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mdanialr/sns_backend/pkg/urlpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Check(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head":
+			// server that doesn't support HEAD
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		url          string
+		expectStatus int
+		expectBroken bool
+	}{
+		{
+			name:         "Given alive url should return status code OK",
+			url:          srv.URL + "/ok",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Given url that reject HEAD should fallback to GET and return status code OK",
+			url:          srv.URL + "/no-head",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Given url that redirect should follow it and return the final status code",
+			url:          srv.URL + "/moved",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Given dead url should return status code Not Found and be broken",
+			url:          srv.URL + "/gone",
+			expectStatus: http.StatusNotFound,
+			expectBroken: true,
+		},
+		{
+			name:         "Given url that can't be reached should be broken",
+			url:          "http://127.0.0.1:1/",
+			expectBroken: true,
+		},
+	}
+
+	c := New(srv.Client(), 1, 0)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := c.Check(context.Background(), tc.url)
+			assert.Equal(t, tc.expectStatus, res.Status)
+			assert.Equal(t, tc.expectBroken, res.Broken())
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/leave":
+			http.Redirect(w, r, "https://127.0.0.1/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		policy       *urlpolicy.Policy
+		url          string
+		expectStatus int
+		expectErr    bool
+	}{
+		{
+			name:      "Given url in private network should be refused before connecting",
+			policy:    &urlpolicy.Policy{Schemes: []string{"http"}},
+			url:       srv.URL + "/ok",
+			expectErr: true,
+		},
+		{
+			name:         "Given url in private network that is allowed should return status code OK",
+			policy:       &urlpolicy.Policy{Schemes: []string{"http"}, AllowPrivate: true},
+			url:          srv.URL + "/moved",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:      "Given redirect that is not accepted by the policy should not be followed",
+			policy:    &urlpolicy.Policy{Schemes: []string{"http"}, AllowPrivate: true},
+			url:       srv.URL + "/leave",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := New(NewClient(tc.policy, time.Second), 1, 0).Check(context.Background(), tc.url)
+			if tc.expectErr {
+				assert.ErrorIs(t, res.Err, urlpolicy.ErrRejected)
+				return
+			}
+			require.NoError(t, res.Err)
+			assert.Equal(t, tc.expectStatus, res.Status)
+		})
+	}
+}
+
+func TestChecker_CheckAll(t *testing.T) {
+	var running, peak int32
+	var mu sync.Mutex
+	var hits []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		hits = append(hits, time.Now())
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	t.Run("Given several targets should check all of them without exceeding the workers", func(t *testing.T) {
+		var targets []Target
+		for i := 1; i <= 12; i++ {
+			targets = append(targets, Target{ID: uint(i), Url: srv.URL})
+		}
+		var checked sync.Map
+		New(srv.Client(), 3, 0).CheckAll(context.Background(), targets, func(tg Target, res Result) {
+			checked.Store(tg.ID, res.Status)
+		})
+
+		for _, tg := range targets {
+			st, ok := checked.Load(tg.ID)
+			require.True(t, ok)
+			assert.Equal(t, http.StatusOK, st)
+		}
+		assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(3))
+	})
+
+	t.Run("Given gap per host should space out requests to the same host", func(t *testing.T) {
+		mu.Lock()
+		hits = nil
+		mu.Unlock()
+		targets := []Target{{ID: 1, Url: srv.URL}, {ID: 2, Url: srv.URL}, {ID: 3, Url: srv.URL}}
+		New(srv.Client(), 3, 50*time.Millisecond).CheckAll(context.Background(), targets, func(Target, Result) {})
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, hits, 3)
+		for i := 1; i < len(hits); i++ {
+			// allow a bit of timer jitter
+			assert.GreaterOrEqual(t, hits[i].Sub(hits[i-1]), 40*time.Millisecond)
+		}
+	})
+
+	t.Run("Given cancelled context should stop early", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var n int32
+		New(srv.Client(), 2, time.Second).CheckAll(ctx, []Target{{ID: 1, Url: srv.URL}, {ID: 2, Url: srv.URL}}, func(Target, Result) {
+			atomic.AddInt32(&n, 1)
+		})
+		assert.Zero(t, atomic.LoadInt32(&n))
+	})
+}
+
+func TestChecker_Start(t *testing.T) {
+	c := New(http.DefaultClient, 1, 0)
+	c.Interval = 10 * time.Millisecond
+
+	var n int32
+	c.Start(func(context.Context) { atomic.AddInt32(&n, 1) })
+	time.Sleep(35 * time.Millisecond)
+	c.Close()
+
+	runs := atomic.LoadInt32(&n)
+	assert.GreaterOrEqual(t, runs, int32(2))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, runs, atomic.LoadInt32(&n), "should not run anymore once it's closed")
+
+	// nil Checker means the checks are disabled
+	var disabled *Checker
+	disabled.Start(func(context.Context) { t.Fatal("should never run") })
+	disabled.Close()
+}
@@ -25,15 +25,92 @@ func Run(isSeeder bool) {
 	}
 	defer sqlDB.Close()
 
-	db.AutoMigrate(
+	// kind should be ready before its constraints are enforced by AutoMigrate
+	migrateKind(db)
+	// url should be unique before the unique index is created by AutoMigrate
+	checkUniqueUrl(db)
+	err = db.AutoMigrate(
 		&domain.RegisteredOTP{},
 		&domain.SNS{},
+		&domain.Blob{},
+		&domain.Upload{},
+		&domain.SendFile{},
+		&domain.ShortenRule{},
+		&domain.ShortenVariant{},
+		&domain.SNSRevision{},
+		&domain.Tag{},
+		&domain.IdempotencyKey{},
 	)
+	if err != nil {
+		log.Fatalln("failed to migrate tables:", err)
+	}
 	if isSeeder {
 		seeder.Run(db)
 	}
 }
 
+// migrateKind create the enum type of column kind in table sns, then
+// backfill the kind of SNS that is created before the column is introduced
+// using whichever of its payload column is filled. SNS that has no payload at
+// all can't be backfilled, so the migration is stopped to let them be fixed
+// manually.
+func migrateKind(db *gorm.DB) {
+	// DO block can't have bind parameters, so write the values as they are
+	err := db.Exec("DO $$ BEGIN CREATE TYPE sns_kind AS ENUM ('" +
+		domain.KindShorten + "', '" + domain.KindSend + "', '" + domain.KindPaste +
+		"'); EXCEPTION WHEN duplicate_object THEN NULL; END $$").Error
+	if err != nil {
+		log.Fatalln("failed to create enum type sns_kind:", err)
+	}
+	// a new table is going to be created by AutoMigrate with the constraints
+	if !db.Migrator().HasTable(&domain.SNS{}) {
+		return
+	}
+	if !db.Migrator().HasColumn(&domain.SNS{}, "Kind") {
+		if err = db.Exec("ALTER TABLE sns ADD COLUMN kind VARCHAR(16)").Error; err != nil {
+			log.Fatalln("failed to add column kind:", err)
+		}
+	}
+
+	// the payload column has the same name as its kind. include the soft
+	// deleted ones since the constraints apply to them too
+	for _, kind := range []string{domain.KindShorten, domain.KindSend, domain.KindPaste} {
+		err = db.Unscoped().Model(&domain.SNS{}).
+			Where("kind IS NULL").
+			Where(kind+" IS NOT NULL").
+			Update("kind", kind).Error
+		if err != nil {
+			log.Fatalln("failed to backfill kind "+kind+":", err)
+		}
+	}
+
+	var ids []uint
+	db.Unscoped().Model(&domain.SNS{}).Where("kind IS NULL").Pluck("id", &ids)
+	if len(ids) > 0 {
+		log.Fatalln("sns with these ids has no shorten, send nor paste, so the kind can't be decided:", ids)
+	}
+}
+
+// checkUniqueUrl make sure there is no url that is used by more than one SNS
+// in the same domain which is not deleted yet. SNS that is created before the
+// domain is introduced belong to the primary domain, so its url alone should
+// be unique. The migration is stopped to let them be fixed manually otherwise.
+func checkUniqueUrl(db *gorm.DB) {
+	if !db.Migrator().HasTable(&domain.SNS{}) {
+		return
+	}
+	group := "url"
+	if db.Migrator().HasColumn(&domain.SNS{}, "Domain") {
+		group = "domain, url"
+	}
+
+	var urls []string
+	db.Model(&domain.SNS{}).Group(group).Having("COUNT(*) > 1").Pluck("url", &urls)
+	if len(urls) > 0 {
+		log.Fatalln("these urls are used by more than one sns, so they can't be unique:", urls)
+	}
+}
+
 func initGorm() *gorm.DB {
 	// init viper config
 	v, err := conf.InitConfigYml()
@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed redoc.html
+var redocPage string
+
+var redoc = template.Must(template.New("redoc").Parse(redocPage))
+
+// RedocSrc the pinned Redoc bundle that is used if Script has no Src, so the
+// page never changes by itself when a new version is published.
+const RedocSrc = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+
+// Script the Redoc bundle that render the document page. Src may point to a
+// self hosted copy of the bundle, while Integrity is its SRI hash such as
+// `sha384-...`, which makes the browser refuse a bundle that was tampered
+// with. Integrity is left out of the page if it's empty.
+type Script struct {
+	Src       string
+	Integrity string
+}
+
+// Handler serve the document of every route in the app. The document is only
+// built on the first successful request, after every route is already
+// registered, so a failed build is tried again on the next request.
+func (s *Spec) Handler() fiber.Handler {
+	var (
+		mu  sync.Mutex
+		doc []byte
+	)
+	return func(c *fiber.Ctx) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if doc == nil {
+			b, err := json.Marshal(s.Build(c.App().GetRoutes(true)))
+			if err != nil {
+				return err
+			}
+			doc = b
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(doc)
+	}
+}
+
+// UI serve the page that render the document in given url using given Redoc
+// script.
+func UI(title, url string, sc Script) fiber.Handler {
+	if sc.Src == "" {
+		sc.Src = RedocSrc
+	}
+	var page bytes.Buffer
+	err := redoc.Execute(&page, struct {
+		Title, Url string
+		Script     Script
+	}{title, url, sc})
+	return func(c *fiber.Ctx) error {
+		if err != nil {
+			return err
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(page.Bytes())
+	}
+}
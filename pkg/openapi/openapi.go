@@ -0,0 +1,351 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Version the version of OpenAPI Specification that the document follow.
+const Version = "3.0.3"
+
+// Operation describe an endpoint that is registered in the router. Each struct
+// is described using the same struct tags that are used to parse the request,
+// along with the rules in its `validate` tags.
+type Operation struct {
+	// Summary a short explanation of what the operation does.
+	Summary string
+	// Description a longer explanation of the operation, if any.
+	Description string
+	// Tag the group of the operation.
+	Tag string
+	// Secured whether the operation require the bearer token.
+	Secured bool
+	// Deprecated whether the operation shouldn't be used anymore.
+	Deprecated bool
+	// Params the struct whose `params` tags are the path parameters. Path
+	// parameters that aren't found in Params, Query nor Body are strings.
+	Params any
+	// Query the struct whose `query` tags are the query parameters.
+	Query any
+	// Header the struct whose `reqHeader` tags are the request headers.
+	Header any
+	// Body the struct whose `json` tags are the request body, or the `form`
+	// tags if BodyType is multipart form. Fields that are path parameters are
+	// left out. Other BodyType are described as binary.
+	Body any
+	// BodyType the media type of Body. Default to application/json.
+	BodyType string
+	// Status the status code of the successful response. Default to 200.
+	Status int
+	// Data the `data` of the standard success response, if any.
+	Data any
+	// Meta the `meta` of the standard success response, if any.
+	Meta any
+	// Content the media types of the successful response that is sent as it
+	// is instead of the standard success response.
+	Content []string
+	// Empty whether the successful response has no body.
+	Empty bool
+	// ResponseHeaders the headers that are set in the successful response.
+	ResponseHeaders []string
+	// Statuses other status codes without body that may be responded.
+	Statuses []int
+	// Errors the status codes of the standard error response that may be
+	// responded. Unauthorized is always included for Secured operation.
+	Errors []int
+}
+
+// Spec describe every Operation of the API, which are keyed by Key.
+type Spec struct {
+	Title       string
+	Description string
+	Version     string
+	// Success the standard success response that has `data` and `meta`
+	// which are replaced by the Data and Meta of each Operation.
+	Success any
+	// Error the standard error response.
+	Error any
+	// Operations every documented route, keyed by Key.
+	Operations map[string]Operation
+}
+
+// Key return the key of an Operation for given method and path of a route,
+// where the path use the syntax of fiber such as `/links/:id`.
+func Key(method, path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return method + " " + path
+}
+
+// Missing return the key of every given route that has no Operation. HEAD
+// routes that come along with GET routes are ignored.
+func (s *Spec) Missing(routes []fiber.Route) []string {
+	gets := make(map[string]bool)
+	for _, r := range routes {
+		if r.Method == fiber.MethodGet {
+			gets[Key(fiber.MethodHead, r.Path)] = true
+		}
+	}
+
+	var keys []string
+	for _, r := range routes {
+		k := Key(r.Method, r.Path)
+		if _, ok := s.Operations[k]; !ok && !gets[k] {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Unused return the key of every Operation that has no route in given routes,
+// which is usually left behind after the route is removed.
+func (s *Spec) Unused(routes []fiber.Route) []string {
+	found := make(map[string]bool)
+	for _, r := range routes {
+		found[Key(r.Method, r.Path)] = true
+	}
+
+	var keys []string
+	for k := range s.Operations {
+		if !found[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Build return the document that describe given routes. Routes without an
+// Operation are still listed, except the HEAD routes that come along with GET
+// routes.
+func (s *Spec) Build(routes []fiber.Route) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info{s.Title, s.Description, s.Version},
+		Paths:   make(map[string]map[string]*operation),
+		Components: components{
+			Schemas: map[string]*Schema{"Error": schemaOrAny(s.Error)},
+			SecuritySchemes: map[string]*securityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	for _, r := range routes {
+		op, ok := s.Operations[Key(r.Method, r.Path)]
+		if !ok && r.Method == fiber.MethodHead {
+			continue
+		}
+		p := pathOf(r.Path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(map[string]*operation)
+		}
+		doc.Paths[p][strings.ToLower(r.Method)] = s.operation(op, r.Params)
+	}
+
+	return doc
+}
+
+// operation return the Operation Object of given Operation of a route that
+// has given path parameters.
+func (s *Spec) operation(op Operation, params []string) *operation {
+	o := &operation{
+		Summary:     op.Summary,
+		Description: op.Description,
+		Deprecated:  op.Deprecated,
+		Responses:   make(map[string]*response),
+	}
+	if op.Tag != "" {
+		o.Tags = []string{op.Tag}
+	}
+	if op.Secured {
+		o.Security = []map[string][]string{{"bearer": {}}}
+	}
+
+	// parameters
+	inPath := make(map[string]bool)
+	for _, p := range params {
+		inPath[p] = true
+		o.Parameters = append(o.Parameters, &parameter{
+			Name:     p,
+			In:       "path",
+			Required: true,
+			Schema:   pathSchema(p, op.Params, op.Query, op.Body),
+		})
+	}
+	o.Parameters = append(o.Parameters, parameters("query", "query", op.Query)...)
+	o.Parameters = append(o.Parameters, parameters("header", "reqHeader", op.Header)...)
+
+	// request body
+	if op.Body != nil {
+		o.RequestBody = requestBodyOf(op, func(f reflect.StructField) bool {
+			return inPath[f.Tag.Get("params")]
+		})
+	}
+
+	// responses
+	status := op.Status
+	if status == 0 {
+		status = fiber.StatusOK
+	}
+	res := &response{Description: http.StatusText(status)}
+	switch {
+	case op.Empty:
+	case len(op.Content) > 0:
+		res.Content = make(map[string]*mediaType)
+		for _, m := range op.Content {
+			res.Content[m] = &mediaType{contentSchema(m)}
+		}
+	default:
+		res.Content = map[string]*mediaType{fiber.MIMEApplicationJSON: {s.success(op)}}
+	}
+	for _, h := range op.ResponseHeaders {
+		if res.Headers == nil {
+			res.Headers = make(map[string]*header)
+		}
+		res.Headers[h] = &header{&Schema{Type: "string"}}
+	}
+	o.Responses[strconv.Itoa(status)] = res
+
+	for _, code := range op.Statuses {
+		o.Responses[strconv.Itoa(code)] = &response{Description: http.StatusText(code)}
+	}
+	errs := op.Errors
+	if op.Secured {
+		errs = append(errs, fiber.StatusUnauthorized)
+	}
+	for _, code := range errs {
+		o.Responses[strconv.Itoa(code)] = &response{
+			Description: http.StatusText(code),
+			Content: map[string]*mediaType{
+				fiber.MIMEApplicationJSON: {&Schema{Ref: "#/components/schemas/Error"}},
+			},
+		}
+	}
+
+	return o
+}
+
+// success return the standard success response along with the Data and Meta
+// of given Operation.
+func (s *Spec) success(op Operation) *Schema {
+	sc := schemaOrAny(s.Success)
+	if sc.Properties == nil {
+		return sc
+	}
+	for name, v := range map[string]any{"data": op.Data, "meta": op.Meta} {
+		if v == nil {
+			delete(sc.Properties, name)
+			continue
+		}
+		sc.Properties[name] = schemaOf(reflect.TypeOf(v), "json")
+	}
+	return sc
+}
+
+// parameters return the parameters in given struct that is named by given
+// tag, which are located in given in.
+func parameters(in, tag string, v any) []*parameter {
+	if v == nil {
+		return nil
+	}
+
+	var ps []*parameter
+	for _, f := range fields(reflect.TypeOf(v), tag) {
+		sc := schemaOf(f.Type, tag)
+		ps = append(ps, &parameter{
+			Name:     f.name,
+			In:       in,
+			Required: sc.validate(f.Tag.Get("validate")),
+			Schema:   sc,
+		})
+	}
+	return ps
+}
+
+// pathSchema return the schema of given path parameter from the first given
+// struct that has it, otherwise a string.
+func pathSchema(name string, vs ...any) *Schema {
+	for _, v := range vs {
+		if v == nil {
+			continue
+		}
+		for _, f := range fields(reflect.TypeOf(v), "params") {
+			if f.name == name {
+				sc := schemaOf(f.Type, "params")
+				sc.validate(f.Tag.Get("validate"))
+				return sc
+			}
+		}
+	}
+	return &Schema{Type: "string"}
+}
+
+// requestBodyOf return the request body of given Operation, leaving out the
+// fields that given skip report.
+func requestBodyOf(op Operation, skip func(reflect.StructField) bool) *requestBody {
+	media := op.BodyType
+	if media == "" {
+		media = fiber.MIMEApplicationJSON
+	}
+
+	t := reflect.TypeOf(op.Body)
+	var sc *Schema
+	switch media {
+	case fiber.MIMEApplicationJSON, fiber.MIMEMultipartForm:
+		tag := "json"
+		if media == fiber.MIMEMultipartForm {
+			tag = "form"
+		}
+		sc = schemaOf(t, tag)
+		if deref(t).Kind() == reflect.Struct {
+			sc = objectOf(deref(t), tag, skip, map[reflect.Type]bool{deref(t): true})
+		}
+	default:
+		sc = &Schema{Type: "string", Format: "binary"}
+	}
+
+	return &requestBody{Required: true, Content: map[string]*mediaType{media: {sc}}}
+}
+
+// contentSchema return the schema of a response body that has given media
+// type.
+func contentSchema(media string) *Schema {
+	switch {
+	case media == fiber.MIMEApplicationJSON:
+		return &Schema{Type: "object"}
+	case strings.HasPrefix(media, "text/"):
+		return &Schema{Type: "string"}
+	}
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+// schemaOrAny return the schema of the type of given value, or any object if
+// it's nil.
+func schemaOrAny(v any) *Schema {
+	if v == nil {
+		return &Schema{Type: "object"}
+	}
+	return schemaOf(reflect.TypeOf(v), "json")
+}
+
+// pathOf convert given path that use the syntax of fiber to the one that is
+// used in the document, such as `/links/:id` to `/links/{id}`.
+func pathOf(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") {
+			segs[i] = "{" + strings.TrimSuffix(seg[1:], "?") + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	embedded struct {
+		Reason string `json:"reason" form:"reason" validate:"omitempty,max=255"`
+	}
+	sample struct {
+		ID       uint              `json:"id" params:"id" validate:"required,numeric"`
+		Url      string            `json:"url" form:"url" query:"url" validate:"required,url"`
+		Kind     string            `json:"kind" validate:"omitempty,oneof='' short long"`
+		Weight   int               `json:"weight" validate:"min=1,max=1000"`
+		Tags     []string          `json:"tags" validate:"omitempty,max=20,dive,max=64"`
+		Params   map[string]string `json:"params" validate:"omitempty,max=10"`
+		Internal string            `json:"-"`
+		Untagged string
+		embedded
+	}
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "GET /api/links", Key(fiber.MethodGet, "/api/links/"))
+	assert.Equal(t, "GET /", Key(fiber.MethodGet, "/"))
+}
+
+func TestPathOf(t *testing.T) {
+	assert.Equal(t, "/links/{id}/files/{name}", pathOf("/links/:id/files/:name"))
+	assert.Equal(t, "/links/{id}", pathOf("/links/:id?/"))
+	assert.Equal(t, "/", pathOf("/"))
+}
+
+func TestSchemaOf(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tag    string
+		expect *Schema
+	}{
+		{
+			name: "Given json tag should describe the json fields along with the validation",
+			tag:  "json",
+			expect: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"id":     {Type: "integer", Minimum: ptr(0.0)},
+					"url":    {Type: "string", Format: "uri"},
+					"kind":   {Type: "string", Enum: []any{"", "short", "long"}},
+					"weight": {Type: "integer", Minimum: ptr(1.0), Maximum: ptr(1000.0)},
+					"tags":   {Type: "array", MaxItems: ptr(20), Items: &Schema{Type: "string", MaxLength: ptr(64)}},
+					"params": {Type: "object", MaxProperties: ptr(10), AdditionalProperties: &Schema{Type: "string"}},
+					"reason": {Type: "string", MaxLength: ptr(255)},
+				},
+				Required: []string{"id", "url"},
+			},
+		},
+		{
+			name: "Given form tag should only describe the form fields",
+			tag:  "form",
+			expect: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"url":    {Type: "string", Format: "uri"},
+					"reason": {Type: "string", MaxLength: ptr(255)},
+				},
+				Required: []string{"url"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, schemaOf(reflect.TypeOf(sample{}), tc.tag))
+		})
+	}
+}
+
+func TestSpec_Build(t *testing.T) {
+	s := &Spec{
+		Title:   "test",
+		Version: "1.0.0",
+		Success: struct {
+			Status string `json:"status"`
+			Data   any    `json:"data"`
+			Meta   any    `json:"meta"`
+		}{},
+		Operations: map[string]Operation{
+			Key(fiber.MethodGet, "/links"): {
+				Summary: "List links",
+				Query:   sample{},
+				Data:    []string{},
+			},
+			Key(fiber.MethodPut, "/links/:id"): {
+				Summary: "Replace a link",
+				Secured: true,
+				Body:    sample{},
+				Errors:  []int{fiber.StatusNotFound},
+			},
+			Key(fiber.MethodDelete, "/links/:id"): {Summary: "Removed route"},
+		},
+	}
+	app := fiber.New()
+	app.Get("/links", func(c *fiber.Ctx) error { return nil })
+	app.Put("/links/:id", func(c *fiber.Ctx) error { return nil })
+	app.Post("/links", func(c *fiber.Ctx) error { return nil })
+	routes := app.GetRoutes(true)
+
+	t.Run("Given routes without operation should be missing", func(t *testing.T) {
+		assert.Equal(t, []string{"POST /links"}, s.Missing(routes))
+	})
+
+	t.Run("Given operations without route should be unused", func(t *testing.T) {
+		assert.Equal(t, []string{"DELETE /links/:id"}, s.Unused(routes))
+	})
+
+	doc := s.Build(routes)
+
+	t.Run("Given GET route should leave out the HEAD route that come along", func(t *testing.T) {
+		assert.Contains(t, doc.Paths["/links"], "get")
+		assert.NotContains(t, doc.Paths["/links"], "head")
+		assert.Contains(t, doc.Paths["/links"], "post")
+	})
+
+	t.Run("Given query should describe the query parameters", func(t *testing.T) {
+		get := doc.Paths["/links"]["get"]
+		if assert.Len(t, get.Parameters, 1) {
+			assert.Equal(t, &parameter{Name: "url", In: "query", Required: true, Schema: &Schema{Type: "string", Format: "uri"}}, get.Parameters[0])
+		}
+		data := get.Responses["200"].Content[fiber.MIMEApplicationJSON].Schema
+		assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, data.Properties["data"])
+		assert.NotContains(t, data.Properties, "meta")
+	})
+
+	t.Run("Given body should leave out the path parameters", func(t *testing.T) {
+		put := doc.Paths["/links/{id}"]["put"]
+		if assert.Len(t, put.Parameters, 1) {
+			assert.Equal(t, &parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Minimum: ptr(0.0)}}, put.Parameters[0])
+		}
+		body := put.RequestBody.Content[fiber.MIMEApplicationJSON].Schema
+		assert.NotContains(t, body.Properties, "id")
+		assert.Equal(t, []string{"url"}, body.Required)
+		assert.Equal(t, []map[string][]string{{"bearer": {}}}, put.Security)
+		assert.Contains(t, put.Responses, "401")
+		assert.Contains(t, put.Responses, "404")
+	})
+}
+
+func TestUI(t *testing.T) {
+	testCases := []struct {
+		name   string
+		sc     Script
+		expect string
+	}{
+		{
+			name:   "Given no script should load the pinned Redoc without integrity",
+			expect: `<script src="` + RedocSrc + `" crossorigin="anonymous">`,
+		},
+		{
+			name:   "Given script with integrity should load it along with the integrity",
+			sc:     Script{Src: "/static/redoc.js", Integrity: "sha384-abc"},
+			expect: `<script src="/static/redoc.js" integrity="sha384-abc" crossorigin="anonymous">`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/docs", UI("Docs", "/openapi.json", tc.sc))
+
+			res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/docs", nil))
+			require.NoError(t, err)
+			b, _ := io.ReadAll(res.Body)
+			assert.Contains(t, string(b), tc.expect)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package openapi
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	fileType = reflect.TypeOf(multipart.FileHeader{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// boolPattern every value that is accepted by the `boolean` validation.
+const boolPattern = "^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$"
+
+// Schema the Schema Object that describe the type of a value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// field a struct field that is named by a struct tag.
+type field struct {
+	name string
+	reflect.StructField
+}
+
+// fields return every field of given struct type that has given tag, where
+// the name is taken from the tag. Fields of embedded structs without the tag
+// are promoted just like encoding/json does.
+func fields(t reflect.Type, tag string) []field {
+	t = deref(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		switch {
+		case name == "-":
+		case name == "" && f.Anonymous:
+			fs = append(fs, fields(f.Type, tag)...)
+		case name != "" && f.IsExported():
+			fs = append(fs, field{name, f})
+		}
+	}
+	return fs
+}
+
+// schemaOf return the schema of given type, where the properties of structs
+// are named by given tag.
+func schemaOf(t reflect.Type, tag string) *Schema {
+	return schemaSeen(t, tag, map[reflect.Type]bool{})
+}
+
+func schemaSeen(t reflect.Type, tag string, seen map[reflect.Type]bool) *Schema {
+	t = deref(t)
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaSeen(t.Elem(), tag, seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaSeen(t.Elem(), tag, seen)}
+	case reflect.Struct:
+		// recursive types are left as any object
+		if seen[t] {
+			return &Schema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return objectOf(t, tag, nil, seen)
+	}
+	return &Schema{}
+}
+
+// objectOf return the schema of given struct type, leaving out the fields
+// that given skip report, if any.
+func objectOf(t reflect.Type, tag string, skip func(reflect.StructField) bool, seen map[reflect.Type]bool) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, f := range fields(t, tag) {
+		if skip != nil && skip(f.StructField) {
+			continue
+		}
+		p := schemaSeen(f.Type, tag, seen)
+		if p.validate(f.Tag.Get("validate")) {
+			s.Required = append(s.Required, f.name)
+		}
+		s.Properties[f.name] = p
+	}
+	return s
+}
+
+// validate apply given rules of go-playground/validator to the schema, then
+// return whether the value is required.
+func (s *Schema) validate(rules string) bool {
+	if rules == "" {
+		return false
+	}
+
+	var required bool
+	rs := strings.Split(rules, ",")
+	for i, r := range rs {
+		name, param, _ := strings.Cut(r, "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			// the rest of the rules are for each element
+			el := s.Items
+			if el == nil {
+				el = s.AdditionalProperties
+			}
+			if el != nil {
+				el.validate(strings.Join(rs[i+1:], ","))
+			}
+			return required
+		case "min", "max", "len":
+			s.limit(name, param)
+		case "oneof":
+			s.Enum = s.enum(param)
+		case "url":
+			s.Format = "uri"
+		case "numeric":
+			if s.Type == "string" {
+				s.Pattern = "^[0-9]+$"
+			}
+		case "boolean":
+			if s.Type == "string" {
+				s.Pattern = boolPattern
+			}
+		case "datetime", "opt_datetime":
+			s.Format = "date-time"
+		}
+	}
+	return required
+}
+
+// limit apply the min, max or len validation that means a different thing
+// depending on the type.
+func (s *Schema) limit(name, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+
+	var lo, hi **int
+	switch s.Type {
+	case "string":
+		lo, hi = &s.MinLength, &s.MaxLength
+	case "array":
+		lo, hi = &s.MinItems, &s.MaxItems
+	case "object":
+		lo, hi = &s.MinProperties, &s.MaxProperties
+	case "integer", "number":
+		if name != "max" {
+			s.Minimum = &n
+		}
+		if name != "min" {
+			s.Maximum = &n
+		}
+		return
+	default:
+		return
+	}
+	if name != "max" {
+		*lo = &i
+	}
+	if name != "min" {
+		*hi = &i
+	}
+}
+
+// enum return the values in given param of oneof validation, which are
+// separated by space and may be quoted by single quotes.
+func (s *Schema) enum(param string) []any {
+	var vals []any
+	for param = strings.TrimSpace(param); param != ""; param = strings.TrimSpace(param) {
+		var v string
+		if param[0] == '\'' {
+			v, param, _ = strings.Cut(param[1:], "'")
+		} else {
+			v, param, _ = strings.Cut(param, " ")
+		}
+		if s.Type == "integer" {
+			if n, err := strconv.Atoi(v); err == nil {
+				vals = append(vals, n)
+			}
+			continue
+		}
+		vals = append(vals, v)
+	}
+	return vals
+}
+
+// deref return the type that given pointer type point to.
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func ptr[T any](v T) *T { return &v }
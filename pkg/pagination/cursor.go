@@ -0,0 +1,93 @@
+package paginate
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidCursor the given cursor is not made by this app, or it's made for
+// other order of the data.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var (
+	keyMu sync.RWMutex
+	// key sign every cursor, which is random until SetKey is called so the
+	// cursors are still signed but only valid for this process.
+	key = randomKey()
+)
+
+// SetKey derive the key that is used to sign the cursors from given secret,
+// so they can't be forged by the clients and stay valid across restarts. The
+// secret may be shared with other purposes since the key is never the secret
+// itself.
+func SetKey(secret []byte) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if len(secret) > 0 {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte("cursor"))
+		key = mac.Sum(nil)
+	}
+}
+
+func randomKey() []byte {
+	k := make([]byte, 32)
+	rand.Read(k)
+	return k
+}
+
+// cursor the position in data that is sorted by Column, then by the id, where
+// ID and Value are the id and the value of Column of the row the page continue
+// from. Value is empty if Column is the id. Backward means the page is the one
+// before the row instead of after it.
+type cursor struct {
+	Column   string          `json:"c"`
+	Desc     bool            `json:"d,omitempty"`
+	ID       uint            `json:"i"`
+	Value    json.RawMessage `json:"v,omitempty"`
+	Backward bool            `json:"b,omitempty"`
+}
+
+// encode return the signed cursor that is safe to put in url.
+func (c *cursor) encode() string {
+	b, _ := json.Marshal(c)
+	p := base64.RawURLEncoding.EncodeToString(b)
+	return p + "." + base64.RawURLEncoding.EncodeToString(sign(p))
+}
+
+// decodeCursor return the cursor in given string that is made by encode.
+// Return ErrInvalidCursor if it's malformed or the signature doesn't match.
+func decodeCursor(s string) (*cursor, error) {
+	p, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, sign(p)) {
+		return nil, ErrInvalidCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err = json.Unmarshal(b, &c); err != nil || c.Column == "" || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// sign return the signature of given payload.
+func sign(payload string) []byte {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -1,9 +1,29 @@
 package paginate
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"reflect"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidOrder the data is ordered by something other than a column.
+var ErrInvalidOrder = errors.New("invalid order column")
+
+const (
+	// ModePage paginate using page numbers, which count every data to know
+	// the total pages. This is the default.
+	ModePage = "page"
+	// ModeCursor paginate using the cursors of the pages around the current
+	// one, which doesn't count the data and stay consistent while the data
+	// is inserted in between.
+	ModeCursor = "cursor"
 )
 
 // M standard pagination object that should be embedded to request object that
@@ -21,13 +41,45 @@ type M struct {
 	// TotalPage based on given Limit how many page that can be divided from
 	// total available data.
 	TotalPage int `json:"total_page,omitempty"`
+	// Mode how the data is paginated, either ModePage or ModeCursor. Default
+	// to ModePage.
+	Mode string `json:"-" query:"pagination"`
+	// Cursor which page to retrieve in ModeCursor, that is either NextCursor
+	// or PrevCursor of other page. Empty means the first page.
+	Cursor string `json:"-" query:"cursor"`
+	// NextCursor the cursor of the next page in ModeCursor, empty if this is
+	// the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// PrevCursor the cursor of the previous page in ModeCursor, empty if this
+	// is the first page.
+	PrevCursor string `json:"prev_cursor,omitempty"`
 	// count just a placeholder to count the total retrieved number of data.
 	count int64
+	// column and desc the order of the data that is set by OrderBy.
+	column string
+	desc   bool
+	// cur the decoded Cursor.
+	cur *cursor
+	// field the field of the model that order the data, which is found by
+	// Set.
+	field *schema.Field
+}
+
+// OrderBy order the data by given column using given sort, either asc or
+// desc, then by the id in ModeCursor, so each page knows where it ends. The
+// column should be a column of the model, otherwise the query fails.
+func (m *M) OrderBy(column, sort string) {
+	m.column = column
+	m.desc = strings.EqualFold(sort, "desc")
 }
 
 // Paginate setup current, previous and next page based on the count & Limit
 // fields. This should be called after retrieving the actual data from DB.
+// Does nothing in ModeCursor, use Cut instead.
 func (m *M) Paginate() {
+	if m.Mode == ModeCursor {
+		return
+	}
 	m.TotalPage = int(math.Ceil(float64(m.count) / float64(m.Limit)))
 	if m.TotalPage > 0 {
 		switch {
@@ -45,8 +97,19 @@ func (m *M) Paginate() {
 	}
 }
 
-// Set implementation of repository.IOptions.
+// Set implementation of repository.IOptions. The query fails with
+// ErrInvalidOrder if the data is ordered by something other than a column of
+// the model, or with ErrInvalidCursor if the Cursor in ModeCursor is not
+// valid.
 func (m *M) Set(db *gorm.DB) *gorm.DB {
+	col, err := m.lookup(db)
+	if err != nil {
+		db.AddError(err)
+		return db
+	}
+	if m.Mode == ModeCursor {
+		return m.setCursor(db, col)
+	}
 	db.Count(&m.count)
 
 	if m.Page == 0 { // set to first page if only has one page
@@ -56,6 +119,152 @@ func (m *M) Set(db *gorm.DB) *gorm.DB {
 		db = db.Limit(m.Limit)
 	}
 	db = db.Offset((m.Page - 1) * m.Limit)
+	if m.column != "" {
+		db = db.Order(col + " " + direction(m.desc))
+	}
+
+	return db
+}
+
+// lookup return the name of the column that order the data, which should be
+// a column of the model of given query.
+func (m *M) lookup(db *gorm.DB) (string, error) {
+	if db.Statement.Schema == nil {
+		if err := db.Statement.Parse(db.Statement.Model); err != nil {
+			return "", err
+		}
+	}
+	f := db.Statement.Schema.LookUpField(m.orderColumn())
+	if f == nil || f.DBName == "" {
+		return "", ErrInvalidOrder
+	}
+	m.field = f
+
+	return f.DBName, nil
+}
+
+// setCursor continue from the row in the Cursor if any, then retrieve one
+// more data than Limit to know whether there is another page.
+func (m *M) setCursor(db *gorm.DB, col string) *gorm.DB {
+	if m.Cursor != "" {
+		c, err := decodeCursor(m.Cursor)
+		if err == nil && (c.Column != col || c.Desc != m.desc) {
+			err = ErrInvalidCursor
+		}
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		m.cur = c
+		if db, err = m.after(db, col); err != nil {
+			db.AddError(err)
+			return db
+		}
+	}
+
+	// the previous page is retrieved backward, then reversed by Cut
+	dir := direction(m.desc != m.backward())
+	db = db.Order(col + " " + dir)
+	if col != "id" {
+		db = db.Order("id " + dir)
+	}
+	if m.Limit != 0 {
+		db = db.Limit(m.Limit + 1)
+	}
 
 	return db
 }
+
+// after add the condition that only keep the rows after the one in the
+// cursor, in the order that the rows are retrieved. The value of the row is
+// taken from the cursor, so the row doesn't need to exist anymore. NULL
+// comes last in ascending order and first in descending order, just like
+// PostgreSQL sort them by default.
+func (m *M) after(db *gorm.DB, col string) (*gorm.DB, error) {
+	desc := m.desc != m.cur.Backward
+	if col == "id" {
+		if desc {
+			return db.Where("id < ?", m.cur.ID), nil
+		}
+		return db.Where("id > ?", m.cur.ID), nil
+	}
+
+	v := reflect.New(m.field.FieldType)
+	if len(m.cur.Value) == 0 || json.Unmarshal(m.cur.Value, v.Interface()) != nil {
+		return db, ErrInvalidCursor
+	}
+	if v = reflect.Indirect(v); v.Kind() == reflect.Pointer && v.IsNil() {
+		if desc {
+			return db.Where("("+col+" IS NOT NULL OR id < ?)", m.cur.ID), nil
+		}
+		return db.Where("("+col+" IS NULL AND id > ?)", m.cur.ID), nil
+	}
+
+	if desc {
+		return db.Where(fmt.Sprintf("(%s, id) < (?, ?)", col), v.Interface(), m.cur.ID), nil
+	}
+	return db.Where(fmt.Sprintf("((%s, id) > (?, ?) OR %s IS NULL)", col, col), v.Interface(), m.cur.ID), nil
+}
+
+// orderColumn return the column that order the data, default to id.
+func (m *M) orderColumn() string {
+	if m.column == "" {
+		return "id"
+	}
+	return m.column
+}
+
+// backward return whether the page is the one before the Cursor.
+func (m *M) backward() bool {
+	return m.cur != nil && m.cur.Backward
+}
+
+// Cut trim given items that are retrieved in ModeCursor to the page, then
+// set NextCursor and PrevCursor using the id of the items at both ends that
+// is returned by given id. Given items are returned as is in ModePage.
+func Cut[T any](m *M, items []T, id func(T) uint) []T {
+	if m.Mode != ModeCursor {
+		return items
+	}
+	more := m.Limit != 0 && len(items) > m.Limit
+	if more {
+		items = items[:m.Limit]
+	}
+	if m.backward() {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if len(items) == 0 {
+		return items
+	}
+
+	at := func(item T, backward bool) string {
+		c := cursor{Column: m.orderColumn(), Desc: m.desc, ID: id(item), Backward: backward}
+		if m.field != nil {
+			c.Column = m.field.DBName
+		}
+		if c.Column != "id" && m.field != nil {
+			v, _ := m.field.ValueOf(context.Background(), reflect.ValueOf(item))
+			c.Value, _ = json.Marshal(v)
+		}
+		return c.encode()
+	}
+	// there is always a page on the side the cursor came from
+	if more || m.backward() {
+		m.NextCursor = at(items[len(items)-1], false)
+	}
+	if (more && m.backward()) || (m.cur != nil && !m.backward()) {
+		m.PrevCursor = at(items[0], true)
+	}
+
+	return items
+}
+
+// direction return the SQL sort direction.
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+	return "ASC"
+}
@@ -0,0 +1,327 @@
+package paginate
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type row struct {
+	ID  uint
+	Url string
+	At  *time.Time
+}
+
+func rowID(r row) uint { return r.ID }
+
+func rows(ids ...uint) []row {
+	var r []row
+	for _, id := range ids {
+		r = append(r, row{ID: id})
+	}
+	return r
+}
+
+func dryRun(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func TestM_Paginate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		m      M
+		expect M
+	}{
+		{
+			name:   "Given first page of several pages should only has next page",
+			m:      M{Limit: 2, Page: 1, count: 5},
+			expect: M{Limit: 2, Page: 1, Next: 2, TotalPage: 3, count: 5},
+		},
+		{
+			name:   "Given middle page should has both next and previous page",
+			m:      M{Limit: 2, Page: 2, count: 5},
+			expect: M{Limit: 2, Page: 2, Next: 3, Prev: 1, TotalPage: 3, count: 5},
+		},
+		{
+			name:   "Given last page should only has previous page",
+			m:      M{Limit: 2, Page: 3, count: 5},
+			expect: M{Limit: 2, Page: 3, Prev: 2, TotalPage: 3, count: 5},
+		},
+		{
+			name:   "Given no data should has one page",
+			m:      M{Limit: 2, Page: 1},
+			expect: M{Limit: 2, Page: 1, TotalPage: 1},
+		},
+		{
+			name:   "Given cursor mode should not touch the pages",
+			m:      M{Limit: 2, Mode: ModeCursor, count: 5},
+			expect: M{Limit: 2, Mode: ModeCursor, count: 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.m.Paginate()
+			assert.Equal(t, tc.expect, tc.m)
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	c := cursor{Column: "url", Desc: true, ID: 7, Backward: true}
+	valid := c.encode()
+
+	testCases := []struct {
+		name      string
+		cursor    string
+		expectErr bool
+	}{
+		{name: "Given encoded cursor should return the same cursor", cursor: valid},
+		{name: "Given cursor without signature should return error", cursor: "eyJjIjoidXJsIn0", expectErr: true},
+		{name: "Given tampered payload should return error", cursor: "eyJjIjoidXJsIiwiaSI6MX0" + valid[len(valid)-44:], expectErr: true},
+		{name: "Given tampered signature should return error", cursor: valid[:len(valid)-2] + "AA", expectErr: true},
+		{name: "Given random string should return error", cursor: "not.a-cursor", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeCursor(tc.cursor)
+			if tc.expectErr {
+				assert.ErrorIs(t, err, ErrInvalidCursor)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c, *got)
+		})
+	}
+}
+
+func TestM_Set(t *testing.T) {
+	next := (&cursor{Column: "url", ID: 4, Value: json.RawMessage(`"b"`)}).encode()
+	prev := (&cursor{Column: "url", ID: 4, Value: json.RawMessage(`"b"`), Backward: true}).encode()
+	nextDesc := (&cursor{Column: "url", Desc: true, ID: 4, Value: json.RawMessage(`"b"`)}).encode()
+	nextNull := (&cursor{Column: "at", ID: 4, Value: json.RawMessage(`null`)}).encode()
+	nextNullDesc := (&cursor{Column: "at", Desc: true, ID: 4, Value: json.RawMessage(`null`)}).encode()
+	nextID := (&cursor{Column: "id", ID: 4}).encode()
+	noValue := (&cursor{Column: "url", ID: 4}).encode()
+
+	testCases := []struct {
+		name      string
+		m         M
+		column    string
+		sort      string
+		expectSQL string
+		expectErr error
+	}{
+		{
+			name:      "Given page mode should count the rows first",
+			m:         M{Limit: 2, Page: 2},
+			column:    "url",
+			sort:      "desc",
+			expectSQL: `SELECT count(*) FROM "rows"`,
+		},
+		{
+			name:      "Given cursor mode without cursor should retrieve the first page with one more row",
+			m:         M{Limit: 2, Mode: ModeCursor},
+			column:    "url",
+			sort:      "asc",
+			expectSQL: `SELECT * FROM "rows" ORDER BY url ASC,id ASC LIMIT 3`,
+		},
+		{
+			name:      "Given next cursor should continue after the value of the row",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: next},
+			column:    "url",
+			sort:      "asc",
+			expectSQL: `SELECT * FROM "rows" WHERE ((url, id) > ($1, $2) OR url IS NULL) ORDER BY url ASC,id ASC LIMIT 3`,
+		},
+		{
+			name:      "Given previous cursor should retrieve backward before the value of the row",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: prev},
+			column:    "url",
+			sort:      "asc",
+			expectSQL: `SELECT * FROM "rows" WHERE (url, id) < ($1, $2) ORDER BY url DESC,id DESC LIMIT 3`,
+		},
+		{
+			name:      "Given next cursor in descending order should continue below the value of the row",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: nextDesc},
+			column:    "url",
+			sort:      "desc",
+			expectSQL: `SELECT * FROM "rows" WHERE (url, id) < ($1, $2) ORDER BY url DESC,id DESC LIMIT 3`,
+		},
+		{
+			name:      "Given next cursor of row without value should only continue through the rows without value",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: nextNull},
+			column:    "at",
+			sort:      "asc",
+			expectSQL: `SELECT * FROM "rows" WHERE (at IS NULL AND id > $1) ORDER BY at ASC,id ASC LIMIT 3`,
+		},
+		{
+			name:      "Given next cursor of row without value in descending order should continue to the rows with value",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: nextNullDesc},
+			column:    "at",
+			sort:      "desc",
+			expectSQL: `SELECT * FROM "rows" WHERE (at IS NOT NULL OR id < $1) ORDER BY at DESC,id DESC LIMIT 3`,
+		},
+		{
+			name:      "Given next cursor ordered by id should only compare the id",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: nextID},
+			sort:      "asc",
+			expectSQL: `SELECT * FROM "rows" WHERE id > $1 ORDER BY id ASC LIMIT 3`,
+		},
+		{
+			name:      "Given order by something other than a column should return error",
+			m:         M{Limit: 2},
+			column:    "url; DROP TABLE rows",
+			sort:      "asc",
+			expectErr: ErrInvalidOrder,
+		},
+		{
+			name:      "Given cursor without the value of the row should return error",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: noValue},
+			column:    "url",
+			sort:      "asc",
+			expectErr: ErrInvalidCursor,
+		},
+		{
+			name:      "Given cursor of other sort should return error",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: next},
+			column:    "url",
+			sort:      "desc",
+			expectErr: ErrInvalidCursor,
+		},
+		{
+			name:      "Given invalid cursor should return error",
+			m:         M{Limit: 2, Mode: ModeCursor, Cursor: "invalid"},
+			column:    "url",
+			sort:      "asc",
+			expectErr: ErrInvalidCursor,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.m.OrderBy(tc.column, tc.sort)
+			var out []row
+			tx := tc.m.Set(dryRun(t).Model(&row{}))
+			if tc.m.Mode == ModeCursor {
+				tx = tx.Find(&out)
+			}
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, tx.Error, tc.expectErr)
+				return
+			}
+			require.NoError(t, tx.Error)
+			assert.Equal(t, tc.expectSQL, tx.Statement.SQL.String())
+		})
+	}
+}
+
+func TestCut(t *testing.T) {
+	after := (&cursor{Column: "id", ID: 2}).encode()
+	before := (&cursor{Column: "id", ID: 5, Backward: true}).encode()
+
+	testCases := []struct {
+		name       string
+		m          M
+		items      []row
+		expectIDs  []row
+		expectNext *cursor
+		expectPrev *cursor
+	}{
+		{
+			name:      "Given page mode should return the items as is",
+			m:         M{Limit: 2},
+			items:     rows(1, 2, 3),
+			expectIDs: rows(1, 2, 3),
+		},
+		{
+			name:       "Given first page with more rows should only has next cursor",
+			m:          M{Limit: 2, Mode: ModeCursor},
+			items:      rows(1, 2, 3),
+			expectIDs:  rows(1, 2),
+			expectNext: &cursor{Column: "id", ID: 2},
+		},
+		{
+			name:      "Given the only page should has no cursor",
+			m:         M{Limit: 2, Mode: ModeCursor},
+			items:     rows(1, 2),
+			expectIDs: rows(1, 2),
+		},
+		{
+			name:       "Given next page with more rows should has both cursors",
+			m:          M{Limit: 2, Mode: ModeCursor, Cursor: after},
+			items:      rows(3, 4, 5),
+			expectIDs:  rows(3, 4),
+			expectNext: &cursor{Column: "id", ID: 4},
+			expectPrev: &cursor{Column: "id", ID: 3, Backward: true},
+		},
+		{
+			name:       "Given last page should only has previous cursor",
+			m:          M{Limit: 2, Mode: ModeCursor, Cursor: after},
+			items:      rows(3),
+			expectIDs:  rows(3),
+			expectPrev: &cursor{Column: "id", ID: 3, Backward: true},
+		},
+		{
+			name:       "Given previous page with more rows should be reversed and has both cursors",
+			m:          M{Limit: 2, Mode: ModeCursor, Cursor: before},
+			items:      rows(4, 3, 2),
+			expectIDs:  rows(3, 4),
+			expectNext: &cursor{Column: "id", ID: 4},
+			expectPrev: &cursor{Column: "id", ID: 3, Backward: true},
+		},
+		{
+			name:       "Given previous page that is the first page should only has next cursor",
+			m:          M{Limit: 2, Mode: ModeCursor, Cursor: before},
+			items:      rows(2, 1),
+			expectIDs:  rows(1, 2),
+			expectNext: &cursor{Column: "id", ID: 2},
+		},
+		{
+			name:  "Given empty page should has no cursor",
+			m:     M{Limit: 2, Mode: ModeCursor, Cursor: after},
+			items: rows(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// decode the given cursor just like Set does
+			tc.m.Set(dryRun(t).Model(&row{}))
+
+			got := Cut(&tc.m, tc.items, rowID)
+			assert.Equal(t, tc.expectIDs, got)
+			assertCursor(t, tc.expectNext, tc.m.NextCursor)
+			assertCursor(t, tc.expectPrev, tc.m.PrevCursor)
+		})
+	}
+}
+
+func TestCut_Value(t *testing.T) {
+	m := M{Limit: 1, Mode: ModeCursor}
+	m.OrderBy("url", "asc")
+	m.Set(dryRun(t).Model(&row{}))
+
+	Cut(&m, []row{{ID: 1, Url: "a"}, {ID: 2, Url: "b"}}, rowID)
+	assertCursor(t, &cursor{Column: "url", ID: 1, Value: json.RawMessage(`"a"`)}, m.NextCursor)
+}
+
+func assertCursor(t *testing.T, expect *cursor, got string) {
+	if expect == nil {
+		assert.Empty(t, got)
+		return
+	}
+	c, err := decodeCursor(got)
+	require.NoError(t, err)
+	assert.Equal(t, expect, c)
+}
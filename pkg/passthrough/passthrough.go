@@ -0,0 +1,102 @@
+package passthrough
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	// ModeMerge forward the incoming parameters that are not in the
+	// destination yet.
+	ModeMerge = "merge"
+	// ModeOverride forward every incoming parameter, replacing the ones in
+	// the destination.
+	ModeOverride = "override"
+)
+
+// Template replace each {name} placeholder in the values of given params with
+// the value of the name in given vars. Unknown placeholders are left as is.
+func Template(params url.Values, vars map[string]string) url.Values {
+	if len(params) == 0 {
+		return params
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	r := strings.NewReplacer(pairs...)
+
+	out := make(url.Values, len(params))
+	for k, vs := range params {
+		for _, v := range vs {
+			out.Add(k, r.Replace(v))
+		}
+	}
+	return out
+}
+
+// Apply add given default params to the destination if they are not in it
+// yet, then forward given incoming params using given mode, either ModeMerge
+// or ModeOverride, while any other mode doesn't forward anything. Incoming
+// params always win over the defaults. The query of the destination is kept
+// as it is written and the new params are appended after it. Return the
+// destination as is if there is nothing to add.
+func Apply(dest string, defaults, incoming url.Values, mode string) (string, error) {
+	if mode != ModeMerge && mode != ModeOverride {
+		incoming = nil
+	}
+	if len(defaults) == 0 && len(incoming) == 0 {
+		return dest, nil
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", err
+	}
+	orig := u.Query()
+	add := make(url.Values)
+	for k, vs := range defaults {
+		if _, ok := orig[k]; !ok {
+			add[k] = vs
+		}
+	}
+	replaced := make(map[string]bool)
+	for k, vs := range incoming {
+		if _, ok := orig[k]; ok {
+			if mode == ModeMerge {
+				continue
+			}
+			replaced[k] = true
+		}
+		add[k] = vs
+	}
+	if len(add) == 0 {
+		return dest, nil
+	}
+
+	raw := dropKeys(u.RawQuery, replaced)
+	if raw != "" {
+		raw += "&"
+	}
+	u.RawQuery = raw + add.Encode()
+
+	return u.String(), nil
+}
+
+// dropKeys remove every pair whose key is in given keys from given raw query
+// while leaving the other pairs untouched.
+func dropKeys(raw string, keys map[string]bool) string {
+	if len(keys) == 0 || raw == "" {
+		return raw
+	}
+	pairs := strings.Split(raw, "&")
+	kept := pairs[:0]
+	for _, p := range pairs {
+		k, _, _ := strings.Cut(p, "=")
+		if uk, err := url.QueryUnescape(k); err == nil && keys[uk] {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, "&")
+}
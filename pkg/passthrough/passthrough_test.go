@@ -0,0 +1,88 @@
+package passthrough
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate(t *testing.T) {
+	params := url.Values{"utm_campaign": {"{slug}"}, "utm_source": {"{domain}-{unknown}"}}
+	vars := map[string]string{"slug": "promo 2024", "domain": "go.example.com"}
+
+	out := Template(params, vars)
+	assert.Equal(t, "promo 2024", out.Get("utm_campaign"))
+	assert.Equal(t, "go.example.com-{unknown}", out.Get("utm_source"))
+	// the given params should stay intact
+	assert.Equal(t, "{slug}", params.Get("utm_campaign"))
+}
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dest     string
+		defaults url.Values
+		incoming url.Values
+		mode     string
+		expect   string
+	}{
+		{
+			name:     "Given nothing to add should return the destination as is",
+			dest:     "https://example.com/page?b=2&a=1#top",
+			incoming: url.Values{"utm_source": {"x"}},
+			expect:   "https://example.com/page?b=2&a=1#top",
+		},
+		{
+			name:     "Given default params should only append the missing ones and encode them",
+			dest:     "https://example.com/page?utm_medium=mail#top",
+			defaults: url.Values{"utm_medium": {"short"}, "utm_campaign": {"promo 2024&more"}},
+			expect:   "https://example.com/page?utm_medium=mail&utm_campaign=promo+2024%26more#top",
+		},
+		{
+			name:     "Given destination query that is not canonical should keep it as it is written",
+			dest:     "https://example.com/?z=b%2Cc&flag&a=1",
+			defaults: url.Values{"utm_source": {"short"}},
+			expect:   "https://example.com/?z=b%2Cc&flag&a=1&utm_source=short",
+		},
+		{
+			name:     "Given incoming params in merge mode should win over the defaults",
+			dest:     "https://example.com/",
+			defaults: url.Values{"utm_source": {"short"}, "utm_medium": {"link"}},
+			incoming: url.Values{"utm_source": {"x"}},
+			mode:     ModeMerge,
+			expect:   "https://example.com/?utm_medium=link&utm_source=x",
+		},
+		{
+			name:     "Given merge mode should keep the params in the destination",
+			dest:     "https://example.com/?ref=site",
+			incoming: url.Values{"ref": {"x"}, "utm_source": {"x"}},
+			mode:     ModeMerge,
+			expect:   "https://example.com/?ref=site&utm_source=x",
+		},
+		{
+			name:     "Given override mode should replace the params in the destination and the defaults",
+			dest:     "https://example.com/?ref=site",
+			defaults: url.Values{"utm_source": {"short"}},
+			incoming: url.Values{"ref": {"x"}, "utm_source": {"x"}},
+			mode:     ModeOverride,
+			expect:   "https://example.com/?ref=x&utm_source=x",
+		},
+		{
+			name:     "Given override mode should only drop the replaced params from the destination",
+			dest:     "https://example.com/?z=b%2Cc&ref=site&ref=old",
+			incoming: url.Values{"ref": {"x"}},
+			mode:     ModeOverride,
+			expect:   "https://example.com/?z=b%2Cc&ref=x",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := Apply(tc.dest, tc.defaults, tc.incoming, tc.mode)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, out)
+		})
+	}
+}
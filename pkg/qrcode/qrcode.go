@@ -0,0 +1,123 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+const (
+	// FormatPNG render the QR code as PNG image.
+	FormatPNG = "png"
+	// FormatSVG render the QR code as SVG image.
+	FormatSVG = "svg"
+)
+
+// Options how the QR code should be rendered.
+type Options struct {
+	// Format either FormatPNG or FormatSVG.
+	Format string
+	// Size the width and height of the image in pixels. The actual size may
+	// be slightly smaller, so each module has the same number of pixels.
+	Size int
+	// Level the error correction level, either L, M, Q or H.
+	Level string
+	// Quiet the number of blank modules around the code.
+	Quiet int
+}
+
+// ParseLevel convert given error correction level name to qr.Level.
+func ParseLevel(s string) (qr.Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return qr.L, nil
+	case "M":
+		return qr.M, nil
+	case "Q":
+		return qr.Q, nil
+	case "H":
+		return qr.H, nil
+	}
+	return 0, errors.New("unknown error correction level " + s)
+}
+
+// MimeType return the MIME type of given format.
+func MimeType(format string) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render encode given text as QR code based on given options.
+func Render(text string, opt Options) ([]byte, error) {
+	lv, err := ParseLevel(opt.Level)
+	if err != nil {
+		return nil, err
+	}
+	code, err := qr.Encode(text, lv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode: %s", err)
+	}
+
+	modules := code.Size + 2*opt.Quiet
+	scale := opt.Size / modules
+	if scale < 1 {
+		scale = 1
+	}
+
+	if opt.Format == FormatSVG {
+		return renderSVG(code, opt.Quiet, modules, scale), nil
+	}
+	return renderPNG(code, opt.Quiet, modules, scale)
+}
+
+func renderPNG(code *qr.Code, quiet, modules, scale int) ([]byte, error) {
+	pal := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, modules*scale, modules*scale), pal)
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if !code.Black(x, y) {
+				continue
+			}
+			// fill the whole module
+			for py := (y + quiet) * scale; py < (y+quiet+1)*scale; py++ {
+				row := img.Pix[py*img.Stride:]
+				for px := (x + quiet) * scale; px < (x+quiet+1)*scale; px++ {
+					row[px] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG draw each black module as a unit square in a single path, then
+// let the viewBox scale it to the requested size.
+func renderSVG(code *qr.Code, quiet, modules, scale int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		modules*scale, modules*scale, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,61 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"rsc.io/qr"
+)
+
+func TestRender(t *testing.T) {
+	const text = "https://example.com/zoom"
+	code, err := qr.Encode(text, qr.M)
+	require.NoError(t, err)
+
+	t.Run("Given png format should return PNG that has the whole modules in given size", func(t *testing.T) {
+		b, err := Render(text, Options{Format: FormatPNG, Size: 300, Level: "m", Quiet: 4})
+		require.NoError(t, err)
+
+		img, err := png.Decode(bytes.NewReader(b))
+		require.NoError(t, err)
+		modules := code.Size + 8
+		scale := 300 / modules
+		assert.Equal(t, modules*scale, img.Bounds().Dx())
+
+		// the quiet zone is white while the top left finder pattern is black
+		r, _, _, _ := img.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), r)
+		r, _, _, _ = img.At(4*scale, 4*scale).RGBA()
+		assert.Equal(t, uint32(0), r)
+	})
+
+	t.Run("Given svg format should return SVG that use the modules as the viewBox", func(t *testing.T) {
+		b, err := Render(text, Options{Format: FormatSVG, Size: 200, Level: "H", Quiet: 0})
+		require.NoError(t, err)
+		hc, _ := qr.Encode(text, qr.H)
+
+		svg := string(b)
+		assert.True(t, strings.HasPrefix(svg, "<svg"))
+		assert.Contains(t, svg, `viewBox="0 0 `+strconv.Itoa(hc.Size)+" "+strconv.Itoa(hc.Size)+`"`)
+		assert.Contains(t, svg, "M0 0h1v1h-1z")
+	})
+
+	t.Run("Given size smaller than the modules should use one pixel per module", func(t *testing.T) {
+		b, err := Render(text, Options{Format: FormatPNG, Size: 1, Level: "L", Quiet: 1})
+		require.NoError(t, err)
+		cfg, err := png.DecodeConfig(bytes.NewReader(b))
+		require.NoError(t, err)
+		lc, _ := qr.Encode(text, qr.L)
+		assert.Equal(t, lc.Size+2, cfg.Width)
+	})
+
+	t.Run("Given unknown level should return error", func(t *testing.T) {
+		_, err := Render(text, Options{Format: FormatPNG, Size: 100, Level: "X"})
+		assert.Error(t, err)
+	})
+}
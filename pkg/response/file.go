@@ -0,0 +1,17 @@
+package response
+
+import "github.com/gofiber/fiber/v2"
+
+// File return given content as it is using given MIME type. Given etag is used
+// as the cache validator, so the content is only sent if the client doesn't
+// have it yet.
+func File(c *fiber.Ctx, content []byte, mimeType, etag string) error {
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	c.Set(fiber.HeaderETag, `"`+etag+`"`)
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, mimeType)
+	return c.Status(fiber.StatusOK).Send(content)
+}
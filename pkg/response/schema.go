@@ -0,0 +1,7 @@
+package response
+
+// Schemas return the zero value of the standard success and error response,
+// so the API documentation describe the same structure that is responded.
+func Schemas() (success, failed any) {
+	return appSuccess{}, appError{}
+}
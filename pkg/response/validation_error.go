@@ -41,6 +41,14 @@ func errMsgMapping(fe validator.FieldError) string {
 		return "should be a complete url along with the FQDN"
 	case "boolean":
 		return "should be a boolean string"
+	case "oneof":
+		return "should be one of " + fe.Param()
+	case "datetime":
+		return "should be a datetime in RFC3339 format"
+	case "opt_datetime":
+		return "should be empty or a datetime in RFC3339 format"
+	case "after":
+		return "should be after " + strings.ToLower(fe.Param())
 	}
 	return fe.Error()
 }
@@ -0,0 +1,72 @@
+package rewrap
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+
+	conf "github.com/mdanialr/sns_backend/pkg/config"
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/mdanialr/sns_backend/pkg/storage"
+)
+
+// Run do wrap again every encrypted file in the storage path using the
+// current master key from config. Should be run after the master key is
+// rotated while the previous one is still listed in old keys.
+func Run() {
+	// init viper config
+	v, err := conf.InitConfigYml()
+	if err != nil {
+		log.Fatalln("failed to init config:", err)
+	}
+	if v.GetString("storage.encryption.key") == "" {
+		log.Fatalln("storage encryption is not enabled")
+	}
+	l := logger.NewStdOut()
+	l.Init()
+	st, err := storage.NewEncryptedWithConfig(v, l, storage.NewFile(l))
+	if err != nil {
+		log.Fatalln("failed to init encrypted storage:", err)
+	}
+
+	var total, rewrapped int
+	err = filepath.WalkDir(v.GetString("storage.path"), func(pt string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		// leave the files that are still being written
+		if isPending(pt) {
+			return nil
+		}
+		total++
+		ok, err := st.Rewrap(pt)
+		if err != nil {
+			l.Err("failed to rewrap", pt, ":", err)
+			return nil
+		}
+		if ok {
+			rewrapped++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalln("failed to walk storage path:", err)
+	}
+	fmt.Printf("Rewrapped %d of %d files\n", rewrapped, total)
+}
+
+// pendingSuffixes the suffixes of files that are still being written, either
+// by an upload or by another rewrap.
+var pendingSuffixes = []string{".part", ".rewrap"}
+
+// isPending return true if given file path is still being written.
+func isPending(pt string) bool {
+	for _, sf := range pendingSuffixes {
+		if strings.HasSuffix(pt, sf) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize the size of each chunk that is streamed to clamd. Should be
+// less than StreamMaxLength in clamd config.
+const clamdChunkSize = 64 * 1024
+
+type clamd struct {
+	network, address string
+	timeout          time.Duration
+}
+
+// NewClamd return implementation of IScanner that stream the file to ClamAV
+// daemon using INSTREAM command. Given network should be either tcp or unix
+// along with the address that is either host:port or the socket path.
+// Ref: https://linux.die.net/man/8/clamd
+func NewClamd(network, address string, timeout time.Duration) IScanner {
+	return &clamd{network, address, timeout}
+}
+
+func (c *clamd) Scan(ctx context.Context, r io.Reader) (*Verdict, error) {
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if c.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	// the z prefix means the command and the reply is terminated by null
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, rErr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err = conn.Write(buf[:4+n]); err != nil {
+				return nil, err
+			}
+		}
+		if errors.Is(rErr, io.EOF) || errors.Is(rErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if rErr != nil {
+			return nil, rErr
+		}
+	}
+	// zero length chunk mark the end of the stream
+	if _, err = conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && len(reply) == 0 {
+		return nil, err
+	}
+
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply convert the reply of INSTREAM command that should be either
+// `stream: OK`, `stream: <signature> FOUND` or `<message> ERROR`.
+func parseClamdReply(reply string) (*Verdict, error) {
+	res := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case res == "OK":
+		return &Verdict{}, nil
+	case strings.HasSuffix(res, " FOUND"):
+		return &Verdict{Infected: true, Signature: strings.TrimSuffix(res, " FOUND")}, nil
+	}
+	return nil, errors.New("clamd: " + res)
+}
@@ -0,0 +1,132 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamd serve INSTREAM command on given listener then reply based on the
+// received content.
+func fakeClamd(t *testing.T, ln net.Listener, reply func(content []byte) string) {
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				cmd, err := rd.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var content bytes.Buffer
+				for {
+					var size uint32
+					if err = binary.Read(rd, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err = io.CopyN(&content, rd, int64(size)); err != nil {
+						return
+					}
+				}
+				conn.Write([]byte(reply(content.Bytes()) + "\x00"))
+			}(conn)
+		}
+	}()
+}
+
+// eicarReply mimic clamd that only detect the EICAR test string.
+func eicarReply(content []byte) string {
+	if bytes.Contains(content, []byte("EICAR")) {
+		return "stream: Eicar-Test-Signature FOUND"
+	}
+	if len(content) == 0 {
+		return "INSTREAM size limit exceeded. ERROR"
+	}
+	return "stream: OK"
+}
+
+func TestClamd_Scan(t *testing.T) {
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fakeClamd(t, tcpLn, eicarReply)
+
+	sock := filepath.Join(t.TempDir(), "clamd.sock")
+	unixLn, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	fakeClamd(t, unixLn, eicarReply)
+
+	// reserve an address then close it, so nothing is listening there
+	closedLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closedLn.Addr().String()
+	closedLn.Close()
+
+	testCases := []struct {
+		name             string
+		network, address string
+		content          string
+		wantErr          bool
+		want             *Verdict
+	}{
+		{
+			name:    "Given clean content through tcp should return not infected verdict",
+			network: "tcp", address: tcpLn.Addr().String(),
+			content: "hello world",
+			want:    &Verdict{},
+		},
+		{
+			name:    "Given content bigger than a chunk through unix socket should return not infected verdict",
+			network: "unix", address: sock,
+			content: strings.Repeat("a", 3*clamdChunkSize+7),
+			want:    &Verdict{},
+		},
+		{
+			name:    "Given infected content should return the signature",
+			network: "tcp", address: tcpLn.Addr().String(),
+			content: "X5O!P%@AP EICAR test file",
+			want:    &Verdict{Infected: true, Signature: "Eicar-Test-Signature"},
+		},
+		{
+			name:    "Given clamd that reply with error should return error",
+			network: "unix", address: sock,
+			wantErr: true,
+		},
+		{
+			name:    "Given address that nothing listen to should return error",
+			network: "tcp", address: closedAddr,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sc := NewClamd(tc.network, tc.address, 5*time.Second)
+			vd, err := sc.Scan(context.Background(), strings.NewReader(tc.content))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, vd)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// StatusClean the file has no threat.
+	StatusClean = "clean"
+	// StatusInfected the file contain a threat.
+	StatusInfected = "infected"
+	// StatusFailed the file could not be scanned.
+	StatusFailed = "failed"
+)
+
+// Action what should be done with an upload based on the scan result.
+type Action string
+
+const (
+	// ActionReject reject the upload.
+	ActionReject Action = "reject"
+	// ActionQuarantine keep the upload but never serve it to the public.
+	ActionQuarantine Action = "quarantine"
+	// ActionFlag keep and serve the upload as usual but record the result.
+	ActionFlag Action = "flag"
+)
+
+// ErrRejected the upload is rejected based on the scan result.
+var ErrRejected = errors.New("upload rejected")
+
+// Verdict the outcome of scanning a file.
+type Verdict struct {
+	// Infected true if the scanner found any threat.
+	Infected bool
+	// Signature the name of the found threat.
+	Signature string
+}
+
+// IScanner all implementation of Scanner pkg should use this interface as
+// their signature/guideline.
+type IScanner interface {
+	// Scan read everything from given reader then return the verdict. Any
+	// error means the scanner could not decide.
+	Scan(context.Context, io.Reader) (*Verdict, error)
+}
+
+// NewWithConfig return IScanner based on the driver in given viper config.
+// Return nil IScanner if the driver is empty which means scanning is
+// disabled.
+func NewWithConfig(v *viper.Viper) (IScanner, error) {
+	switch v.GetString("scanner.driver") {
+	case "":
+		return nil, nil
+	case "clamd":
+		timeout := time.Duration(v.GetInt("scanner.timeout")) * time.Second
+		return NewClamd(v.GetString("scanner.network"), v.GetString("scanner.address"), timeout), nil
+	}
+	return nil, errors.New("unsupported scanner driver. currently support [clamd]")
+}
+
+// Result the scan result that should be stored along with the upload.
+type Result struct {
+	Status      string
+	Detail      string
+	Quarantined bool
+}
+
+// Worse return true if this Result is worse than given one, which may be nil.
+// A quarantined Result is always worse than the one that is not, otherwise an
+// infected file is worse than the one that failed to be scanned, which is
+// worse than a clean one.
+func (r *Result) Worse(o *Result) bool {
+	if o == nil {
+		return true
+	}
+	if r.Quarantined != o.Quarantined {
+		return r.Quarantined
+	}
+	return severity(r.Status) > severity(o.Status)
+}
+
+// severity return the rank of given status, the higher the worse.
+func severity(status string) int {
+	switch status {
+	case StatusInfected:
+		return 2
+	case StatusFailed:
+		return 1
+	}
+	return 0
+}
+
+// Policy decide what should be done with an upload based on the verdict.
+type Policy struct {
+	// OnInfected action when the file contain a threat.
+	OnInfected Action
+	// OnError action when the scanner is unavailable or failed.
+	OnError Action
+}
+
+// NewPolicyWithConfig return Policy using the actions from given viper config.
+// Action that is not set or unknown fallback to ActionReject.
+func NewPolicyWithConfig(v *viper.Viper) *Policy {
+	return &Policy{
+		OnInfected: toAction(v.GetString("scanner.on_infected")),
+		OnError:    toAction(v.GetString("scanner.on_error")),
+	}
+}
+
+// Apply return the Result of given verdict and scan error. Return error
+// wrapping ErrRejected if the upload should be rejected.
+func (p *Policy) Apply(vd *Verdict, scanErr error) (*Result, error) {
+	var r Result
+	var act Action
+	switch {
+	case scanErr != nil:
+		r.Status, r.Detail, act = StatusFailed, scanErr.Error(), p.OnError
+	case vd.Infected:
+		r.Status, r.Detail, act = StatusInfected, vd.Signature, p.OnInfected
+	default:
+		r.Status = StatusClean
+		return &r, nil
+	}
+
+	switch act {
+	case ActionFlag:
+	case ActionQuarantine:
+		r.Quarantined = true
+	default:
+		if r.Status == StatusInfected {
+			return nil, fmt.Errorf("%w: file is infected by %s", ErrRejected, r.Detail)
+		}
+		return nil, fmt.Errorf("%w: file could not be scanned", ErrRejected)
+	}
+	return &r, nil
+}
+
+// toAction convert given string to known Action.
+func toAction(s string) Action {
+	switch act := Action(s); act {
+	case ActionQuarantine, ActionFlag:
+		return act
+	}
+	return ActionReject
+}
@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Apply(t *testing.T) {
+	testCases := []struct {
+		name       string
+		onInfected string
+		onError    string
+		verdict    *Verdict
+		scanErr    error
+		wantErr    bool
+		want       *Result
+	}{
+		{
+			name:    "Given clean verdict should return clean status",
+			verdict: &Verdict{},
+			want:    &Result{Status: StatusClean},
+		},
+		{
+			name:    "Given infected verdict and no action in config should be rejected",
+			verdict: &Verdict{Infected: true, Signature: "Eicar"},
+			wantErr: true,
+		},
+		{
+			name:       "Given infected verdict and quarantine action should return quarantined result",
+			onInfected: "quarantine",
+			verdict:    &Verdict{Infected: true, Signature: "Eicar"},
+			want:       &Result{Status: StatusInfected, Detail: "Eicar", Quarantined: true},
+		},
+		{
+			name:       "Given infected verdict and flag action should return infected result",
+			onInfected: "flag",
+			verdict:    &Verdict{Infected: true, Signature: "Eicar"},
+			want:       &Result{Status: StatusInfected, Detail: "Eicar"},
+		},
+		{
+			name:    "Given scan error and unknown action should be rejected",
+			onError: "ignore",
+			scanErr: errors.New("connection refused"),
+			wantErr: true,
+		},
+		{
+			name:    "Given scan error and flag action should return failed result",
+			onError: "flag",
+			scanErr: errors.New("connection refused"),
+			want:    &Result{Status: StatusFailed, Detail: "connection refused"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("scanner.on_infected", tc.onInfected)
+			v.Set("scanner.on_error", tc.onError)
+
+			r, err := NewPolicyWithConfig(v).Apply(tc.verdict, tc.scanErr)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrRejected)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, r)
+		})
+	}
+}
+
+func TestResult_Worse(t *testing.T) {
+	clean := &Result{Status: StatusClean}
+	failed := &Result{Status: StatusFailed}
+	infected := &Result{Status: StatusInfected}
+	quarantined := &Result{Status: StatusFailed, Quarantined: true}
+
+	testCases := []struct {
+		name string
+		r    *Result
+		o    *Result
+		want bool
+	}{
+		{name: "Given nothing to compare with should be worse", r: clean, want: true},
+		{name: "Given failed compared to clean should be worse", r: failed, o: clean, want: true},
+		{name: "Given infected compared to failed should be worse", r: infected, o: failed, want: true},
+		{name: "Given clean compared to infected should not be worse", r: clean, o: infected},
+		{name: "Given the same status should not be worse", r: failed, o: &Result{Status: StatusFailed}},
+		{name: "Given quarantined compared to infected that is only flagged should be worse", r: quarantined, o: infected, want: true},
+		{name: "Given infected that is only flagged compared to quarantined should not be worse", r: infected, o: quarantined},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.r.Worse(tc.o))
+		})
+	}
+}
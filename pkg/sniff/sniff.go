@@ -0,0 +1,111 @@
+package sniff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/spf13/viper"
+)
+
+// ErrRejected the upload is rejected by the Policy.
+var ErrRejected = errors.New("upload rejected")
+
+// Detect return the MIME type of given reader by only reading the first few
+// bytes of it. The reader should be rewound afterward if it's going to be
+// read again.
+func Detect(r io.Reader) (*mimetype.MIME, error) {
+	return mimetype.DetectReader(r)
+}
+
+// Policy decide which detected MIME type is accepted.
+type Policy struct {
+	// Allow only accept MIME types that match one of these patterns if not
+	// empty. Pattern is either exact MIME type or with wildcard subtype such
+	// as image/*.
+	Allow []string
+	// Deny always reject MIME types that match one of these patterns.
+	Deny []string
+	// StrictExtension reject file whose extension disagree with the detected
+	// MIME type.
+	StrictExtension bool
+}
+
+// NewPolicyWithConfig return Policy using the rules from given viper config.
+func NewPolicyWithConfig(v *viper.Viper) *Policy {
+	return &Policy{
+		Allow:           v.GetStringSlice("mime.allow"),
+		Deny:            v.GetStringSlice("mime.deny"),
+		StrictExtension: v.GetBool("mime.strict_extension"),
+	}
+}
+
+// Check return error wrapping ErrRejected if given MIME type of the content
+// of given filename is not accepted.
+func (p *Policy) Check(mt *mimetype.MIME, filename string) error {
+	if matchAny(mt, p.Deny) || len(p.Allow) > 0 && !matchAny(mt, p.Allow) {
+		return fmt.Errorf("%w: file type %s is not allowed", ErrRejected, mediaType(mt))
+	}
+	if p.StrictExtension && !MatchExtension(mt, filepath.Ext(filename)) {
+		return fmt.Errorf("%w: extension %s does not match file type %s", ErrRejected, filepath.Ext(filename), mediaType(mt))
+	}
+	return nil
+}
+
+// MatchExtension return true if given extension is expected for given MIME
+// type. The extension agree if it belongs to the MIME type or to one of its
+// parents, or if the MIME type is the generic parent of the extension's type
+// such as text/plain for .py.
+func MatchExtension(mt *mimetype.MIME, ext string) bool {
+	ext = strings.ToLower(ext)
+	if ext == "" {
+		return true
+	}
+	extType, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	if extType == "" && (mt.Is("application/octet-stream") || mt.Is("text/plain")) {
+		// nothing to compare between generic content and unknown extension
+		return true
+	}
+
+	// the content is the same or more specific than what the extension claim
+	for m := mt; m != nil && m.Parent() != nil; m = m.Parent() {
+		if m.Extension() == ext || extType != "" && m.Is(extType) {
+			return true
+		}
+	}
+	// the content is less specific than what the extension claim
+	if lk := mimetype.Lookup(extType); lk != nil {
+		for m := lk; m != nil && m.Parent() != nil; m = m.Parent() {
+			if m.Is(mt.String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny return true if given MIME type match any of given patterns.
+func matchAny(mt *mimetype.MIME, patterns []string) bool {
+	for _, pt := range patterns {
+		if sub, ok := strings.CutSuffix(pt, "/*"); ok {
+			if strings.HasPrefix(mediaType(mt), sub+"/") {
+				return true
+			}
+			continue
+		}
+		if mt.Is(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType return given MIME type without any parameters.
+func mediaType(mt *mimetype.MIME) string {
+	t, _, _ := mime.ParseMediaType(mt.String())
+	return t
+}
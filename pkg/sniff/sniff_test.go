@@ -0,0 +1,112 @@
+package sniff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	samplePNG  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x02\x00\x00\x00")
+	sampleText = []byte("print('hello world')\n")
+	sampleBin  = []byte{0x00, 0x9f, 0x13, 0x00, 0xfe, 0x01, 0x02, 0x00}
+)
+
+func TestPolicy_Check(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   Policy
+		sample   []byte
+		filename string
+		expect   string
+	}{
+		{
+			name:     "Given png content with png extension and strict extension should be accepted",
+			policy:   Policy{StrictExtension: true},
+			sample:   samplePNG,
+			filename: "image.png",
+		},
+		{
+			name:     "Given png content with upper case png extension and strict extension should be accepted",
+			policy:   Policy{StrictExtension: true},
+			sample:   samplePNG,
+			filename: "image.PNG",
+		},
+		{
+			name:     "Given png content with pdf extension and strict extension should be rejected",
+			policy:   Policy{StrictExtension: true},
+			sample:   samplePNG,
+			filename: "image.pdf",
+			expect:   "upload rejected: extension .pdf does not match file type image/png",
+		},
+		{
+			name:     "Given png content with pdf extension without strict extension should be accepted",
+			policy:   Policy{},
+			sample:   samplePNG,
+			filename: "image.pdf",
+		},
+		{
+			name:     "Given plain text content with py extension and strict extension should be accepted",
+			policy:   Policy{StrictExtension: true},
+			sample:   sampleText,
+			filename: "main.py",
+		},
+		{
+			name:     "Given plain text content with png extension and strict extension should be rejected",
+			policy:   Policy{StrictExtension: true},
+			sample:   sampleText,
+			filename: "image.png",
+			expect:   "upload rejected: extension .png does not match file type text/plain",
+		},
+		{
+			name:     "Given unknown binary content with unknown extension and strict extension should be accepted",
+			policy:   Policy{StrictExtension: true},
+			sample:   sampleBin,
+			filename: "data.sns",
+		},
+		{
+			name:     "Given unknown binary content with png extension and strict extension should be rejected",
+			policy:   Policy{StrictExtension: true},
+			sample:   sampleBin,
+			filename: "image.png",
+			expect:   "upload rejected: extension .png does not match file type application/octet-stream",
+		},
+		{
+			name:     "Given png content that match wildcard in deny list should be rejected",
+			policy:   Policy{Deny: []string{"image/*"}},
+			sample:   samplePNG,
+			filename: "image.png",
+			expect:   "upload rejected: file type image/png is not allowed",
+		},
+		{
+			name:     "Given plain text content that is not in allow list should be rejected",
+			policy:   Policy{Allow: []string{"image/png", "application/pdf"}},
+			sample:   sampleText,
+			filename: "main.py",
+			expect:   "upload rejected: file type text/plain is not allowed",
+		},
+		{
+			name:     "Given png content that is in allow list should be accepted",
+			policy:   Policy{Allow: []string{"image/png", "application/pdf"}},
+			sample:   samplePNG,
+			filename: "image.png",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mt, err := Detect(bytes.NewReader(tc.sample))
+			require.NoError(t, err)
+
+			err = tc.policy.Check(mt, tc.filename)
+			if tc.expect == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrRejected)
+			assert.EqualError(t, err, tc.expect)
+		})
+	}
+}
@@ -0,0 +1,656 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+// Every encrypted file has the following layout:
+//
+//	header: magic (4) | version (1) | key id (8) | wrapped data key (60) | nonce prefix (4)
+//	body  : zero or more chunks of plain length (4, big endian) | sealed chunk (plain length + 16)
+//
+// Each chunk is sealed using AES-GCM with the file's data key and a nonce that
+// is made of the nonce prefix followed by the chunk index, so a file can be
+// appended and read from any offset without decrypting the whole file. The
+// key id and the nonce prefix are authenticated along with every chunk, so
+// does whether it's the final chunk, which has the top bit of its index set
+// as well. That way a chunk can't be moved to another file, and a file that
+// is cut at the chunk boundary is detected.
+const (
+	encMagic        = "SNSE"
+	encVersion      = 1
+	keyIDSize       = 8
+	dataKeySize     = 32
+	gcmNonceSize    = 12
+	gcmTagSize      = 16
+	wrappedKeySize  = gcmNonceSize + dataKeySize + gcmTagSize
+	noncePrefixSize = 4
+	headerSize      = len(encMagic) + 1 + keyIDSize + wrappedKeySize + noncePrefixSize
+	chunkLenSize    = 4
+	chunkSize       = 64 * 1024
+	finalFlag       = 1 << 63
+)
+
+// ErrUnknownKey the data key of a file was wrapped by a master key that is
+// not configured.
+var ErrUnknownKey = errors.New("file was encrypted by unknown master key")
+
+// MasterKey the key that is used to wrap the data key of each encrypted file.
+type MasterKey struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// NewMasterKey return MasterKey from given base64 encoded 32 bytes key.
+func NewMasterKey(b64 string) (*MasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, errors.New("master key should be base64 encoded")
+	}
+	if len(key) != dataKeySize {
+		return nil, errors.New("master key should be 32 bytes long")
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+
+	return &MasterKey{id: sum[:keyIDSize], aead: aead}, nil
+}
+
+// wrap seal given data key using this master key.
+func (m *MasterKey) wrap(dek []byte) ([]byte, error) {
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return m.aead.Seal(nonce, nonce, dek, m.id), nil
+}
+
+// unwrap open given wrapped data key using this master key.
+func (m *MasterKey) unwrap(wrapped []byte) ([]byte, error) {
+	return m.aead.Open(nil, wrapped[:gcmNonceSize], wrapped[gcmNonceSize:], m.id)
+}
+
+type encryptedStorage struct {
+	log  logger.Writer
+	base IStorage
+	key  *MasterKey
+	old  []*MasterKey
+}
+
+// NewEncrypted return implementation of IStorage that transparently encrypt
+// every file before it's handed to given base Storage and decrypt it back when
+// it's opened. Each file has its own data key that is wrapped by given key.
+// Given old keys are only used to unwrap the data key of files that are not
+// rewrapped yet after the master key is rotated. Files that are saved before
+// the encryption is enabled are read as is.
+func NewEncrypted(l logger.Writer, base IStorage, key *MasterKey, old ...*MasterKey) IEncryptedStorage {
+	return &encryptedStorage{l, base, key, old}
+}
+
+// NewEncryptedWithConfig same as NewEncrypted but use the master keys from
+// given viper config.
+func NewEncryptedWithConfig(v *viper.Viper, l logger.Writer, base IStorage) (IEncryptedStorage, error) {
+	key, err := NewMasterKey(v.GetString("storage.encryption.key"))
+	if err != nil {
+		return nil, err
+	}
+	var olds []*MasterKey
+	for _, k := range v.GetStringSlice("storage.encryption.old_keys") {
+		old, err := NewMasterKey(k)
+		if err != nil {
+			return nil, errors.New("invalid old key: " + err.Error())
+		}
+		olds = append(olds, old)
+	}
+
+	return NewEncrypted(l, base, key, olds...), nil
+}
+
+func (e *encryptedStorage) Save(rc io.ReadCloser, s string) {
+	hd, aead, err := e.newHeader()
+	if err != nil {
+		rc.Close()
+		e.log.Err("failed to prepare encryption for", s, ":", err)
+		return
+	}
+	sr := newSealReader(rc, aead, hd, 0)
+	e.base.Save(&readCloser{io.MultiReader(bytes.NewReader(hd.marshal()), sr), rc}, s)
+}
+
+func (e *encryptedStorage) Append(r io.Reader, s string) (int64, error) {
+	fl, err := e.base.Open(s)
+	if err != nil {
+		// the file is not exist yet, so start with a new header
+		hd, aead, err := e.newHeader()
+		if err != nil {
+			return 0, err
+		}
+		sr := newSealReader(r, aead, hd, 0)
+		n, err := e.base.Append(io.MultiReader(bytes.NewReader(hd.marshal()), sr), s)
+		return sr.plainWritten(n - int64(headerSize)), err
+	}
+
+	// continue from the last chunk of the existing file
+	hd, err := readHeader(fl)
+	if err != nil {
+		fl.Close()
+		if errors.Is(err, errNotEncrypted) {
+			return e.base.Append(r, s)
+		}
+		return 0, err
+	}
+	aead, err := e.dataKey(hd)
+	if err != nil {
+		fl.Close()
+		return 0, err
+	}
+	chunks, _, err := scanChunks(fl)
+	if err != nil {
+		fl.Close()
+		return 0, err
+	}
+	last, index, err := e.unfinal(fl, aead, hd, chunks, s)
+	fl.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	// the plain content of the final chunk is sealed again as a regular chunk
+	// followed by given reader
+	sr := newSealReader(io.MultiReader(bytes.NewReader(last), r), aead, hd, index)
+	n, err := e.base.Append(sr, s)
+
+	return sr.plainWritten(n) - int64(len(last)), err
+}
+
+// unfinal cut the final chunk off from the file with given name, so it can be
+// appended, then return its plain content along with the index of the chunk
+// that should be sealed next.
+func (e *encryptedStorage) unfinal(rs io.ReadSeeker, aead cipher.AEAD, hd *encHeader, chunks []chunkInfo, s string) ([]byte, uint64, error) {
+	i := len(chunks) - 1
+	if i < 0 {
+		return nil, uint64(len(chunks)), nil
+	}
+	last, err := openChunk(rs, aead, hd, chunks[i], uint64(i), true)
+	if err != nil {
+		// the previous append stopped midway, so there is nothing to cut
+		if _, rErr := openChunk(rs, aead, hd, chunks[i], uint64(i), false); rErr == nil {
+			return nil, uint64(len(chunks)), nil
+		}
+		return nil, 0, err
+	}
+	tr, ok := e.base.(truncater)
+	if !ok {
+		return nil, 0, errors.New("base storage can't append to encrypted file")
+	}
+	if err = tr.Truncate(s, chunks[i].offset); err != nil {
+		return nil, 0, err
+	}
+
+	return last, uint64(i), nil
+}
+
+// truncater Storage that can cut a file to given size.
+type truncater interface {
+	Truncate(string, int64) error
+}
+
+func (e *encryptedStorage) Open(s string) (io.ReadSeekCloser, error) {
+	fl, err := e.base.Open(s)
+	if err != nil {
+		return nil, err
+	}
+	hd, err := readHeader(fl)
+	if err != nil {
+		if errors.Is(err, errNotEncrypted) {
+			// read the file that is saved before the encryption is enabled as is
+			_, err = fl.Seek(0, io.SeekStart)
+			return fl, err
+		}
+		fl.Close()
+		return nil, err
+	}
+	aead, err := e.dataKey(hd)
+	if err != nil {
+		fl.Close()
+		return nil, err
+	}
+	chunks, size, err := scanChunks(fl)
+	if err != nil {
+		fl.Close()
+		return nil, err
+	}
+	or := &openReader{base: fl, aead: aead, hd: hd, chunks: chunks, size: size, cur: -1}
+	// make sure the file is not cut short by opening its final chunk upfront
+	if len(chunks) == 0 {
+		fl.Close()
+		return nil, errTruncated
+	}
+	if err = or.decrypt(len(chunks) - 1); err != nil {
+		fl.Close()
+		return nil, err
+	}
+
+	return or, nil
+}
+
+func (e *encryptedStorage) Move(src, dst string) error {
+	return e.base.Move(src, dst)
+}
+
+func (e *encryptedStorage) Remove(s string) {
+	e.base.Remove(s)
+}
+
+func (e *encryptedStorage) Rewrap(s string) (bool, error) {
+	fl, err := e.base.Open(s)
+	if err != nil {
+		return false, err
+	}
+	hd, err := readHeader(fl)
+	fl.Close()
+	if err != nil {
+		if errors.Is(err, errNotEncrypted) {
+			return false, nil
+		}
+		return false, err
+	}
+	if bytes.Equal(hd.keyID, e.key.id) {
+		return false, nil
+	}
+
+	// every chunk is bound to the key id, so the whole file is encrypted again
+	// using a new data key that is wrapped by the current master key
+	src, err := e.Open(s)
+	if err != nil {
+		return false, err
+	}
+	size, err := src.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = src.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		src.Close()
+		return false, err
+	}
+
+	// write to a temporary file first, so the original is never half written
+	tmp := s + ".rewrap"
+	e.Save(src, tmp)
+	if err = e.checkSize(tmp, size); err != nil {
+		e.base.Remove(tmp)
+		return false, err
+	}
+
+	return true, e.base.Move(tmp, s)
+}
+
+// checkSize make sure the file with given name has given plain size.
+func (e *encryptedStorage) checkSize(s string, size int64) error {
+	fl, err := e.Open(s)
+	if err != nil {
+		return err
+	}
+	defer fl.Close()
+
+	n, err := fl.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return errors.New("rewrapped file " + s + " is incomplete")
+	}
+	return nil
+}
+
+// newHeader return header for a new file along with the cipher of its newly
+// generated data key.
+func (e *encryptedStorage) newHeader() (*encHeader, cipher.AEAD, error) {
+	dek := make([]byte, dataKeySize)
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := e.key.wrap(dek)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &encHeader{keyID: e.key.id, wrapped: wrapped, prefix: prefix}, aead, nil
+}
+
+// dataKey return the cipher of the data key in given header.
+func (e *encryptedStorage) dataKey(hd *encHeader) (cipher.AEAD, error) {
+	dek, err := e.unwrap(hd)
+	if err != nil {
+		return nil, err
+	}
+	return newGCM(dek)
+}
+
+// unwrap open the data key in given header using the master key that has the
+// same key id.
+func (e *encryptedStorage) unwrap(hd *encHeader) ([]byte, error) {
+	for _, key := range append([]*MasterKey{e.key}, e.old...) {
+		if bytes.Equal(hd.keyID, key.id) {
+			return key.unwrap(hd.wrapped)
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+var (
+	// errNotEncrypted the file does not start with the expected header.
+	errNotEncrypted = errors.New("file is not encrypted")
+	// errTruncated the file doesn't end with its final chunk.
+	errTruncated = errors.New("encrypted file is truncated")
+	// errCorrupted the chunks of the file can't be read.
+	errCorrupted = errors.New("encrypted file is corrupted")
+)
+
+// encHeader the header of each encrypted file.
+type encHeader struct {
+	keyID   []byte
+	wrapped []byte
+	prefix  []byte
+}
+
+func (h *encHeader) marshal() []byte {
+	b := make([]byte, 0, headerSize)
+	b = append(b, encMagic...)
+	b = append(b, encVersion)
+	b = append(b, h.keyID...)
+	b = append(b, h.wrapped...)
+	return append(b, h.prefix...)
+}
+
+// aad return the additional data that is authenticated along with each chunk
+// of the file which has this header.
+func (h *encHeader) aad(final bool) []byte {
+	b := make([]byte, 0, len(encMagic)+1+keyIDSize+noncePrefixSize+1)
+	b = append(b, encMagic...)
+	b = append(b, encVersion)
+	b = append(b, h.keyID...)
+	b = append(b, h.prefix...)
+	if final {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+// nonce return the nonce of the chunk with given index of the file which has
+// this header.
+func (h *encHeader) nonce(index uint64, final bool) []byte {
+	if final {
+		index |= finalFlag
+	}
+	return chunkNonce(h.prefix, index)
+}
+
+// readHeader read the header from the beginning of given reader.
+func readHeader(r io.Reader) (*encHeader, error) {
+	b := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errNotEncrypted
+		}
+		return nil, err
+	}
+	if string(b[:len(encMagic)]) != encMagic {
+		return nil, errNotEncrypted
+	}
+	b = b[len(encMagic):]
+	if b[0] != encVersion {
+		return nil, errors.New("unsupported encryption version")
+	}
+	b = b[1:]
+
+	return &encHeader{
+		keyID:   b[:keyIDSize],
+		wrapped: b[keyIDSize : keyIDSize+wrappedKeySize],
+		prefix:  b[keyIDSize+wrappedKeySize:],
+	}, nil
+}
+
+// chunkInfo the position of a chunk.
+type chunkInfo struct {
+	// offset where the chunk start in the encrypted file.
+	offset int64
+	// start where the chunk start in the plain file.
+	start int64
+	// size the plain size of the chunk.
+	size int64
+}
+
+// scanChunks find the position of every chunk by only reading their length
+// starting from the current offset of given reader that should be right
+// after the header. Return the chunks along with the total plain size, or
+// errCorrupted if any chunk is larger than a chunk can be.
+func scanChunks(rs io.ReadSeeker) ([]chunkInfo, int64, error) {
+	var chunks []chunkInfo
+	var start int64
+	offset := int64(headerSize)
+	ln := make([]byte, chunkLenSize)
+	for {
+		if _, err := io.ReadFull(rs, ln); err != nil {
+			if errors.Is(err, io.EOF) {
+				return chunks, start, nil
+			}
+			return nil, 0, errCorrupted
+		}
+		size := int64(binary.BigEndian.Uint32(ln))
+		if size > chunkSize {
+			return nil, 0, errCorrupted
+		}
+		chunks = append(chunks, chunkInfo{offset: offset, start: start, size: size})
+		start += size
+		offset += chunkLenSize + size + gcmTagSize
+		if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// sealReader encrypt everything that is read from src into chunks. The last
+// chunk is sealed as the final one.
+type sealReader struct {
+	src   io.Reader
+	aead  cipher.AEAD
+	hd    *encHeader
+	index uint64
+	// plain has room for one more byte than a chunk to find out whether the
+	// chunk is the final one, n is the number of bytes that are already read
+	// into it.
+	plain []byte
+	n     int
+	out   []byte
+	err   error
+	// sealed the plain and encrypted size of each sealed chunk.
+	sealed [][2]int64
+}
+
+// newSealReader return reader that encrypt given src using given cipher
+// starting from given chunk index.
+func newSealReader(src io.Reader, aead cipher.AEAD, hd *encHeader, index uint64) *sealReader {
+	return &sealReader{src: src, aead: aead, hd: hd, index: index, plain: make([]byte, chunkSize+1)}
+}
+
+func (s *sealReader) Read(p []byte) (int, error) {
+	for len(s.out) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		n, err := io.ReadFull(s.src, s.plain[s.n:])
+		n += s.n
+		switch {
+		case err == nil:
+			// there is more to come, carry the extra byte to the next chunk
+			s.seal(s.plain[:chunkSize], false)
+			s.plain[0], s.n = s.plain[chunkSize], 1
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			// the final chunk is sealed even if it's empty
+			s.seal(s.plain[:n], true)
+			s.err = io.EOF
+		default:
+			// leave the file without the final chunk, so it's known as
+			// incomplete and may be appended later
+			if n > 0 {
+				s.seal(s.plain[:n], false)
+			}
+			s.err = err
+		}
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+
+	return n, nil
+}
+
+// seal encrypt given plain as the next chunk.
+func (s *sealReader) seal(plain []byte, final bool) {
+	out := make([]byte, chunkLenSize, chunkLenSize+len(plain)+gcmTagSize)
+	binary.BigEndian.PutUint32(out, uint32(len(plain)))
+	s.out = s.aead.Seal(out, s.hd.nonce(s.index, final), plain, s.hd.aad(final))
+	s.sealed = append(s.sealed, [2]int64{int64(len(plain)), int64(len(s.out))})
+	s.index++
+}
+
+// plainWritten return the plain size of the chunks that are completely
+// written given n encrypted bytes.
+func (s *sealReader) plainWritten(n int64) int64 {
+	var plain int64
+	for _, sl := range s.sealed {
+		if n < sl[1] {
+			break
+		}
+		n -= sl[1]
+		plain += sl[0]
+	}
+	return plain
+}
+
+// openReader decrypt the chunks of an encrypted file on demand, so it can be
+// read from any offset.
+type openReader struct {
+	base   io.ReadSeekCloser
+	aead   cipher.AEAD
+	hd     *encHeader
+	chunks []chunkInfo
+	size   int64
+	pos    int64
+	// cur the index of the chunk that is currently decrypted in buf.
+	cur int
+	buf []byte
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	if o.pos >= o.size {
+		return 0, io.EOF
+	}
+	i := sort.Search(len(o.chunks), func(i int) bool {
+		return o.chunks[i].start+o.chunks[i].size > o.pos
+	})
+	if i != o.cur {
+		if err := o.decrypt(i); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.buf[o.pos-o.chunks[i].start:])
+	o.pos += int64(n)
+
+	return n, nil
+}
+
+// decrypt read and open the chunk with given index into buf.
+func (o *openReader) decrypt(i int) error {
+	plain, err := openChunk(o.base, o.aead, o.hd, o.chunks[i], uint64(i), i == len(o.chunks)-1)
+	if err != nil {
+		return err
+	}
+	o.buf, o.cur = plain, i
+
+	return nil
+}
+
+// openChunk read and open given chunk that has given index.
+func openChunk(rs io.ReadSeeker, aead cipher.AEAD, hd *encHeader, ch chunkInfo, index uint64, final bool) ([]byte, error) {
+	if ch.size > chunkSize {
+		return nil, errCorrupted
+	}
+	if _, err := rs.Seek(ch.offset+chunkLenSize, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, ch.size+gcmTagSize)
+	if _, err := io.ReadFull(rs, sealed); err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(sealed[:0], hd.nonce(index, final), sealed, hd.aad(final))
+	if err != nil {
+		return nil, errors.New("failed to decrypt chunk: " + err.Error())
+	}
+	return plain, nil
+}
+
+func (o *openReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.pos
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	o.pos = offset
+
+	return o.pos, nil
+}
+
+func (o *openReader) Close() error {
+	return o.base.Close()
+}
+
+// readCloser combine a reader with a different closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// chunkNonce return the nonce of the chunk with given index.
+func chunkNonce(prefix []byte, index uint64) []byte {
+	nonce := make([]byte, gcmNonceSize)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[noncePrefixSize:], index)
+	return nonce
+}
+
+// newGCM return AES-GCM cipher using given key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
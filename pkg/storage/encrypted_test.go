@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleContent return deterministic content that span multiple chunks.
+func sampleContent(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func sampleKey(t *testing.T, b byte) *MasterKey {
+	key, err := NewMasterKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, dataKeySize)))
+	require.NoError(t, err)
+	return key
+}
+
+func newTestEncrypted(t *testing.T, key *MasterKey, old ...*MasterKey) IEncryptedStorage {
+	l := logger.NewStdOut()
+	l.Init()
+	return NewEncrypted(l, NewFile(l), key, old...)
+}
+
+func TestEncryptedStorage_SaveOpen(t *testing.T) {
+	content := sampleContent(3*chunkSize + 123)
+	fn := filepath.Join(t.TempDir(), "sample")
+	st := newTestEncrypted(t, sampleKey(t, 1))
+	st.Save(io.NopCloser(bytes.NewReader(content)), fn)
+
+	// the file in the disk should not contain the plain content
+	raw, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(raw, content[:chunkSize]))
+
+	testCases := []struct {
+		name   string
+		offset int64
+		length int
+	}{
+		{name: "Given offset 0 should return the whole content", offset: 0, length: len(content)},
+		{name: "Given offset in the middle of a chunk should return the rest of content", offset: 100, length: 500},
+		{name: "Given offset that cross the chunk boundary should return the content from both chunks", offset: chunkSize - 10, length: 20},
+		{name: "Given offset in the last chunk should return the rest of content", offset: 3*chunkSize + 100, length: 23},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fl, err := st.Open(fn)
+			require.NoError(t, err)
+			defer fl.Close()
+
+			size, err := fl.Seek(0, io.SeekEnd)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(content)), size)
+
+			_, err = fl.Seek(tc.offset, io.SeekStart)
+			require.NoError(t, err)
+			got := make([]byte, tc.length)
+			_, err = io.ReadFull(fl, got)
+			require.NoError(t, err)
+			assert.Equal(t, content[tc.offset:tc.offset+int64(tc.length)], got)
+		})
+	}
+}
+
+func TestEncryptedStorage_Append(t *testing.T) {
+	content := sampleContent(2*chunkSize + 77)
+	fn := filepath.Join(t.TempDir(), "sample")
+	st := newTestEncrypted(t, sampleKey(t, 1))
+
+	// append an empty chunk then the content in uneven parts
+	n, err := st.Append(bytes.NewReader(nil), fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	for _, part := range [][]byte{content[:10], content[10 : chunkSize+5], content[chunkSize+5:]} {
+		n, err = st.Append(bytes.NewReader(part), fn)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(part)), n)
+	}
+
+	fl, err := st.Open(fn)
+	require.NoError(t, err)
+	defer fl.Close()
+	got, err := io.ReadAll(fl)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestEncryptedStorage_Rewrap(t *testing.T) {
+	content := sampleContent(chunkSize + 1)
+	fn := filepath.Join(t.TempDir(), "sample")
+	oldKey, newKey := sampleKey(t, 1), sampleKey(t, 2)
+	newTestEncrypted(t, oldKey).Save(io.NopCloser(bytes.NewReader(content)), fn)
+
+	// without the old key the file can't be opened
+	_, err := newTestEncrypted(t, newKey).Open(fn)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// rewrap using the old key then make sure it's readable by the new key
+	ok, err := newTestEncrypted(t, newKey, oldKey).Rewrap(fn)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = newTestEncrypted(t, newKey).Rewrap(fn)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	fl, err := newTestEncrypted(t, newKey).Open(fn)
+	require.NoError(t, err)
+	defer fl.Close()
+	got, err := io.ReadAll(fl)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestEncryptedStorage_OpenPlain(t *testing.T) {
+	content := []byte("saved before the encryption is enabled")
+	fn := filepath.Join(t.TempDir(), "sample")
+	require.NoError(t, os.WriteFile(fn, content, 0600))
+
+	fl, err := newTestEncrypted(t, sampleKey(t, 1)).Open(fn)
+	require.NoError(t, err)
+	defer fl.Close()
+	got, err := io.ReadAll(fl)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestEncryptedStorage_Truncated(t *testing.T) {
+	content := sampleContent(2*chunkSize + 10)
+	st := newTestEncrypted(t, sampleKey(t, 1))
+
+	testCases := []struct {
+		name string
+		// cut return the size the file should be cut to from the position of
+		// its chunks
+		cut func([]chunkInfo) int64
+	}{
+		{
+			name: "Given file that is cut before its final chunk should return error",
+			cut:  func(chunks []chunkInfo) int64 { return chunks[len(chunks)-1].offset },
+		},
+		{
+			name: "Given file that has nothing but the header should return error",
+			cut:  func([]chunkInfo) int64 { return int64(headerSize) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "sample")
+			st.Save(io.NopCloser(bytes.NewReader(content)), fn)
+
+			fl, err := os.Open(fn)
+			require.NoError(t, err)
+			_, err = readHeader(fl)
+			require.NoError(t, err)
+			chunks, _, err := scanChunks(fl)
+			fl.Close()
+			require.NoError(t, err)
+			require.NoError(t, os.Truncate(fn, tc.cut(chunks)))
+
+			_, err = st.Open(fn)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEncryptedStorage_OversizedChunk(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "sample")
+	st := newTestEncrypted(t, sampleKey(t, 1))
+	st.Save(io.NopCloser(bytes.NewReader(sampleContent(10))), fn)
+
+	// claim that the first chunk is far larger than a chunk can be
+	b, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	copy(b[headerSize:], []byte{0xff, 0xff, 0xff, 0xff})
+	require.NoError(t, os.WriteFile(fn, b, 0600))
+
+	_, err = st.Open(fn)
+	assert.ErrorIs(t, err, errCorrupted)
+}
@@ -32,6 +32,29 @@ func (f *fileStorage) Save(rc io.ReadCloser, s string) {
 	}
 }
 
+func (f *fileStorage) Append(r io.Reader, s string) (int64, error) {
+	fl, err := os.OpenFile(s, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return 0, err
+	}
+	defer fl.Close()
+
+	return io.Copy(fl, r)
+}
+
+func (f *fileStorage) Open(s string) (io.ReadSeekCloser, error) {
+	return os.Open(s)
+}
+
+func (f *fileStorage) Move(src, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// Truncate cut the file in given path to given size.
+func (f *fileStorage) Truncate(s string, size int64) error {
+	return os.Truncate(s, size)
+}
+
 func (f *fileStorage) Remove(s string) {
 	if err := os.Remove(s); err != nil {
 		f.log.Err("failed to remove", s, ":", err)
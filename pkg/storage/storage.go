@@ -9,6 +9,25 @@ type IStorage interface {
 	// including the file extension. This Save will be responsible for closing
 	// the reader, so you may safely call this in separate goroutine.
 	Save(io.ReadCloser, string)
+	// Append do append given reader to the end of given file path. The file
+	// will be created if it's not exist yet. Return the number of bytes that
+	// was written even if there is any error.
+	Append(io.Reader, string) (int64, error)
+	// Open return the file in given path for reading. The caller is
+	// responsible for closing it.
+	Open(string) (io.ReadSeekCloser, error)
+	// Move do rename the first given file path to the second one.
+	Move(string, string) error
 	// Remove do remove given file path.
 	Remove(string)
 }
+
+// IEncryptedStorage Storage that encrypt every file using a data key which is
+// wrapped by a master key.
+type IEncryptedStorage interface {
+	IStorage
+	// Rewrap do encrypt again given file path using a new data key that is
+	// wrapped by the current master key. Return true if the file was wrapped
+	// by an old master key and now is rewrapped.
+	Rewrap(string) (bool, error)
+}
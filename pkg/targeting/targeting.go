@@ -0,0 +1,202 @@
+package targeting
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// PlatformIOS client that use iPhone, iPad or iPod.
+	PlatformIOS = "ios"
+	// PlatformAndroid client that use Android device.
+	PlatformAndroid = "android"
+	// PlatformDesktop any other client.
+	PlatformDesktop = "desktop"
+)
+
+// Client what is known about the client that resolve a link.
+type Client struct {
+	// Platform either PlatformIOS, PlatformAndroid or PlatformDesktop.
+	Platform string
+	// Language the most preferred language of the client in lower case, such
+	// as en-us. Empty if the client doesn't tell.
+	Language string
+	Query    url.Values
+	// Visitor the key that identify the client across requests, see Visitor.
+	Visitor string
+}
+
+// NewClient return Client from given User-Agent and Accept-Language header
+// along with the query parameters of the request.
+func NewClient(ua, acceptLang string, query url.Values) Client {
+	return Client{Platform: Platform(ua), Language: Language(acceptLang), Query: query}
+}
+
+// Platform return the platform of given User-Agent header.
+func Platform(ua string) string {
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		return PlatformIOS
+	case strings.Contains(ua, "Android"):
+		return PlatformAndroid
+	}
+	return PlatformDesktop
+}
+
+// Language return the most preferred language in given Accept-Language header
+// in lower case. Return empty string if there is none.
+func Language(acceptLang string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(acceptLang, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{lang, q})
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	// keep the order in the header for the same weight
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	return tags[0].lang
+}
+
+// Rule send the client that match all of its conditions to Destination. Empty
+// condition match any client.
+type Rule struct {
+	// Platform either PlatformIOS, PlatformAndroid or PlatformDesktop.
+	Platform string
+	// Language match the language itself and any of its regional variants,
+	// such as en match en-us and en-gb.
+	Language string
+	// Query match if every parameter has the value in the request.
+	Query       url.Values
+	Destination string
+}
+
+// Match whether given client meet every condition of the Rule.
+func (r Rule) Match(c Client) bool {
+	if r.Platform != "" && !strings.EqualFold(r.Platform, c.Platform) {
+		return false
+	}
+	if r.Language != "" {
+		l := strings.ToLower(r.Language)
+		if c.Language != l && !strings.HasPrefix(c.Language, l+"-") {
+			return false
+		}
+	}
+	for k, vs := range r.Query {
+		for _, v := range vs {
+			if c.Query.Get(k) != v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Resolve return the Destination of the first Rule that match given client,
+// otherwise return given default destination.
+func Resolve(rules []Rule, c Client, def string) string {
+	for _, r := range rules {
+		if r.Match(c) {
+			return r.Destination
+		}
+	}
+	return def
+}
+
+// Variant a destination that get a share of the traffic by its Weight.
+type Variant struct {
+	Destination string
+	Weight      int
+}
+
+// Pick return the index of the Variant for given visitor key, the same key
+// always get the same Variant as long as the variants are not changed. Return
+// -1 if none of them has weight.
+func Pick(variants []Variant, key string) int {
+	var total uint64
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += uint64(v.Weight)
+		}
+	}
+	if total == 0 {
+		return -1
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	n := binary.BigEndian.Uint64(sum[:8]) % total
+	for i, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if n < uint64(v.Weight) {
+			return i
+		}
+		n -= uint64(v.Weight)
+	}
+	return -1
+}
+
+var (
+	keyMu sync.RWMutex
+	// key hash the ip of every visitor, which is random until SetKey is
+	// called so the visitors are still hashed but only the same for this
+	// process.
+	key = randomKey()
+)
+
+// SetKey derive the key that is used to hash the ip of every visitor from
+// given secret, so a visitor keep the same key across restarts while the ip
+// can't be found back by hashing every address. The secret may be shared
+// with other purposes since the key is never the secret itself.
+func SetKey(secret []byte) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if len(secret) > 0 {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte("visitor"))
+		key = mac.Sum(nil)
+	}
+}
+
+func randomKey() []byte {
+	k := make([]byte, 32)
+	rand.Read(k)
+	return k
+}
+
+// Visitor return the key that identify a visitor by hashing given ip using
+// the key from SetKey, so the ip itself is never stored anywhere.
+func Visitor(ip string) string {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
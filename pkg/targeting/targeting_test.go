@@ -0,0 +1,177 @@
+package targeting
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatform(t *testing.T) {
+	testCases := []struct {
+		name   string
+		ua     string
+		expect string
+	}{
+		{
+			name:   "Given iPhone user agent should return ios",
+			ua:     "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148",
+			expect: PlatformIOS,
+		},
+		{
+			name:   "Given iPad user agent should return ios",
+			ua:     "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148",
+			expect: PlatformIOS,
+		},
+		{
+			name:   "Given Android user agent should return android",
+			ua:     "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/118.0 Mobile Safari/537.36",
+			expect: PlatformAndroid,
+		},
+		{
+			name:   "Given desktop user agent should return desktop",
+			ua:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/118.0 Safari/537.36",
+			expect: PlatformDesktop,
+		},
+		{
+			name:   "Given empty user agent should return desktop",
+			expect: PlatformDesktop,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Platform(tc.ua))
+		})
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		expect string
+	}{
+		{
+			name:   "Given single language should return it in lower case",
+			header: "en-US",
+			expect: "en-us",
+		},
+		{
+			name:   "Given several languages should return the one with the highest weight",
+			header: "en;q=0.8, id-ID, fr;q=0.9",
+			expect: "id-id",
+		},
+		{
+			name:   "Given languages with the same weight should return the first one",
+			header: "fr, de",
+			expect: "fr",
+		},
+		{
+			name:   "Given wildcard and rejected language should ignore both of them",
+			header: "*, ja;q=0, ko;q=0.5",
+			expect: "ko",
+		},
+		{
+			name: "Given empty header should return empty string",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Language(tc.header))
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	rules := []Rule{
+		{Platform: PlatformIOS, Query: url.Values{"ref": {"promo"}}, Destination: "https://apps.apple.com/promo"},
+		{Platform: PlatformIOS, Destination: "https://apps.apple.com/app"},
+		{Platform: PlatformAndroid, Destination: "https://play.google.com/app"},
+		{Language: "id", Destination: "https://example.com/id"},
+	}
+
+	testCases := []struct {
+		name   string
+		client Client
+		expect string
+	}{
+		{
+			name:   "Given client that match several rules should return the first one",
+			client: Client{Platform: PlatformIOS, Query: url.Values{"ref": {"promo"}}},
+			expect: "https://apps.apple.com/promo",
+		},
+		{
+			name:   "Given client that doesn't match the query should return the next matching rule",
+			client: Client{Platform: PlatformIOS, Query: url.Values{"ref": {"mail"}}},
+			expect: "https://apps.apple.com/app",
+		},
+		{
+			name:   "Given Android client should return the Android rule",
+			client: Client{Platform: PlatformAndroid, Language: "id-id"},
+			expect: "https://play.google.com/app",
+		},
+		{
+			name:   "Given regional variant of the language should match the rule",
+			client: Client{Platform: PlatformDesktop, Language: "id-id"},
+			expect: "https://example.com/id",
+		},
+		{
+			name:   "Given language that only share the prefix should not match the rule",
+			client: Client{Platform: PlatformDesktop, Language: "ido"},
+			expect: "https://example.com/",
+		},
+		{
+			name:   "Given client that match nothing should return the default",
+			client: Client{Platform: PlatformDesktop, Language: "en-us"},
+			expect: "https://example.com/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Resolve(rules, tc.client, "https://example.com/"))
+		})
+	}
+}
+
+func TestPick(t *testing.T) {
+	variants := []Variant{{Destination: "a", Weight: 3}, {Destination: "b", Weight: 0}, {Destination: "c", Weight: 1}}
+
+	t.Run("Given the same key should always pick the same variant", func(t *testing.T) {
+		first := Pick(variants, "visitor-1")
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, Pick(variants, "visitor-1"))
+		}
+	})
+
+	t.Run("Given many keys should split them by weight and never pick variant without weight", func(t *testing.T) {
+		picked := make([]int, len(variants))
+		for i := 0; i < 4000; i++ {
+			picked[Pick(variants, Visitor("10.0.0."+strconv.Itoa(i)))]++
+		}
+		assert.Zero(t, picked[1])
+		assert.InDelta(t, 3000, picked[0], 200)
+		assert.InDelta(t, 1000, picked[2], 200)
+	})
+
+	t.Run("Given no variant with weight should return -1", func(t *testing.T) {
+		assert.Equal(t, -1, Pick(nil, "visitor-1"))
+		assert.Equal(t, -1, Pick([]Variant{{Destination: "a"}}, "visitor-1"))
+	})
+}
+
+func TestVisitor(t *testing.T) {
+	SetKey([]byte("secret"))
+	first := Visitor("10.0.0.1")
+
+	assert.Equal(t, first, Visitor("10.0.0.1"))
+	assert.NotEqual(t, first, Visitor("10.0.0.2"))
+	assert.Len(t, first, 32)
+
+	// the key is derived from the secret, so it's not a plain hash of the ip
+	SetKey([]byte("other"))
+	assert.NotEqual(t, first, Visitor("10.0.0.1"))
+}
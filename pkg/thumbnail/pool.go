@@ -0,0 +1,123 @@
+package thumbnail
+
+import (
+	"io"
+	"sync"
+
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/mdanialr/sns_backend/pkg/storage"
+	"github.com/spf13/viper"
+)
+
+// Job a thumbnail that should be generated by the Pool.
+type Job struct {
+	// Src the file path of the original image in Storage.
+	Src string
+	// Dst the file path of the thumbnail in Storage. The extension decide
+	// the format of the thumbnail.
+	Dst string
+	// Done is called once the thumbnail is saved or already exist.
+	Done func()
+}
+
+// Pool generate thumbnails in the background using a fixed number of workers.
+// Jobs that are submitted while the queue is full are dropped, so the caller
+// is never blocked.
+type Pool struct {
+	log  logger.Writer
+	st   storage.IStorage
+	size int
+	jobs chan Job
+	wg   sync.WaitGroup
+}
+
+// NewPool return Pool that has already started given number of workers.
+// Given queue is the number of jobs that may wait for a free worker and size
+// is the maximum width or height of the thumbnail in pixels.
+func NewPool(l logger.Writer, st storage.IStorage, workers, queue, size int) *Pool {
+	p := &Pool{log: l, st: st, size: size, jobs: make(chan Job, queue)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// NewPoolWithConfig same as NewPool but use the values from given viper
+// config. Return nil if the number of workers is not set which means
+// thumbnail generation is disabled.
+func NewPoolWithConfig(v *viper.Viper, l logger.Writer, st storage.IStorage) *Pool {
+	workers := v.GetInt("thumbnail.workers")
+	if workers <= 0 {
+		return nil
+	}
+	size := v.GetInt("thumbnail.size")
+	if size <= 0 {
+		size = 256
+	}
+	return NewPool(l, st, workers, v.GetInt("thumbnail.queue"), size)
+}
+
+// Submit add given job to the queue. Return false if the job is dropped
+// because the queue is full or the Pool is nil.
+func (p *Pool) Submit(j Job) bool {
+	if p == nil {
+		return false
+	}
+	select {
+	case p.jobs <- j:
+		return true
+	default:
+		p.log.Err("thumbnail queue is full, drop job for", j.Src)
+		return false
+	}
+}
+
+// Close stop accepting new jobs then wait for the queued ones to finish.
+func (p *Pool) Close() {
+	if p == nil {
+		return
+	}
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for j := range p.jobs {
+		if err := p.generate(j); err != nil {
+			p.log.Err("failed to generate thumbnail for", j.Src, ":", err)
+			continue
+		}
+		if j.Done != nil {
+			j.Done()
+		}
+	}
+}
+
+// generate save the thumbnail of the job, skip it if the thumbnail already
+// exist such as when the same content is uploaded before.
+func (p *Pool) generate(j Job) error {
+	if fl, err := p.st.Open(j.Dst); err == nil {
+		fl.Close()
+		return nil
+	}
+
+	src, err := p.st.Open(j.Src)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	buf, err := Generate(src, p.size, j.Dst)
+	if err != nil {
+		return err
+	}
+	p.st.Save(io.NopCloser(buf), j.Dst)
+
+	// Save only log the error, so make sure the thumbnail is there
+	fl, err := p.st.Open(j.Dst)
+	if err != nil {
+		return err
+	}
+	return fl.Close()
+}
@@ -0,0 +1,125 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// maxPixels the maximum number of pixels of an image that is going to be
+// decoded, so a small file that declare a huge dimension can't eat all the
+// memory.
+const maxPixels = 50_000_000
+
+// ErrTooLarge the image dimension exceed maxPixels.
+var ErrTooLarge = errors.New("image is too large")
+
+// Supported whether a thumbnail can be generated from given MIME type.
+func Supported(mime string) bool {
+	switch mime {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Ext return the extension of the thumbnail for an image that has given MIME
+// type. JPEG stay as JPEG while the others use PNG to keep the transparency.
+func Ext(mime string) string {
+	if mime == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// Generate decode the image in given reader then scale it down, so neither
+// the width nor the height exceed given size. The result is encoded as JPEG
+// if given ext is .jpg otherwise as PNG. For GIF only the first frame is used.
+func Generate(rs io.ReadSeeker, size int, ext string) (*bytes.Buffer, error) {
+	cfg, _, err := image.DecodeConfig(rs)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	if _, err = rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	th := Resize(img, size)
+	if strings.EqualFold(filepath.Ext(ext), ".jpg") {
+		err = jpeg.Encode(&buf, th, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, th)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &buf, nil
+}
+
+// Resize scale down given image, so neither the width nor the height exceed
+// given size while keeping the aspect ratio. Each pixel of the result is the
+// average of the pixels it covers in the source. Image that is already small
+// enough is returned as it is.
+func Resize(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+	dw, dh := size, sh*size/sw
+	if sh > sw {
+		dw, dh = sw*size/sh, size
+	}
+	dw, dh = maxInt(dw, 1), maxInt(dh, 1)
+
+	// convert to RGBA first, so the pixels can be read directly
+	rgba := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, maxInt((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, maxInt((x+1)*sw/dw, x*sw/dw+1)
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[i])
+					g += uint32(rgba.Pix[i+1])
+					bl += uint32(rgba.Pix[i+2])
+					a += uint32(rgba.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(bl/n), uint8(a/n)
+		}
+	}
+
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,142 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mdanialr/sns_backend/pkg/logger"
+	"github.com/mdanialr/sns_backend/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleImage return an image with given dimension that is half red and half
+// blue.
+func sampleImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestResize(t *testing.T) {
+	testCases := []struct {
+		name      string
+		w, h      int
+		expectDim image.Point
+	}{
+		{name: "Given landscape image should limit the width", w: 400, h: 200, expectDim: image.Pt(100, 50)},
+		{name: "Given portrait image should limit the height", w: 150, h: 600, expectDim: image.Pt(25, 100)},
+		{name: "Given image smaller than the size should keep the dimension", w: 40, h: 20, expectDim: image.Pt(40, 20)},
+		{name: "Given very thin image should keep at least one pixel", w: 1000, h: 2, expectDim: image.Pt(100, 1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			th := Resize(sampleImage(tc.w, tc.h), 100)
+			assert.Equal(t, tc.expectDim, th.Bounds().Size())
+		})
+	}
+
+	t.Run("Given two colors image should keep the colors on each side", func(t *testing.T) {
+		th := Resize(sampleImage(400, 200), 100)
+		assert.Equal(t, color.RGBA{R: 255, A: 255}, th.At(10, 10))
+		assert.Equal(t, color.RGBA{B: 255, A: 255}, th.At(90, 10))
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	src := sampleImage(300, 300)
+	var jpg, pn, gf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpg, src, nil))
+	require.NoError(t, png.Encode(&pn, src))
+	require.NoError(t, gif.Encode(&gf, src, nil))
+
+	testCases := []struct {
+		name         string
+		content      []byte
+		ext          string
+		expectFormat string
+		wantErr      bool
+	}{
+		{name: "Given JPEG should return JPEG thumbnail", content: jpg.Bytes(), ext: ".jpg", expectFormat: "jpeg"},
+		{name: "Given PNG should return PNG thumbnail", content: pn.Bytes(), ext: ".png", expectFormat: "png"},
+		{name: "Given GIF should return PNG thumbnail", content: gf.Bytes(), ext: ".png", expectFormat: "png"},
+		{name: "Given content that is not an image should return error", content: []byte("hello"), ext: ".png", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf, err := Generate(bytes.NewReader(tc.content), 64, tc.ext)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			cfg, format, err := image.DecodeConfig(buf)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectFormat, format)
+			assert.Equal(t, 64, cfg.Width)
+			assert.Equal(t, 64, cfg.Height)
+		})
+	}
+
+	t.Run("Given image that declare a huge dimension should return error too large", func(t *testing.T) {
+		var huge bytes.Buffer
+		require.NoError(t, png.Encode(&huge, image.NewGray(image.Rect(0, 0, 10000, 10000))))
+		_, err := Generate(bytes.NewReader(huge.Bytes()), 64, ".png")
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+}
+
+func TestPool(t *testing.T) {
+	l := logger.NewStdOut()
+	l.Init()
+	dir := t.TempDir()
+	var pn bytes.Buffer
+	require.NoError(t, png.Encode(&pn, sampleImage(300, 100)))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src.png"), pn.Bytes(), 0600))
+
+	p := NewPool(l, storage.NewFile(l), 2, 4, 30)
+	done := make(chan struct{})
+	ok := p.Submit(Job{
+		Src:  filepath.Join(dir, "src.png"),
+		Dst:  filepath.Join(dir, "src_thumb.png"),
+		Done: func() { close(done) },
+	})
+	require.True(t, ok)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("thumbnail was not generated in time")
+	}
+	p.Close()
+
+	fl, err := os.Open(filepath.Join(dir, "src_thumb.png"))
+	require.NoError(t, err)
+	defer fl.Close()
+	cfg, err := png.DecodeConfig(fl)
+	require.NoError(t, err)
+	assert.Equal(t, 30, cfg.Width)
+	assert.Equal(t, 10, cfg.Height)
+
+	// nil Pool means the generation is disabled
+	var disabled *Pool
+	assert.False(t, disabled.Submit(Job{}))
+}
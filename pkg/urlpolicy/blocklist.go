@@ -0,0 +1,88 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blocklist the blocked domains that are read from a file, one domain per
+// line. Blank lines and lines that start with # are ignored. Blocking a
+// domain also blocks all of its subdomains. The file is read again whenever
+// it's modified, so it can be changed without restarting the app.
+type Blocklist struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	domains map[string]struct{}
+}
+
+// NewBlocklist return Blocklist that read the blocked domains from given
+// file path. The file is only read once it's needed.
+func NewBlocklist(path string) *Blocklist {
+	return &Blocklist{path: path}
+}
+
+// Match return the blocked domain that given host is, or is a subdomain of.
+// Return empty string if the host is not blocked.
+func (b *Blocklist) Match(host string) (string, error) {
+	if err := b.reload(); err != nil {
+		return "", err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for d := host; d != ""; {
+		if _, ok := b.domains[d]; ok {
+			return d, nil
+		}
+		_, d, _ = strings.Cut(d, ".")
+	}
+
+	return "", nil
+}
+
+// reload read the file again if it's modified since the last time it's read.
+func (b *Blocklist) reload() error {
+	st, err := os.Stat(b.path)
+	if err != nil {
+		return err
+	}
+	b.mu.RLock()
+	fresh := b.domains != nil && st.ModTime().Equal(b.modTime)
+	b.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	fl, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer fl.Close()
+
+	domains := make(map[string]struct{})
+	sc := bufio.NewScanner(fl)
+	for sc.Scan() {
+		ln := strings.TrimSpace(sc.Text())
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		// allow wildcard notation even though subdomains are always blocked
+		ln = strings.TrimPrefix(strings.TrimPrefix(ln, "*."), ".")
+		domains[strings.TrimSuffix(strings.ToLower(ln), ".")] = struct{}{}
+	}
+	if err = sc.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.domains, b.modTime = domains, st.ModTime()
+	b.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	"github.com/spf13/viper"
+)
+
+// ErrRejected the destination is rejected by the Policy.
+var ErrRejected = errors.New("destination rejected")
+
+// Link a link that is identified by its url within one of the public domains,
+// where empty Domain is the primary domain.
+type Link struct {
+	Domain string
+	Url    string
+}
+
+// Follower return the destination of given link if it redirects somewhere
+// else, otherwise return false.
+type Follower func(ctx context.Context, l Link) (string, bool)
+
+// Resolver look up the IP addresses of a host. *net.Resolver satisfies it.
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// Policy decide which destination is accepted for a link.
+type Policy struct {
+	// Schemes the accepted schemes of the destination.
+	Schemes []string
+	// AllowPrivate accept destination in private, loopback or link-local
+	// network.
+	AllowPrivate bool
+	// Resolver look up the host of the destination to make sure it doesn't
+	// point to private network. Only IP address host is checked if nil.
+	Resolver Resolver
+	// MaxHops the maximum number of our own links that may be followed from
+	// the destination.
+	MaxHops int
+	// Domains the public domains that serve our own links.
+	Domains *domains.Domains
+	// Blocklist the blocked domains, may be nil.
+	Blocklist *Blocklist
+}
+
+// NewPolicyWithConfig return Policy using the rules from given viper config.
+// Given domains are the public domains that serve our own links. The host of
+// the destination is resolved unless `shorten.resolve` is set to false.
+func NewPolicyWithConfig(v *viper.Viper, d *domains.Domains) *Policy {
+	v.SetDefault("shorten.schemes", []string{"http", "https"})
+	v.SetDefault("shorten.resolve", true)
+	v.SetDefault("shorten.max_hops", 3)
+	v.SetDefault("shorten.resolve_timeout", 3)
+
+	p := &Policy{
+		Schemes:      v.GetStringSlice("shorten.schemes"),
+		AllowPrivate: v.GetBool("shorten.allow_private"),
+		MaxHops:      v.GetInt("shorten.max_hops"),
+		Domains:      d,
+	}
+	if v.GetBool("shorten.resolve") {
+		p.Resolver = &timeoutResolver{net.DefaultResolver, time.Duration(v.GetInt("shorten.resolve_timeout")) * time.Second}
+	}
+	if fl := v.GetString("shorten.blocklist"); fl != "" {
+		p.Blocklist = NewBlocklist(fl)
+	}
+
+	return p
+}
+
+// Check return error wrapping ErrRejected if given destination of given link
+// is not accepted. Destination that point to our own links is followed using
+// given follower to make sure it never comes back to the link itself.
+func (p *Policy) Check(ctx context.Context, self Link, dest string, follow Follower) error {
+	host, err := p.checkUrl(dest)
+	if err != nil {
+		return err
+	}
+	// our own domains are allowed as long as they don't end up in a loop
+	if _, _, ok := p.Domains.Parse(dest); ok {
+		return p.checkChain(ctx, self, dest, follow)
+	}
+	if !p.AllowPrivate {
+		return p.checkPublic(ctx, host)
+	}
+
+	return nil
+}
+
+// CheckHop return error wrapping ErrRejected if given url is not accepted as
+// the next hop of a request that is sent by the server itself, such as the
+// location of a redirect. Unlike Check our own links are not followed.
+func (p *Policy) CheckHop(ctx context.Context, dest string) error {
+	host, err := p.checkUrl(dest)
+	if err != nil {
+		return err
+	}
+	if !p.AllowPrivate {
+		return p.checkPublic(ctx, host)
+	}
+
+	return nil
+}
+
+// Control reject the connection to an address that is not publicly routable
+// unless AllowPrivate. It's meant to be the Control of net.Dialer, so the
+// address is checked after the host is resolved and right before connecting.
+func (p *Policy) Control(_, address string, _ syscall.RawConn) error {
+	if p.AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s is not a valid address", ErrRejected, address)
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+		return fmt.Errorf("%w: address %s is in private network", ErrRejected, host)
+	}
+	return nil
+}
+
+// checkUrl return the host of given destination, or error wrapping
+// ErrRejected if its scheme or domain is not accepted.
+func (p *Policy) checkUrl(dest string) (string, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s is not a valid url", ErrRejected, dest)
+	}
+	if !p.allowScheme(u.Scheme) {
+		return "", fmt.Errorf("%w: scheme %s is not allowed", ErrRejected, u.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "", fmt.Errorf("%w: %s has no host", ErrRejected, dest)
+	}
+
+	if p.Blocklist != nil {
+		blocked, err := p.Blocklist.Match(host)
+		if err != nil {
+			return "", err
+		}
+		if blocked != "" {
+			return "", fmt.Errorf("%w: domain %s is blocked", ErrRejected, blocked)
+		}
+	}
+
+	return host, nil
+}
+
+// checkChain follow given destination through our own links until it leaves
+// our domains, and reject it if it comes back to given link or is too long.
+func (p *Policy) checkChain(ctx context.Context, self Link, dest string, follow Follower) error {
+	seen := map[Link]struct{}{self: {}}
+	for hop := 0; ; hop++ {
+		dom, slug, ok := p.Domains.Parse(dest)
+		if !ok {
+			return nil
+		}
+		l := Link{dom, slug}
+		if _, ok = seen[l]; ok {
+			return fmt.Errorf("%w: redirect loop through %s", ErrRejected, p.Domains.Url(dom, slug))
+		}
+		seen[l] = struct{}{}
+
+		next, ok := follow(ctx, l)
+		if !ok {
+			return nil
+		}
+		if hop >= p.MaxHops {
+			return fmt.Errorf("%w: more than %d redirects through our own links", ErrRejected, p.MaxHops)
+		}
+		dest = next
+	}
+}
+
+// checkPublic reject given host if it is, or resolves to, an address that is
+// not publicly routable.
+func (p *Policy) checkPublic(ctx context.Context, host string) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: host %s is in private network", ErrRejected, host)
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if p.Resolver == nil {
+			return nil
+		}
+		var err error
+		// the host may resolve to a private address later on, so it can't be
+		// trusted until it resolves
+		if ips, err = p.Resolver.LookupIP(ctx, "ip", host); err != nil {
+			return fmt.Errorf("%w: host %s can't be resolved", ErrRejected, host)
+		}
+	}
+	for _, ip := range ips {
+		if !isPublic(ip) {
+			return fmt.Errorf("%w: host %s is in private network", ErrRejected, host)
+		}
+	}
+	return nil
+}
+
+// allowScheme whether given scheme is one of the accepted schemes.
+func (p *Policy) allowScheme(scheme string) bool {
+	for _, s := range p.Schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublic whether given ip is publicly routable.
+func isPublic(ip net.IP) bool {
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// timeoutResolver Resolver that give up after the timeout.
+type timeoutResolver struct {
+	r       Resolver
+	timeout time.Duration
+}
+
+func (t *timeoutResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.r.LookupIP(ctx, network, host)
+}
@@ -0,0 +1,221 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mdanialr/sns_backend/pkg/domains"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolve each host to the addresses in the map.
+type fakeResolver map[string][]net.IP
+
+func (f fakeResolver) LookupIP(_ context.Context, _, host string) ([]net.IP, error) {
+	ips, ok := f[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+// fakeLinks follow our own links using the destinations in the map.
+type fakeLinks map[Link]string
+
+func (f fakeLinks) follow(_ context.Context, l Link) (string, bool) {
+	dest, ok := f[l]
+	return dest, ok
+}
+
+func writeBlocklist(t *testing.T, content string) string {
+	fl := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(fl, []byte(content), 0644))
+	return fl
+}
+
+func TestPolicy_Check(t *testing.T) {
+	dom, err := domains.New("https://sns.example.com", "go.example.com")
+	require.NoError(t, err)
+	self := Link{Url: "self"}
+	links := fakeLinks{
+		{Url: "a"}:                           "https://go.example.com/b",
+		{Domain: "go.example.com", Url: "b"}: "https://sns.example.com/self",
+		{Url: "c"}:                           "https://sns.example.com/d",
+		{Url: "d"}:                           "https://sns.example.com/e",
+		{Url: "e"}:                           "https://sns.example.com/f",
+		{Url: "f"}:                           "https://www.youtube.com/",
+	}
+	policy := &Policy{
+		Schemes:   []string{"http", "https"},
+		MaxHops:   2,
+		Domains:   dom,
+		Blocklist: NewBlocklist(writeBlocklist(t, "# phishing\nevil.example.org\n*.tracker.example.net\n")),
+		Resolver: fakeResolver{
+			"www.youtube.com":      {net.ParseIP("142.250.4.93")},
+			"intranet.example.com": {net.ParseIP("10.0.0.8")},
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		dest   string
+		expect string
+	}{
+		{
+			name: "Given public https url should be accepted",
+			dest: "https://www.youtube.com/watch?v=1",
+		},
+		{
+			name:   "Given host that can't be resolved should be rejected",
+			dest:   "https://unknown.example.com/",
+			expect: "destination rejected: host unknown.example.com can't be resolved",
+		},
+		{
+			name:   "Given javascript scheme should be rejected",
+			dest:   "javascript:alert(1)",
+			expect: "destination rejected: scheme javascript is not allowed",
+		},
+		{
+			name:   "Given file scheme should be rejected",
+			dest:   "file:///etc/passwd",
+			expect: "destination rejected: scheme file is not allowed",
+		},
+		{
+			name:   "Given loopback address should be rejected",
+			dest:   "http://127.0.0.1:8080/admin",
+			expect: "destination rejected: host 127.0.0.1 is in private network",
+		},
+		{
+			name:   "Given link-local address should be rejected",
+			dest:   "http://169.254.169.254/latest/meta-data",
+			expect: "destination rejected: host 169.254.169.254 is in private network",
+		},
+		{
+			name:   "Given ipv6 loopback address should be rejected",
+			dest:   "http://[::1]/",
+			expect: "destination rejected: host ::1 is in private network",
+		},
+		{
+			name:   "Given localhost should be rejected",
+			dest:   "http://LOCALHOST/",
+			expect: "destination rejected: host localhost is in private network",
+		},
+		{
+			name:   "Given host that resolves to private address should be rejected",
+			dest:   "https://intranet.example.com/",
+			expect: "destination rejected: host intranet.example.com is in private network",
+		},
+		{
+			name:   "Given blocked domain should be rejected",
+			dest:   "https://evil.example.org/login",
+			expect: "destination rejected: domain evil.example.org is blocked",
+		},
+		{
+			name:   "Given subdomain of blocked domain should be rejected",
+			dest:   "https://a.b.tracker.example.net/",
+			expect: "destination rejected: domain tracker.example.net is blocked",
+		},
+		{
+			name:   "Given the link itself should be rejected",
+			dest:   "https://sns.example.com/self",
+			expect: "destination rejected: redirect loop through https://sns.example.com/self",
+		},
+		{
+			name:   "Given our own link that eventually come back to the link should be rejected",
+			dest:   "https://sns.example.com/a",
+			expect: "destination rejected: redirect loop through https://sns.example.com/self",
+		},
+		{
+			name:   "Given our own link that redirect too many times should be rejected",
+			dest:   "https://sns.example.com/c",
+			expect: "destination rejected: more than 2 redirects through our own links",
+		},
+		{
+			name: "Given our own link that is not a redirect should be accepted",
+			dest: "https://go.example.com/notes/raw",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(context.Background(), self, tc.dest, links.follow)
+			if tc.expect == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrRejected)
+			assert.EqualError(t, err, tc.expect)
+		})
+	}
+}
+
+func TestPolicy_Check_AllowPrivate(t *testing.T) {
+	dom, err := domains.New("https://sns.example.com")
+	require.NoError(t, err)
+	policy := &Policy{Schemes: []string{"https"}, AllowPrivate: true, Domains: dom}
+
+	assert.NoError(t, policy.Check(context.Background(), Link{Url: "self"}, "https://192.168.1.1/", nil))
+}
+
+func TestNewPolicyWithConfig(t *testing.T) {
+	v := viper.New()
+	assert.NotNil(t, NewPolicyWithConfig(v, nil).Resolver, "should resolve the host by default")
+
+	v.Set("shorten.resolve", false)
+	assert.Nil(t, NewPolicyWithConfig(v, nil).Resolver, "should only check ip address host once it's opted out")
+}
+
+func TestPolicy_CheckHop(t *testing.T) {
+	policy := &Policy{Schemes: []string{"http", "https"}}
+
+	assert.NoError(t, policy.CheckHop(context.Background(), "https://93.184.216.34/"))
+	assert.ErrorIs(t, policy.CheckHop(context.Background(), "http://169.254.169.254/latest/meta-data"), ErrRejected)
+	assert.ErrorIs(t, policy.CheckHop(context.Background(), "ftp://93.184.216.34/"), ErrRejected)
+}
+
+func TestPolicy_Control(t *testing.T) {
+	policy := &Policy{}
+
+	assert.NoError(t, policy.Control("tcp4", "93.184.216.34:443", nil))
+	assert.ErrorIs(t, policy.Control("tcp4", "10.0.0.8:80", nil), ErrRejected)
+	assert.ErrorIs(t, policy.Control("tcp6", "[fe80::1]:80", nil), ErrRejected)
+	assert.ErrorIs(t, policy.Control("tcp4", "127.0.0.1:5432", nil), ErrRejected)
+
+	policy.AllowPrivate = true
+	assert.NoError(t, policy.Control("tcp4", "127.0.0.1:5432", nil))
+}
+
+func TestBlocklist_Match(t *testing.T) {
+	fl := writeBlocklist(t, "evil.example.org\n")
+	bl := NewBlocklist(fl)
+
+	blocked, err := bl.Match("www.evil.example.org")
+	require.NoError(t, err)
+	assert.Equal(t, "evil.example.org", blocked)
+	blocked, err = bl.Match("example.org")
+	require.NoError(t, err)
+	assert.Empty(t, blocked)
+
+	t.Run("Given modified file should use the new list", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(fl, []byte("example.org\n"), 0644))
+		// make sure the modification time is different from the last read
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(fl, later, later))
+
+		blocked, err = bl.Match("example.org")
+		require.NoError(t, err)
+		assert.Equal(t, "example.org", blocked)
+	})
+
+	t.Run("Given missing file should return error", func(t *testing.T) {
+		_, err = NewBlocklist(filepath.Join(t.TempDir(), "nope.txt")).Match("example.org")
+		assert.Error(t, err)
+	})
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
@@ -17,12 +18,21 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/helmet/v2"
 	"github.com/mdanialr/sns_backend/internal/app"
+	"github.com/mdanialr/sns_backend/internal/core/repository/idempotency_repository"
+	"github.com/mdanialr/sns_backend/internal/core/service/idempotency_service"
 	conf "github.com/mdanialr/sns_backend/pkg/config"
+	"github.com/mdanialr/sns_backend/pkg/domains"
 	gormLogger "github.com/mdanialr/sns_backend/pkg/gorm"
 	"github.com/mdanialr/sns_backend/pkg/helper"
+	"github.com/mdanialr/sns_backend/pkg/linkcheck"
 	"github.com/mdanialr/sns_backend/pkg/logger"
+	paginate "github.com/mdanialr/sns_backend/pkg/pagination"
 	"github.com/mdanialr/sns_backend/pkg/postgresql"
+	"github.com/mdanialr/sns_backend/pkg/scanner"
 	"github.com/mdanialr/sns_backend/pkg/storage"
+	"github.com/mdanialr/sns_backend/pkg/targeting"
+	"github.com/mdanialr/sns_backend/pkg/thumbnail"
+	"github.com/mdanialr/sns_backend/pkg/urlpolicy"
 	"github.com/spf13/viper"
 	gLog "gorm.io/gorm/logger"
 )
@@ -74,11 +84,58 @@ func Http() {
 		os.Exit(1)
 		return
 	}
+	// optionally encrypt every file before it's written to storage
+	if v.GetString("storage.encryption.key") != "" {
+		if st, err = storage.NewEncryptedWithConfig(v, appWr, st); err != nil {
+			appWr.Err("failed to init storage encryption:", err)
+			os.Exit(1)
+			return
+		}
+	}
+	// init the antivirus scanner, if any
+	sc, err := scanner.NewWithConfig(v)
+	if err != nil {
+		appWr.Err("failed to init scanner:", err)
+		os.Exit(1)
+		return
+	}
+	// init the public domains that serve the links
+	doms, err := domains.NewWithConfig(v)
+	if err != nil {
+		appWr.Err("failed to init public domains:", err)
+		os.Exit(1)
+		return
+	}
+	// init the background thumbnail generator, if any
+	thumbs := thumbnail.NewPoolWithConfig(v, appWr, st)
+	// sign the pagination cursors with a key that is derived from the jwt
+	// secret, so they stay valid across restarts
+	paginate.SetKey([]byte(v.GetString("jwt.secret")))
+	// the same goes for the visitor key that pick the variant of a shorten
+	targeting.SetKey([]byte(v.GetString("jwt.secret")))
+	// init the policy of shorten destinations
+	policy := urlpolicy.NewPolicyWithConfig(v, doms)
+	// init the periodic checker of shorten destinations, if any
+	links := linkcheck.NewWithConfig(v, policy)
+	// init the keeper of idempotency keys and remove the ones that are over
+	// every hour
+	idem := idempotency_service.New(appWr, v, idempotency_repository.New(db))
+	purge, stopPurge := time.NewTicker(time.Hour), make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-purge.C:
+				idem.PurgeExpired(context.Background())
+			case <-stopPurge:
+				return
+			}
+		}
+	}()
 	// init fiber
 	fiberApp := fiber.New(fiber.Config{
 		IdleTimeout:           5 * time.Second,
 		BodyLimit:             v.GetInt("server.limit") * 1024 * 1024,
-		RequestMethods:        []string{fiber.MethodHead, fiber.MethodGet, fiber.MethodPost},
+		RequestMethods:        []string{fiber.MethodHead, fiber.MethodGet, fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete, fiber.MethodOptions},
 		JSONEncoder:           sonic.Marshal,
 		JSONDecoder:           sonic.Unmarshal,
 		DisableStartupMessage: !v.GetBool("server.debug"),
@@ -89,21 +146,31 @@ func Http() {
 		Output:     logFiber,
 		TimeFormat: "02-Jan-06 15:04:05",
 	})
+	// public routes stream the response body and set their own cache
+	// validator, so skip etag and compression that need the whole body
+	isPublic := func(c *fiber.Ctx) bool { return !strings.HasPrefix(c.Path(), "/api") }
 	// add middlewares
 	fiberApp.Use(
 		fiberLog,
-		etag.New(),
+		etag.New(etag.Config{Next: isPublic}),
 		recover.New(),
-		compress.New(),
+		compress.New(compress.Config{Next: isPublic}),
 		helmet.New(),
 	)
 	// init http handlers
 	h := app.HttpHandlers{
-		R:       fiberApp.Group("/api"), // add prefix /api to route stack
-		DB:      db,
-		Config:  v,
-		Log:     appWr,
-		Storage: st,
+		R:           fiberApp.Group("/api"), // add prefix /api to route stack
+		Public:      fiberApp,
+		DB:          db,
+		Config:      v,
+		Log:         appWr,
+		Storage:     st,
+		Scanner:     sc,
+		Thumbnail:   thumbs,
+		Domains:     doms,
+		UrlPolicy:   policy,
+		LinkCheck:   links,
+		Idempotency: idem,
 	}
 	h.SetupRouter()
 	// log the app host and port
@@ -124,6 +191,10 @@ func Http() {
 	appWr.Inf("gracefully shutting down...")
 	fiberApp.Shutdown()
 	appWr.Inf("running cleanup tasks...")
+	thumbs.Close()
+	links.Close()
+	purge.Stop()
+	close(stopPurge)
 	sqlDB.Close()
 	appWr.Inf("services was successful shutdown.")
 }